@@ -0,0 +1,41 @@
+package circuit
+
+import "sync/atomic"
+
+// AbandonedPolicy is what a breaker does once it has MaxAbandoned abandoned
+// calls. See Options.MaxAbandoned.
+type AbandonedPolicy int
+
+const (
+	// AbandonedReject makes the breaker reject calls with
+	// ErrTooManyAbandoned, without making them, until some of the abandoned
+	// calls return.
+	AbandonedReject AbandonedPolicy = iota
+
+	// AbandonedTrip trips the breaker.
+	AbandonedTrip
+)
+
+// Abandoned returns the number of calls that timed out but whose functions
+// have not returned yet. Call cannot stop a function that overruns its
+// timeout, so each of these is a goroutine still running in the background.
+func (cb *Breaker) Abandoned() int64 {
+	return atomic.LoadInt64(&cb.abandoned)
+}
+
+// tooManyAbandoned reports whether calls must be rejected because the
+// breaker has MaxAbandoned abandoned calls.
+func (cb *Breaker) tooManyAbandoned() bool {
+	return cb.maxAbandoned > 0 && cb.onAbandon == AbandonedReject &&
+		cb.Abandoned() >= cb.maxAbandoned
+}
+
+// abandon records that a call has been abandoned, tripping the breaker if
+// that makes MaxAbandoned and its policy says so.
+func (cb *Breaker) abandon() {
+	n := atomic.AddInt64(&cb.abandoned, 1)
+	if cb.maxAbandoned > 0 && cb.onAbandon == AbandonedTrip &&
+		n >= cb.maxAbandoned && !cb.Tripped() && !cb.Disabled() {
+		cb.Trip()
+	}
+}
@@ -0,0 +1,90 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAbandoned(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{MaxAbandoned: 2})
+	release := make(chan struct{})
+	hang := func() error {
+		<-release
+		return nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := cb.Call(hang, time.Millisecond); err != ErrBreakerTimeout {
+			t.Fatalf("expected ErrBreakerTimeout, got %v", err)
+		}
+	}
+	if n := cb.Abandoned(); n != 2 {
+		t.Fatalf("expected 2 abandoned calls, got %d", n)
+	}
+	if n := cb.Stats().Abandoned; n != 2 {
+		t.Fatalf("expected Stats to report 2 abandoned calls, got %d", n)
+	}
+
+	called := false
+	if err := cb.Call(func() error { called = true; return nil }, time.Millisecond); err != ErrTooManyAbandoned || called {
+		t.Fatalf("expected the call to be rejected with ErrTooManyAbandoned, got %v", err)
+	}
+	if cb.Rejects() != 1 || cb.Tripped() {
+		t.Fatal("expected the call to be counted as rejected without tripping the breaker")
+	}
+
+	close(release)
+	for i := 0; cb.Abandoned() != 0; i++ {
+		if i == 100 {
+			t.Fatalf("expected abandoned calls to be forgotten once they return, got %d", cb.Abandoned())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := cb.Call(func() error { return nil }, time.Second); err != nil {
+		t.Fatalf("expected calls to be made again, got %v", err)
+	}
+}
+
+func TestAbandonedTrip(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{MaxAbandoned: 1, AbandonedPolicy: AbandonedTrip})
+	release := make(chan struct{})
+	defer close(release)
+
+	cb.Call(func() error {
+		<-release
+		return nil
+	}, time.Millisecond)
+	if !cb.Tripped() {
+		t.Fatal("expected the abandoned call to trip the breaker")
+	}
+}
+
+func TestReconcileLateResults(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{ReconcileLateResults: true})
+	release := make(chan error)
+
+	for i := 0; i < 2; i++ {
+		if err := cb.Call(func() error { return <-release }, time.Millisecond); err != ErrBreakerTimeout {
+			t.Fatalf("expected ErrBreakerTimeout, got %v", err)
+		}
+	}
+	if cb.Failures() != 2 || cb.ConsecFailures() != 2 {
+		t.Fatalf("expected the timeouts to be recorded as failures, got %d", cb.Failures())
+	}
+
+	release <- nil
+	release <- errors.New("late failure")
+	for i := 0; cb.Abandoned() != 0; i++ {
+		if i == 100 {
+			t.Fatal("expected the abandoned calls to return")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if cb.Failures() != 1 || cb.Successes() != 1 {
+		t.Fatalf("expected the late success to replace a timeout, got %d failures and %d successes", cb.Failures(), cb.Successes())
+	}
+	if cb.ConsecFailures() != 1 {
+		t.Fatalf("expected 1 consecutive failure, got %d", cb.ConsecFailures())
+	}
+}
@@ -0,0 +1,64 @@
+package circuit
+
+import "time"
+
+// DefaultAdaptiveQuantile and DefaultAdaptiveMultiplier are the Quantile and
+// Multiplier of an AdaptiveTimeout that leaves them 0: calls time out at one
+// and a half times the 99th percentile latency.
+const (
+	DefaultAdaptiveQuantile   = 0.99
+	DefaultAdaptiveMultiplier = 1.5
+)
+
+// AdaptiveTimeout derives the timeout of a breaker's calls from the latency
+// of the calls in its window, so that the timeout follows the dependency's
+// actual performance rather than a static guess. See Options.AdaptiveTimeout.
+//
+// Calls that time out are counted as taking the full timeout, so a dependency
+// that slows down pushes the timeout up, towards Max, one window at a time.
+type AdaptiveTimeout struct {
+	// Quantile is the latency quantile the timeout is derived from, and
+	// Multiplier what it is multiplied by. The defaults are used if they are
+	// 0.
+	Quantile   float64
+	Multiplier float64
+
+	// Min and Max bound the timeout. Max is not applied if it is 0.
+	Min time.Duration
+	Max time.Duration
+
+	// MinSamples is the number of calls the window must hold before the
+	// timeout is derived from their latency. Until then, the timeout passed
+	// to Call is used.
+	MinSamples int64
+}
+
+// EffectiveTimeout returns the timeout the breaker applies to a call made
+// with timeout, such as by Call. It is timeout itself unless the breaker was
+// created with an AdaptiveTimeout. A timeout of 0, for a call run without one,
+// is never replaced. ProbeTimeout still applies to trial calls.
+func (cb *Breaker) EffectiveTimeout(timeout time.Duration) time.Duration {
+	a := cb.adaptive
+	if a == nil || timeout == 0 || cb.Samples() < a.MinSamples {
+		return timeout
+	}
+	q, m := a.Quantile, a.Multiplier
+	if q == 0 {
+		q = DefaultAdaptiveQuantile
+	}
+	if m == 0 {
+		m = DefaultAdaptiveMultiplier
+	}
+	latency := cb.LatencyQuantile(q)
+	if latency == 0 {
+		return timeout
+	}
+	d := time.Duration(float64(latency) * m)
+	if d < a.Min {
+		d = a.Min
+	}
+	if a.Max != 0 && d > a.Max {
+		d = a.Max
+	}
+	return d
+}
@@ -0,0 +1,48 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestAdaptiveTimeout(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	adaptive := &AdaptiveTimeout{MinSamples: 10}
+	cb := NewBreakerWithOptions(&Options{Clock: c, AdaptiveTimeout: adaptive})
+
+	call := func() {
+		cb.Call(func() error {
+			c.Add(10 * time.Millisecond)
+			return nil
+		}, 0)
+	}
+	for i := 0; i < 9; i++ {
+		call()
+	}
+	if d := cb.EffectiveTimeout(time.Second); d != time.Second {
+		t.Fatalf("expected the given timeout before MinSamples calls, got %v", d)
+	}
+
+	call()
+	d := cb.EffectiveTimeout(time.Second)
+	if d < 15*time.Millisecond || d > 24*time.Millisecond {
+		t.Fatalf("expected about 1.5 times the 10ms latency, got %v", d)
+	}
+
+	adaptive.Max = 12 * time.Millisecond
+	if d := cb.EffectiveTimeout(time.Second); d != 12*time.Millisecond {
+		t.Fatalf("expected the timeout to be capped at Max, got %v", d)
+	}
+	adaptive.Min = 100 * time.Millisecond
+	adaptive.Max = 0
+	if d := cb.EffectiveTimeout(time.Second); d != 100*time.Millisecond {
+		t.Fatalf("expected the timeout to be raised to Min, got %v", d)
+	}
+
+	if d := NewBreaker().EffectiveTimeout(time.Second); d != time.Second {
+		t.Fatalf("expected a breaker without an AdaptiveTimeout to keep the timeout, got %v", d)
+	}
+}
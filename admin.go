@@ -0,0 +1,158 @@
+package circuit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AdminHandler returns an http.Handler exposing the breakers in p for
+// operators. It should only be served on an internal port. Paths are relative
+// to where the handler is mounted, so it is normally wrapped in
+// http.StripPrefix:
+//
+//	mux.Handle("/debug/circuit/", http.StripPrefix("/debug/circuit", circuit.AdminHandler(p)))
+//
+// The handler serves:
+//
+//	GET /breakers                  returns the Stats of every breaker
+//	GET /breakers/{name}           returns the Stats of one breaker
+//	GET /breakers/{name}/faults    returns the faults injected into a breaker
+//	PUT /breakers/{name}/faults    sets the faults injected into a breaker
+//	PUT /breakers/{name}/disabled  disables (true) or enables (false) a breaker
+//	GET /graph                     returns the panel's Graph as JSON
+//	GET /graph?format=dot          returns the panel's Graph in DOT
+//
+// A {name} containing "/", such as a tenant breaker's, must be escaped with
+// url.PathEscape.
+//
+// Faults can only be injected into breakers created with a FaultInjector.
+// See Breaker.Disable for what disabling a breaker does.
+func AdminHandler(p *Panel) http.Handler {
+	return &adminHandler{panel: p}
+}
+
+type adminHandler struct {
+	panel *Panel
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Route on the escaped path so that a name containing "/", such as a
+	// tenant breaker's, can be addressed as a single %2F-escaped segment.
+	parts := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	if len(parts) == 1 && parts[0] == "graph" {
+		h.serveGraph(w, r)
+		return
+	}
+	if parts[0] != "breakers" || len(parts) > 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != "GET" {
+			adminMethodNotAllowed(w, "GET")
+			return
+		}
+		adminWriteJSON(w, h.panel.Stats())
+		return
+	}
+
+	name, err := url.PathUnescape(parts[1])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cb, ok := h.panel.Get(name)
+	if !ok {
+		http.Error(w, "no such breaker", http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 2 {
+		if r.Method != "GET" {
+			adminMethodNotAllowed(w, "GET")
+			return
+		}
+		adminWriteJSON(w, cb.Stats())
+		return
+	}
+
+	switch parts[2] {
+	case "faults":
+		h.serveFaults(w, r, cb)
+	case "disabled":
+		h.serveDisabled(w, r, cb)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *adminHandler) serveGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		adminMethodNotAllowed(w, "GET")
+		return
+	}
+	g := h.panel.Graph()
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		adminWriteJSON(w, g)
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		g.WriteDOT(w)
+	default:
+		http.Error(w, "unknown format", http.StatusBadRequest)
+	}
+}
+
+func (h *adminHandler) serveDisabled(w http.ResponseWriter, r *http.Request, cb *Breaker) {
+	if r.Method != "PUT" {
+		adminMethodNotAllowed(w, "PUT")
+		return
+	}
+	var disabled bool
+	if err := json.NewDecoder(r.Body).Decode(&disabled); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if disabled {
+		cb.Disable()
+	} else {
+		cb.Enable()
+	}
+	adminWriteJSON(w, cb.Stats())
+}
+
+func (h *adminHandler) serveFaults(w http.ResponseWriter, r *http.Request, cb *Breaker) {
+	f := cb.FaultInjector()
+	if f == nil {
+		http.Error(w, "fault injection is not enabled for this breaker", http.StatusConflict)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		adminWriteJSON(w, f.Faults())
+	case "PUT":
+		var faults Faults
+		if err := json.NewDecoder(r.Body).Decode(&faults); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.Set(faults)
+		adminWriteJSON(w, faults)
+	default:
+		adminMethodNotAllowed(w, "GET, PUT")
+	}
+}
+
+func adminMethodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
+
+func adminWriteJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,84 @@
+package circuit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandler(t *testing.T) {
+	p := NewPanel()
+	f := NewFaultInjector()
+	p.Add("db", NewBreakerWithOptions(&Options{FaultInjector: f}))
+	p.Add("cache", NewBreaker())
+	h := AdminHandler(p)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do("GET", "/breakers", "")
+	var all map[string]Stats
+	if err := json.Unmarshal(w.Body.Bytes(), &all); err != nil || len(all) != 2 {
+		t.Fatalf("expected stats of 2 breakers, got %s (%v)", w.Body, err)
+	}
+
+	if w := do("GET", "/breakers/missing", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing breaker, got %d", w.Code)
+	}
+
+	w = do("PUT", "/breakers/db/faults", `{"failure_rate":0.5,"open":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected faults to be set, got %d: %s", w.Code, w.Body)
+	}
+	if faults := f.Faults(); faults.FailureRate != 0.5 || !faults.Open {
+		t.Fatalf("expected faults to be applied, got %+v", faults)
+	}
+
+	w = do("GET", "/breakers/db/faults", "")
+	var faults Faults
+	if err := json.Unmarshal(w.Body.Bytes(), &faults); err != nil || !faults.Open {
+		t.Fatalf("expected the faults set to be returned, got %s (%v)", w.Body, err)
+	}
+
+	if w := do("PUT", "/breakers/cache/faults", `{"open":true}`); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a breaker without a fault injector, got %d", w.Code)
+	}
+	if w := do("DELETE", "/breakers/db/faults", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+
+	cache, _ := p.Get("cache")
+	if w := do("PUT", "/breakers/cache/disabled", "true"); w.Code != http.StatusOK || !cache.Disabled() {
+		t.Fatalf("expected the breaker to be disabled, got %d: %s", w.Code, w.Body)
+	}
+	if w := do("PUT", "/breakers/cache/disabled", "false"); w.Code != http.StatusOK || cache.Disabled() {
+		t.Fatalf("expected the breaker to be enabled, got %d: %s", w.Code, w.Body)
+	}
+
+	tenant := NewBreaker()
+	p.Add("acme/db", tenant)
+	if w := do("PUT", "/breakers/"+url.PathEscape("acme/db")+"/disabled", "true"); w.Code != http.StatusOK || !tenant.Disabled() {
+		t.Fatalf("expected a breaker whose name contains / to be addressable, got %d: %s", w.Code, w.Body)
+	}
+	if w := do("GET", "/breakers/acme/db", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unescaped name, got %d", w.Code)
+	}
+	p.Remove("acme/db")
+
+	p.DependsOn("db", "cache")
+	w = do("GET", "/graph", "")
+	var g Graph
+	if err := json.Unmarshal(w.Body.Bytes(), &g); err != nil || len(g.Nodes) != 2 || len(g.Edges) != 1 {
+		t.Fatalf("expected a graph of 2 breakers and 1 dependency, got %s (%v)", w.Body, err)
+	}
+	w = do("GET", "/graph?format=dot", "")
+	if !strings.Contains(w.Body.String(), `"db" -> "cache";`) {
+		t.Fatalf("expected the graph in DOT, got %s", w.Body)
+	}
+}
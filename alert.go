@@ -0,0 +1,273 @@
+package circuit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+// DefaultAlertInterval is how often Alerter.Run evaluates its rules when its
+// Interval is 0.
+const DefaultAlertInterval = 10 * time.Second
+
+// Snapshot is the state and statistics of a Panel's breakers at one time.
+type Snapshot struct {
+	Time     time.Time        `json:"time"`
+	Breakers map[string]Stats `json:"breakers"`
+}
+
+// Snapshot returns a snapshot of the panel's breakers.
+func (p *Panel) Snapshot() Snapshot {
+	return Snapshot{Time: realClock.Now(), Breakers: p.Stats()}
+}
+
+// Open returns the names of the tripped breakers in the snapshot, sorted.
+func (s Snapshot) Open() []string {
+	var open []string
+	for name, stats := range s.Breakers {
+		if stats.Tripped {
+			open = append(open, name)
+		}
+	}
+	sort.Strings(open)
+	return open
+}
+
+// OpenFor returns how long the breaker named name had been tripped at the time
+// of the snapshot, or 0 if it was closed or missing.
+func (s Snapshot) OpenFor(name string) time.Duration {
+	stats, ok := s.Breakers[name]
+	if !ok || !stats.Tripped || stats.TrippedAt.IsZero() {
+		return 0
+	}
+	return s.Time.Sub(stats.TrippedAt)
+}
+
+// SnapshotDiff lists the breakers that changed between two snapshots of a
+// panel, each list sorted by name.
+type SnapshotDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Tripped []string `json:"tripped,omitempty"`
+	Reset   []string `json:"reset,omitempty"`
+}
+
+// Empty reports whether no breaker changed.
+func (d SnapshotDiff) Empty() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Tripped)+len(d.Reset) == 0
+}
+
+// Diff returns the changes from prev to s. A breaker that is tripped in s is
+// listed as Tripped if it was closed in prev or has tripped again since.
+func (s Snapshot) Diff(prev Snapshot) SnapshotDiff {
+	var d SnapshotDiff
+	for name, stats := range s.Breakers {
+		old, ok := prev.Breakers[name]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, name)
+		case stats.Tripped && (!old.Tripped || stats.Trips > old.Trips):
+			d.Tripped = append(d.Tripped, name)
+		case !stats.Tripped && old.Tripped:
+			d.Reset = append(d.Reset, name)
+		}
+	}
+	for name := range prev.Breakers {
+		if _, ok := s.Breakers[name]; !ok {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Tripped)
+	sort.Strings(d.Reset)
+	return d
+}
+
+// AlertRule is a condition on the snapshots of a panel that raises an alert
+// while it holds.
+type AlertRule struct {
+	// Name identifies the rule in its alerts.
+	Name string
+
+	// Match returns the names of the breakers the condition holds for. The
+	// condition holds while it returns any.
+	Match func(s Snapshot) []string
+
+	// For is how long the condition must hold before the alert fires.
+	For time.Duration
+}
+
+// BreakerOpenFor returns a rule that holds while the breaker named name has
+// been tripped for longer than d.
+func BreakerOpenFor(name string, d time.Duration) AlertRule {
+	return AlertRule{
+		Name: fmt.Sprintf("%s open for %s", name, d),
+		Match: func(s Snapshot) []string {
+			if s.OpenFor(name) > d {
+				return []string{name}
+			}
+			return nil
+		},
+	}
+}
+
+// OpenBreakersAbove returns a rule that holds while more than n breakers are
+// tripped at once.
+func OpenBreakersAbove(n int) AlertRule {
+	return AlertRule{
+		Name: fmt.Sprintf("more than %d breakers open", n),
+		Match: func(s Snapshot) []string {
+			if open := s.Open(); len(open) > n {
+				return open
+			}
+			return nil
+		},
+	}
+}
+
+// Alert is a change in whether an AlertRule is firing.
+type Alert struct {
+	// Rule is the name of the rule.
+	Rule string `json:"rule"`
+
+	// Firing is true when the rule starts firing and false when it resolves.
+	Firing bool `json:"firing"`
+
+	// Breakers are the breakers the rule matched, or matched last if it
+	// resolved.
+	Breakers []string `json:"breakers"`
+
+	// Since is when the rule's condition started holding, and Time when the
+	// alert was raised.
+	Since time.Time `json:"since"`
+	Time  time.Time `json:"time"`
+}
+
+// Alerter evaluates alert rules against snapshots of a Panel and passes the
+// alerts that start firing or resolve to Notify, for alerting on breakers
+// without a separate metrics pipeline:
+//
+//	a := circuit.NewAlerter(panel, page,
+//		circuit.BreakerOpenFor("db", 2*time.Minute),
+//		circuit.OpenBreakersAbove(3),
+//	)
+//	go a.Run(ctx)
+type Alerter struct {
+	// Panel holds the breakers.
+	Panel *Panel
+
+	// Rules are the rules to evaluate. Their names must be unique.
+	Rules []AlertRule
+
+	// Notify, if non-nil, is called with each alert raised by Evaluate.
+	Notify func(Alert)
+
+	// Interval is how often Run evaluates the rules. DefaultAlertInterval is
+	// used if it is 0.
+	Interval time.Duration
+
+	// Clock times the snapshots. The real clock is used if it is nil.
+	Clock clock.Clock
+
+	mu     sync.Mutex
+	since  map[string]time.Time
+	firing map[string]Alert
+}
+
+// NewAlerter creates an Alerter evaluating rules against p.
+func NewAlerter(p *Panel, notify func(Alert), rules ...AlertRule) *Alerter {
+	return &Alerter{Panel: p, Rules: rules, Notify: notify}
+}
+
+func (a *Alerter) clock() clock.Clock {
+	if a.Clock == nil {
+		return realClock
+	}
+	return a.Clock
+}
+
+// Evaluate evaluates the rules against a snapshot of the panel and returns the
+// alerts of the rules that started firing or resolved, which are also passed
+// to Notify.
+func (a *Alerter) Evaluate() []Alert {
+	s := Snapshot{Time: a.clock().Now(), Breakers: a.Panel.Stats()}
+
+	a.mu.Lock()
+	if a.since == nil {
+		a.since = make(map[string]time.Time)
+		a.firing = make(map[string]Alert)
+	}
+	var alerts []Alert
+	for _, rule := range a.Rules {
+		matched := rule.Match(s)
+		alert, firing := a.firing[rule.Name]
+		if len(matched) == 0 {
+			delete(a.since, rule.Name)
+			if firing {
+				delete(a.firing, rule.Name)
+				alert.Firing = false
+				alert.Time = s.Time
+				alerts = append(alerts, alert)
+			}
+			continue
+		}
+
+		since, ok := a.since[rule.Name]
+		if !ok {
+			since = s.Time
+			a.since[rule.Name] = since
+		}
+		switch {
+		case firing:
+			alert.Breakers = matched
+			a.firing[rule.Name] = alert
+		case s.Time.Sub(since) >= rule.For:
+			alert = Alert{Rule: rule.Name, Firing: true, Breakers: matched, Since: since, Time: s.Time}
+			a.firing[rule.Name] = alert
+			alerts = append(alerts, alert)
+		}
+	}
+	a.mu.Unlock()
+
+	if a.Notify != nil {
+		for _, alert := range alerts {
+			a.Notify(alert)
+		}
+	}
+	return alerts
+}
+
+// Firing returns the alerts of the rules that are firing, sorted by rule name.
+func (a *Alerter) Firing() []Alert {
+	a.mu.Lock()
+	alerts := make([]Alert, 0, len(a.firing))
+	for _, alert := range a.firing {
+		alerts = append(alerts, alert)
+	}
+	a.mu.Unlock()
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Rule < alerts[j].Rule })
+	return alerts
+}
+
+// Run calls Evaluate every Interval until ctx is done.
+func (a *Alerter) Run(ctx context.Context) {
+	interval := a.Interval
+	if interval == 0 {
+		interval = DefaultAlertInterval
+	}
+	ticker := a.clock().Ticker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.Evaluate()
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package circuit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	p := NewPanel()
+	a := NewBreaker()
+	b := NewBreaker()
+	p.Add("a", a)
+	p.Add("b", b)
+	b.Break()
+	prev := p.Snapshot()
+
+	a.Break()
+	b.Reset()
+	p.Remove("b")
+	p.Add("c", NewBreaker())
+
+	got := p.Snapshot().Diff(prev)
+	want := SnapshotDiff{Added: []string{"c"}, Removed: []string{"b"}, Tripped: []string{"a"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if d := prev.Diff(prev); !d.Empty() {
+		t.Fatalf("expected no changes between equal snapshots, got %+v", d)
+	}
+}
+
+func TestAlerter(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	p := NewPanel()
+	for _, name := range []string{"db", "cache", "queue", "search"} {
+		p.Add(name, NewBreakerWithOptions(&Options{Clock: c}))
+	}
+	get := func(name string) *Breaker {
+		cb, _ := p.Get(name)
+		return cb
+	}
+
+	var notified []Alert
+	a := NewAlerter(p, func(alert Alert) { notified = append(notified, alert) },
+		BreakerOpenFor("db", 2*time.Minute),
+		OpenBreakersAbove(3),
+	)
+	a.Clock = c
+
+	get("db").Break()
+	c.Add(time.Minute)
+	if alerts := a.Evaluate(); len(alerts) != 0 {
+		t.Fatalf("expected no alerts within 2m of the trip, got %+v", alerts)
+	}
+
+	c.Add(2 * time.Minute)
+	alerts := a.Evaluate()
+	if len(alerts) != 1 || !alerts[0].Firing || alerts[0].Rule != "db open for 2m0s" {
+		t.Fatalf("expected the db rule to fire, got %+v", alerts)
+	}
+	if alerts := a.Evaluate(); len(alerts) != 0 {
+		t.Fatalf("expected a firing rule not to fire again, got %+v", alerts)
+	}
+
+	get("cache").Break()
+	get("queue").Break()
+	get("search").Break()
+	alerts = a.Evaluate()
+	if len(alerts) != 1 || alerts[0].Rule != "more than 3 breakers open" {
+		t.Fatalf("expected the open breakers rule to fire, got %+v", alerts)
+	}
+	if want := []string{"cache", "db", "queue", "search"}; !reflect.DeepEqual(alerts[0].Breakers, want) {
+		t.Fatalf("expected breakers %v, got %v", want, alerts[0].Breakers)
+	}
+	if n := len(a.Firing()); n != 2 {
+		t.Fatalf("expected 2 firing alerts, got %d", n)
+	}
+
+	get("db").Reset()
+	alerts = a.Evaluate()
+	if len(alerts) != 2 || alerts[0].Firing || alerts[1].Firing {
+		t.Fatalf("expected both rules to resolve, got %+v", alerts)
+	}
+	if len(notified) != 4 {
+		t.Fatalf("expected 4 notifications, got %d", len(notified))
+	}
+}
+
+func TestAlerterFor(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	p := NewPanel()
+	cb := NewBreakerWithOptions(&Options{Clock: c})
+	p.Add("db", cb)
+
+	rule := OpenBreakersAbove(0)
+	rule.For = time.Minute
+	a := NewAlerter(p, nil, rule)
+	a.Clock = c
+
+	cb.Break()
+	if alerts := a.Evaluate(); len(alerts) != 0 {
+		t.Fatalf("expected no alert before the condition held for a minute, got %+v", alerts)
+	}
+	c.Add(time.Minute)
+	alerts := a.Evaluate()
+	if len(alerts) != 1 || !alerts[0].Since.Equal(c.Now().Add(-time.Minute)) {
+		t.Fatalf("expected an alert since the first evaluation, got %+v", alerts)
+	}
+}
@@ -0,0 +1,195 @@
+// Package awscircuit provides smithy middleware that protects AWS SDK v2 calls
+// with one circuit breaker per service.
+//
+// Throttling responses are classified separately from server errors. A
+// throttled attempt means the service is healthy but busy, so it is neither
+// counted as a failure nor as a success; this lets breakers trip quickly on
+// 5xx brownouts without tripping on ordinary request throttling.
+//
+// The middleware is installed through a client's APIOptions:
+//
+//	m := awscircuit.New(nil)
+//	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+//		o.APIOptions = append(o.APIOptions, m.AddToStack)
+//	})
+package awscircuit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+// Outcome classifies the result of a single AWS API call attempt.
+type Outcome int
+
+const (
+	// Success indicates the service handled the request. Client errors such
+	// as a missing key or failed validation are successes.
+	Success Outcome = iota
+
+	// Failure indicates the service failed, either with a 5xx response or by
+	// not producing a response at all.
+	Failure
+
+	// Throttle indicates the service rejected the request because of rate
+	// limits. Throttles are not recorded on the breaker.
+	Throttle
+
+	// Ignore indicates an outcome that says nothing about the service, such as
+	// the caller canceling the request.
+	Ignore
+)
+
+var throttles = retry.IsErrorThrottles(retry.DefaultThrottles)
+
+// Classify is the default classification of the error returned by an attempt.
+// HTTP 429 responses and the SDK's throttle error codes are Throttle, 5xx
+// responses and errors without a response are Failure, and canceled requests
+// are ignored.
+func Classify(err error) Outcome {
+	if err == nil {
+		return Success
+	}
+	if errors.Is(err, context.Canceled) {
+		return Ignore
+	}
+	if throttles.IsErrorThrottle(err) == aws.TrueTernary {
+		return Throttle
+	}
+
+	var re interface{ HTTPStatusCode() int }
+	if errors.As(err, &re) {
+		switch code := re.HTTPStatusCode(); {
+		case code == 429:
+			return Throttle
+		case code >= 500:
+			return Failure
+		case code != 0:
+			return Success
+		}
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorFault() != smithy.FaultServer {
+		return Success
+	}
+	return Failure
+}
+
+// Options configures the middleware.
+type Options struct {
+	// Panel holds the breakers, named by service ID (e.g. "S3", "DynamoDB").
+	// A new Panel is used if it is nil.
+	Panel *circuit.Panel
+
+	// NewBreaker creates the breaker for a service the first time it is
+	// called. By default each service gets a rate breaker that trips at a 50%
+	// error rate over at least 20 attempts.
+	NewBreaker func(service string) *circuit.Breaker
+
+	// Classify classifies attempt errors. Defaults to Classify.
+	Classify func(error) Outcome
+
+	// OnThrottle, if non-nil, is called for each throttled attempt.
+	OnThrottle func(service string, err error)
+}
+
+// Middleware is a finalize step middleware that wraps each attempt of an AWS
+// API call in the breaker for the call's service. Because it runs after the
+// SDK's retry middleware, every attempt is recorded and retries stop as soon
+// as the breaker opens.
+type Middleware struct {
+	opts Options
+	mu   sync.Mutex
+}
+
+// New creates a Middleware. opts may be nil.
+func New(opts *Options) *Middleware {
+	m := &Middleware{}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.Panel == nil {
+		m.opts.Panel = circuit.NewPanel()
+	}
+	if m.opts.NewBreaker == nil {
+		m.opts.NewBreaker = func(string) *circuit.Breaker {
+			return circuit.NewRateBreaker(0.5, 20)
+		}
+	}
+	if m.opts.Classify == nil {
+		m.opts.Classify = Classify
+	}
+	return m
+}
+
+// AddToStack adds the middleware to the end of the finalize step. It has the
+// signature of an entry in a service client's APIOptions.
+func (m *Middleware) AddToStack(stack *middleware.Stack) error {
+	return stack.Finalize.Add(m, middleware.After)
+}
+
+// Panel returns the panel holding the per-service breakers.
+func (m *Middleware) Panel() *circuit.Panel {
+	return m.opts.Panel
+}
+
+// Breaker returns the breaker for service, creating it if needed.
+func (m *Middleware) Breaker(service string) *circuit.Breaker {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cb, ok := m.opts.Panel.Get(service)
+	if !ok {
+		cb = m.opts.NewBreaker(service)
+		m.opts.Panel.Add(service, cb)
+	}
+	return cb
+}
+
+// ID implements middleware.FinalizeMiddleware.
+func (m *Middleware) ID() string {
+	return "CircuitBreaker"
+}
+
+// HandleFinalize implements middleware.FinalizeMiddleware. Attempts made while
+// the service's breaker is open fail with an error wrapping
+// circuit.ErrBreakerOpen without being sent, or circuit.ErrUnavailable while
+// it is marked unavailable. Trial attempts count against the breaker's
+// MaxProbes.
+func (m *Middleware) HandleFinalize(
+	ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler,
+) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	service := awsmiddleware.GetServiceID(ctx)
+	cb := m.Breaker(service)
+	token, err := cb.Acquire()
+	if err != nil {
+		return middleware.FinalizeOutput{}, middleware.Metadata{},
+			fmt.Errorf("awscircuit: %s: %w", service, err)
+	}
+
+	out, md, err := next.HandleFinalize(ctx, in)
+	switch m.opts.Classify(err) {
+	case Success:
+		token.Done(nil)
+	case Failure:
+		token.Done(err)
+	case Throttle:
+		token.Release()
+		if m.opts.OnThrottle != nil {
+			m.opts.OnThrottle(service, err)
+		}
+	default:
+		token.Release()
+	}
+	return out, md, err
+}
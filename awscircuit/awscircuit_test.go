@@ -0,0 +1,101 @@
+package awscircuit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+func responseError(code int, err error) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: code}},
+			Err:      err,
+		},
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Outcome
+	}{
+		{nil, Success},
+		{context.Canceled, Ignore},
+		{errors.New("connection refused"), Failure},
+		{responseError(500, errors.New("internal error")), Failure},
+		{responseError(503, &smithy.GenericAPIError{Code: "SlowDown"}), Throttle},
+		{responseError(400, &smithy.GenericAPIError{Code: "ThrottlingException"}), Throttle},
+		{responseError(429, errors.New("too many requests")), Throttle},
+		{responseError(404, &smithy.GenericAPIError{Code: "NoSuchKey"}), Success},
+	}
+	for _, test := range tests {
+		if got := Classify(test.err); got != test.want {
+			t.Errorf("Classify(%v) = %d, want %d", test.err, got, test.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	throttled := 0
+	m := New(&Options{
+		NewBreaker: func(string) *circuit.Breaker {
+			return circuit.NewConsecutiveBreaker(2)
+		},
+		OnThrottle: func(service string, err error) {
+			throttled++
+		},
+	})
+
+	var result error
+	sent := 0
+	next := middleware.FinalizeHandlerFunc(func(
+		ctx context.Context, in middleware.FinalizeInput,
+	) (middleware.FinalizeOutput, middleware.Metadata, error) {
+		sent++
+		return middleware.FinalizeOutput{}, middleware.Metadata{}, result
+	})
+	ctx := awsmiddleware.SetServiceID(context.Background(), "S3")
+	call := func(err error) error {
+		result = err
+		_, _, err = m.HandleFinalize(ctx, middleware.FinalizeInput{}, next)
+		return err
+	}
+
+	for i := 0; i < 5; i++ {
+		call(responseError(429, errors.New("slow down")))
+	}
+	if throttled != 5 {
+		t.Fatalf("expected 5 throttles, got %d", throttled)
+	}
+	cb := m.Breaker("S3")
+	if cb.Failures() != 0 || cb.Tripped() {
+		t.Fatal("expected throttles not to count as failures")
+	}
+
+	call(responseError(500, errors.New("internal error")))
+	call(responseError(502, errors.New("bad gateway")))
+	if !cb.Tripped() {
+		t.Fatal("expected server errors to trip the breaker")
+	}
+
+	sent = 0
+	if err := call(nil); !errors.Is(err, circuit.ErrBreakerOpen) {
+		t.Fatalf("expected ErrBreakerOpen, got %v", err)
+	}
+	if sent != 0 {
+		t.Fatal("expected attempt not to be sent while the breaker is open")
+	}
+
+	if dynamo := m.Breaker("DynamoDB"); dynamo.Tripped() {
+		t.Fatal("expected breakers to be per service")
+	}
+}
@@ -4,9 +4,9 @@ package circuit
 
 import "strconv"
 
-const _BreakerEvent_name = "BreakerTrippedBreakerResetBreakerFailBreakerReady"
+const _BreakerEvent_name = "BreakerTrippedBreakerResetBreakerFailBreakerReadyBreakerAddedBreakerRemovedBreakerCallComplete"
 
-var _BreakerEvent_index = [...]uint8{0, 14, 26, 37, 49}
+var _BreakerEvent_index = [...]uint8{0, 14, 26, 37, 49, 61, 75, 94}
 
 func (i BreakerEvent) String() string {
 	if i < 0 || i >= BreakerEvent(len(_BreakerEvent_index)-1) {
@@ -0,0 +1,66 @@
+package circuit
+
+import "fmt"
+
+// ErrorBudget is a budget of failures shared by a group of breakers in a
+// Panel, such as those of the shards of one dependency. See Panel.ShareBudget.
+type ErrorBudget struct {
+	// Failures is the number of failures the group may record in the budget's
+	// window before the budget is exhausted.
+	Failures int64
+
+	// Rate, if non-zero, exhausts the budget when the error rate over the
+	// calls of the group reaches it, once there have been at least MinSamples
+	// calls, instead of after a number of Failures.
+	Rate       float64
+	MinSamples int64
+
+	// Options configure the budget's breaker, such as its window and BackOff.
+	// Its ShouldTrip is set from the fields above.
+	Options *Options
+}
+
+// tripFunc returns the TripFunc that trips when the budget is exhausted.
+func (b ErrorBudget) tripFunc() TripFunc {
+	if b.Rate > 0 {
+		return RateTripFunc(b.Rate, b.MinSamples)
+	}
+	return ThresholdTripFunc(b.Failures)
+}
+
+// ShareBudget makes the breakers named members draw on a shared budget. The
+// outcomes of their calls are also recorded on a breaker added under name,
+// which trips when the group exhausts the budget, even if no member has
+// tripped on its own failures, and then rejects the calls of every member
+// until it lets calls through again. The budget's breaker is the members'
+// parent, as if they had been created with it as Options.Parent, and further
+// breakers can join the budget that way.
+//
+// ShareBudget must be called before the members are used. It fails, without
+// changing the panel, if a member is missing from the panel or already has a
+// parent.
+func (p *Panel) ShareBudget(name string, budget ErrorBudget, members ...string) (*Breaker, error) {
+	breakers := make([]*Breaker, len(members))
+	for i, member := range members {
+		cb, ok := p.Get(member)
+		if !ok {
+			return nil, fmt.Errorf("circuit: no breaker %q to share budget %q", member, name)
+		}
+		if cb.parent != nil {
+			return nil, fmt.Errorf("circuit: breaker %q already has a parent", member)
+		}
+		breakers[i] = cb
+	}
+
+	var opts Options
+	if budget.Options != nil {
+		opts = *budget.Options
+	}
+	opts.ShouldTrip = budget.tripFunc()
+	parent := p.AddWithOptions(name, &opts)
+	for _, cb := range breakers {
+		cb.parent = parent
+		parent.addChild(cb)
+	}
+	return parent, nil
+}
@@ -0,0 +1,60 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestShareBudget(t *testing.T) {
+	p := NewPanel()
+	shards := []string{"shard-0", "shard-1", "shard-2"}
+	for _, name := range shards {
+		p.AddWithOptions(name, &Options{ShouldTrip: ThresholdTripFunc(5)})
+	}
+
+	budget, err := p.ShareBudget("shards", ErrorBudget{Failures: 6}, shards...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb, ok := p.Get("shards"); !ok || cb != budget {
+		t.Fatal("expected the budget's breaker to be added to the panel")
+	}
+
+	fail := func() error { return errors.New("failed") }
+	for i := 0; i < 2; i++ {
+		for _, name := range shards {
+			cb, _ := p.Get(name)
+			cb.Call(fail, 0)
+		}
+	}
+	if !budget.Tripped() {
+		t.Fatalf("expected the budget to trip after %d failures", budget.Failures())
+	}
+	for _, name := range shards {
+		cb, _ := p.Get(name)
+		if cb.Tripped() {
+			t.Fatalf("expected %s not to trip on its own failures", name)
+		}
+		if err := cb.Call(func() error { return nil }, 0); err != ErrBreakerOpen {
+			t.Fatalf("expected %s to reject calls while the budget is exhausted, got %v", name, err)
+		}
+	}
+}
+
+func TestShareBudgetErrors(t *testing.T) {
+	p := NewPanel()
+	p.Add("a", NewBreaker())
+	if _, err := p.ShareBudget("budget", ErrorBudget{Failures: 1}, "a", "missing"); err == nil {
+		t.Fatal("expected an error for a missing member")
+	}
+	if _, ok := p.Get("budget"); ok {
+		t.Fatal("expected a failed ShareBudget not to add a breaker")
+	}
+
+	if _, err := p.ShareBudget("budget", ErrorBudget{Rate: 0.5, MinSamples: 10}, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ShareBudget("other", ErrorBudget{Failures: 1}, "a"); err == nil {
+		t.Fatal("expected an error for a member that already has a parent")
+	}
+}
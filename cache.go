@@ -0,0 +1,207 @@
+package circuit
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ResultCache stores the results of calls made with CachedCall, so that they
+// can be served while the breaker is open. Implementations must be safe for
+// concurrent use. See Options.Cache and NewLRUCache.
+type ResultCache interface {
+	// Get returns the value added under key, if there is one.
+	Get(key string) (value interface{}, ok bool)
+
+	// Add stores value under key, replacing any value already there.
+	Add(key string, value interface{})
+}
+
+// LRUCache is an in-memory ResultCache holding a fixed number of values. When
+// it is full, adding a value evicts the least recently used one.
+type LRUCache struct {
+	lock  sync.Mutex
+	size  int
+	order *list.List // of *lruEntry, most recently used first
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+// NewLRUCache creates an LRUCache holding up to size values. It panics if
+// size is not positive.
+func NewLRUCache(size int) *LRUCache {
+	if size <= 0 {
+		panic("circuit: NewLRUCache requires a positive size")
+	}
+	return &LRUCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// Get returns the value added under key, if it has not been evicted, and marks
+// it as the most recently used.
+func (c *LRUCache) Get(key string) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+// Add stores value under key as the most recently used value, evicting the
+// least recently used one if the cache is full.
+func (c *LRUCache) Add(key string, value interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e, ok := c.items[key]; ok {
+		e.Value.(*lruEntry).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of values in the cache.
+func (c *LRUCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
+
+// CachedCall makes a call with fn through cb, as with Do, and returns its
+// result. If the breaker has a Cache, results of successful calls are added to
+// it under key, and a call the breaker rejects because it is open or
+// unavailable returns the cached result for key instead of the error, if
+// there is one. This suits idempotent reads, for which slightly stale data
+// beats an error:
+//
+//	user, err := circuit.CachedCall(ctx, cb, "user/"+id, func(ctx context.Context) (*User, error) {
+//		return client.GetUser(ctx, id)
+//	}, 0)
+//
+// Calls served from the cache are still counted as rejected, and are counted
+// by Breaker.CacheServed too. Calls that fail are not served from the cache.
+//
+// Under Options.StaleWhileRevalidate, a call for a cached key made while the
+// breaker is not closed is served from the cache without being counted as
+// rejected. If the breaker is ready for a trial call, fn is called in the
+// background as the trial, with a context carrying the values of ctx but not
+// its cancellation, and its result updates the cache if it succeeds.
+func CachedCall[T any](ctx context.Context, cb *Breaker, key string, fn func(ctx context.Context) (T, error), timeout time.Duration) (T, error) {
+	if cb.revalidate && cb.cache != nil && cb.State() != StateClosed {
+		if value, ok := cachedResult[T](cb, key); ok {
+			if token, ok := cb.TryProbe(); ok {
+				go revalidate(detachedContext{ctx}, cb, token, key, fn, timeout)
+			}
+			atomic.AddInt64(&cb.cacheServed, 1)
+			return value, nil
+		}
+	}
+
+	result := make(chan T, 1)
+	err := cb.Do(ctx, func(ctx context.Context) error {
+		value, err := fn(ctx)
+		if err != nil {
+			return err
+		}
+		result <- value
+		return nil
+	}, timeout)
+
+	if err == nil {
+		value := <-result
+		if cb.cache != nil {
+			cb.cache.Add(key, value)
+		}
+		return value, nil
+	}
+
+	var zero T
+	if cb.cache == nil || !rejectedOpen(err) {
+		return zero, err
+	}
+	value, ok := cachedResult[T](cb, key)
+	if !ok {
+		return zero, err
+	}
+	atomic.AddInt64(&cb.cacheServed, 1)
+	return value, nil
+}
+
+// cachedResult returns the result cached under key, if there is one of type T.
+func cachedResult[T any](cb *Breaker, key string) (T, bool) {
+	cached, ok := cb.cache.Get(key)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	value, ok := cached.(T)
+	return value, ok
+}
+
+// revalidate makes the trial call of token with fn, as Do would, and caches
+// its result under key if it succeeds. The trial call's slot is freed once it
+// returns or times out, even if fn ignores the cancellation of its context.
+// It runs in the background, where no caller could handle a panic, so a panic
+// in fn is recorded as a failure with a *PanicError whatever the breaker's
+// PanicPolicy, and is not raised again.
+func revalidate[T any](ctx context.Context, cb *Breaker, token ProbeToken, key string, fn func(ctx context.Context) (T, error), timeout time.Duration) {
+	defer func() {
+		// Under PanicRepanic, the call raises the panic it recorded again,
+		// but there is no caller to raise it to.
+		recover()
+	}()
+	result := make(chan T, 1)
+	err := cb.do(ctx, func(ctx context.Context) error {
+		return recoverPanics(func() error {
+			value, err := fn(ctx)
+			if err != nil {
+				return err
+			}
+			result <- value
+			return nil
+		})()
+	}, timeout, &token)
+	if err == nil {
+		cb.cache.Add(key, <-result)
+	}
+}
+
+// detachedContext carries the values of its parent but not its deadline or
+// cancellation, for work that outlives the call that started it.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// CacheServed returns the number of calls made with CachedCall that were
+// served from the breaker's Cache because it was open.
+func (cb *Breaker) CacheServed() int64 {
+	return atomic.LoadInt64(&cb.cacheServed)
+}
+
+// rejectedOpen reports whether err is that of a call rejected because the
+// breaker was open or unavailable.
+func rejectedOpen(err error) bool {
+	return errors.Is(err, ErrBreakerOpen) || errors.Is(err, ErrUnavailable) || errors.Is(err, ErrQueueTimeout)
+}
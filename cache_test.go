@@ -0,0 +1,223 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/facebookgo/clock"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := NewLRUCache(2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("expected a to be 1, got %v, %v", v, ok)
+	}
+
+	// b is now the least recently used.
+	c.Add("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Fatalf("expected c to be 3, got %v, %v", v, ok)
+	}
+
+	c.Add("a", 4)
+	if v, _ := c.Get("a"); v != 4 {
+		t.Fatalf("expected a to be replaced by 4, got %v", v)
+	}
+	if n := c.Len(); n != 2 {
+		t.Fatalf("expected 2 values, got %d", n)
+	}
+}
+
+func TestCachedCall(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{Cache: NewLRUCache(10)})
+	ctx := context.Background()
+	read := func(value string, err error) func(context.Context) (string, error) {
+		return func(context.Context) (string, error) {
+			return value, err
+		}
+	}
+
+	v, err := CachedCall(ctx, cb, "k", read("fresh", nil), 0)
+	if err != nil || v != "fresh" {
+		t.Fatalf("expected fresh result, got %q, %v", v, err)
+	}
+
+	errRead := errors.New("read failed")
+	if _, err := CachedCall(ctx, cb, "k", read("", errRead), 0); err != errRead {
+		t.Fatalf("expected a failed call not to be served from the cache, got %v", err)
+	}
+
+	cb.Trip()
+	v, err = CachedCall(ctx, cb, "k", read("unreachable", nil), 0)
+	if err != nil || v != "fresh" {
+		t.Fatalf("expected cached result while open, got %q, %v", v, err)
+	}
+	if _, err := CachedCall(ctx, cb, "other", read("unreachable", nil), 0); err != ErrBreakerOpen {
+		t.Fatalf("expected ErrBreakerOpen for an uncached key, got %v", err)
+	}
+	if n := cb.CacheServed(); n != 1 {
+		t.Fatalf("expected 1 call served from the cache, got %d", n)
+	}
+	if s := cb.Stats(); s.CacheServed != 1 || s.Rejects != 2 {
+		t.Fatalf("expected 1 cache-served call and 2 rejects in stats, got %d and %d", s.CacheServed, s.Rejects)
+	}
+}
+
+func TestCachedCallWithoutCache(t *testing.T) {
+	cb := NewBreaker()
+	ctx := context.Background()
+	CachedCall(ctx, cb, "k", func(context.Context) (int, error) { return 1, nil }, 0)
+	cb.Trip()
+	if _, err := CachedCall(ctx, cb, "k", func(context.Context) (int, error) { return 2, nil }, 0); err != ErrBreakerOpen {
+		t.Fatalf("expected ErrBreakerOpen without a cache, got %v", err)
+	}
+}
+
+func TestCachedCallStaleWhileRevalidate(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	cb := NewBreakerWithOptions(&Options{
+		Clock:                c,
+		BackOff:              &backoff.ConstantBackOff{Interval: time.Second},
+		Cache:                NewLRUCache(10),
+		StaleWhileRevalidate: true,
+	})
+	ctx := context.Background()
+
+	CachedCall(ctx, cb, "k", func(context.Context) (string, error) { return "v1", nil }, 0)
+	cb.Trip()
+
+	called := false
+	v, err := CachedCall(ctx, cb, "k", func(context.Context) (string, error) {
+		called = true
+		return "v2", nil
+	}, 0)
+	if err != nil || v != "v1" || called {
+		t.Fatalf("expected stale result without a call while open, got %q, %v, called %v", v, err, called)
+	}
+	if r := cb.Rejects(); r != 0 {
+		t.Fatalf("expected no rejects, got %d", r)
+	}
+
+	c.Add(5 * time.Second)
+	release := make(chan struct{})
+	done := make(chan struct{})
+	reqCtx, cancel := context.WithCancel(ctx)
+	v, err = CachedCall(reqCtx, cb, "k", func(ctx context.Context) (string, error) {
+		defer close(done)
+		<-release
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "v2", nil
+	}, 0)
+	if err != nil || v != "v1" {
+		t.Fatalf("expected stale result while revalidating, got %q, %v", v, err)
+	}
+	cancel()
+	close(release)
+	<-done
+
+	for cb.Tripped() {
+		time.Sleep(time.Millisecond)
+	}
+	if v, _ := cb.cache.Get("k"); v != "v2" {
+		t.Fatalf("expected the trial call to update the cache, got %v", v)
+	}
+	if n := cb.CacheServed(); n != 2 {
+		t.Fatalf("expected 2 calls served from the cache, got %d", n)
+	}
+}
+
+func TestCachedCallRevalidatePanic(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := NewBreakerWithOptions(&Options{
+		Clock:                c,
+		BackOff:              &backoff.ConstantBackOff{Interval: time.Second},
+		Cache:                NewLRUCache(10),
+		StaleWhileRevalidate: true,
+	})
+	ctx := context.Background()
+
+	CachedCall(ctx, cb, "k", func(context.Context) (string, error) { return "v1", nil }, 0)
+	cb.Trip()
+	c.Add(5 * time.Second)
+	v, err := CachedCall(ctx, cb, "k", func(context.Context) (string, error) {
+		panic("boom")
+	}, 0)
+	if err != nil || v != "v1" {
+		t.Fatalf("expected stale result while revalidating, got %q, %v", v, err)
+	}
+
+	for cb.Failures() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if !cb.Tripped() {
+		t.Fatal("expected the panicking trial call to keep the breaker tripped")
+	}
+	// The trial call's slot is freed once its failure is recorded.
+	c.Add(5 * time.Second)
+	for {
+		if _, ok := cb.TryProbe(); ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCachedCallRevalidateTimeout(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{
+		BackOff:              &backoff.ConstantBackOff{Interval: time.Millisecond},
+		Cache:                NewLRUCache(10),
+		StaleWhileRevalidate: true,
+		PanicPolicy:          PanicRepanic,
+	})
+	ctx := context.Background()
+
+	CachedCall(ctx, cb, "k", func(context.Context) (string, error) { return "v1", nil }, 0)
+	cb.Trip()
+	time.Sleep(5 * time.Millisecond)
+	block := make(chan struct{})
+	defer close(block)
+	v, err := CachedCall(ctx, cb, "k", func(context.Context) (string, error) {
+		<-block // Ignores the cancellation of its context.
+		return "v2", nil
+	}, 10*time.Millisecond)
+	if err != nil || v != "v1" {
+		t.Fatalf("expected stale result while revalidating, got %q, %v", v, err)
+	}
+
+	for cb.Failures() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if a := cb.Abandoned(); a != 1 {
+		t.Fatalf("expected the timed out trial call to be abandoned, got %d", a)
+	}
+	// The trial call's slot is freed once it times out, though fn is still
+	// running.
+	for {
+		if token, ok := cb.TryProbe(); ok {
+			token.Release()
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Under PanicRepanic, a panicking trial call is recorded without
+	// crashing the process.
+	time.Sleep(5 * time.Millisecond)
+	failures := cb.Failures()
+	CachedCall(ctx, cb, "k", func(context.Context) (string, error) { panic("boom") }, 0)
+	for cb.Failures() == failures {
+		time.Sleep(time.Millisecond)
+	}
+}
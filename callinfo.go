@@ -0,0 +1,31 @@
+package circuit
+
+import "context"
+
+// CallInfo describes a call made through a breaker with Do. It is carried by
+// the context passed to the call's function, so that code downstream of a
+// breaker, and its logs, can tell trial calls from normal traffic:
+//
+//	if info, ok := circuit.CallInfoFromContext(ctx); ok && info.Probe {
+//		req.Header.Set("X-Probe", strconv.FormatInt(info.Attempt, 10))
+//	}
+type CallInfo struct {
+	// Breaker is the name of the breaker. See Options.Name.
+	Breaker string
+
+	// Probe is true for a trial call made while the breaker is half-open.
+	Probe bool
+
+	// Attempt numbers the trial calls made since the breaker last tripped,
+	// from 1. It is 0 for calls made while the breaker is closed.
+	Attempt int64
+}
+
+type callInfoKey struct{}
+
+// CallInfoFromContext returns the CallInfo carried by ctx. Inside calls made
+// through several breakers, it is that of the innermost breaker.
+func CallInfoFromContext(ctx context.Context) (CallInfo, bool) {
+	info, ok := ctx.Value(callInfoKey{}).(CallInfo)
+	return info, ok
+}
@@ -0,0 +1,62 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/facebookgo/clock"
+)
+
+func TestCallInfo(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	cb := NewBreakerWithOptions(&Options{
+		Clock:      c,
+		BackOff:    &backoff.ConstantBackOff{Interval: time.Second},
+		ShouldTrip: ThresholdTripFunc(1),
+		Name:       "db",
+	})
+	ctx := context.Background()
+	if _, ok := CallInfoFromContext(ctx); ok {
+		t.Fatal("expected a plain context to carry no CallInfo")
+	}
+
+	var info CallInfo
+	call := func(err error) {
+		cb.Do(ctx, func(ctx context.Context) error {
+			info, _ = CallInfoFromContext(ctx)
+			return err
+		}, 0)
+	}
+
+	call(nil)
+	if want := (CallInfo{Breaker: "db"}); info != want {
+		t.Fatalf("expected %+v, got %+v", want, info)
+	}
+
+	cb.Trip()
+	c.Add(2 * time.Second)
+	call(errors.New("still down"))
+	if want := (CallInfo{Breaker: "db", Probe: true, Attempt: 1}); info != want {
+		t.Fatalf("expected %+v, got %+v", want, info)
+	}
+	c.Add(2 * time.Second)
+	call(nil)
+	if want := (CallInfo{Breaker: "db", Probe: true, Attempt: 2}); info != want {
+		t.Fatalf("expected %+v, got %+v", want, info)
+	}
+
+	cb.Trip()
+	c.Add(2 * time.Second)
+	wrapped := Wrap(cb, func(ctx context.Context) error {
+		info, _ = CallInfoFromContext(ctx)
+		return nil
+	})
+	wrapped(ctx)
+	if want := (CallInfo{Breaker: "db", Probe: true, Attempt: 1}); info != want {
+		t.Fatalf("expected attempts to restart after a trip, got %+v", info)
+	}
+}
@@ -0,0 +1,64 @@
+package circuit
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CanaryPolicy configures a canary window: a short period after a breaker
+// closes during which calls are watched more strictly than ShouldTrip does,
+// so a backend that recovers and immediately regresses is cut off again
+// quickly.
+type CanaryPolicy struct {
+	// Window is how long after closing the breaker is on canary.
+	Window time.Duration
+
+	// Rate is the error rate over the calls made during the canary window at
+	// which the breaker reopens.
+	Rate float64
+
+	// MinSamples is the number of calls that must have been made during the
+	// canary window before Rate is applied.
+	MinSamples int64
+}
+
+// canaryCounts counts the outcomes of the calls made during a canary window.
+type canaryCounts struct {
+	failures  int64
+	successes int64
+}
+
+// inCanary reports whether the breaker has recovered from a trip less than its
+// canary window ago.
+func (cb *Breaker) inCanary() bool {
+	if cb.canary == nil || cb.Tripped() || atomic.LoadInt64(&cb.recoveries) == 0 {
+		return false
+	}
+	return cb.closedFor() < cb.canary.Window
+}
+
+// canarySuccess records n successes during the canary window.
+func (cb *Breaker) canarySuccess(n int64) {
+	if cb.inCanary() {
+		atomic.AddInt64(&cb.canaryCounts.successes, n)
+	}
+}
+
+// canaryFail records n failures during the canary window and reports whether
+// the breaker should reopen.
+func (cb *Breaker) canaryFail(n int64) bool {
+	if !cb.inCanary() {
+		return false
+	}
+	failures := atomic.AddInt64(&cb.canaryCounts.failures, n)
+	total := failures + atomic.LoadInt64(&cb.canaryCounts.successes)
+	return total >= cb.canary.MinSamples && float64(failures)/float64(total) >= cb.canary.Rate
+}
+
+// escalateBackOff moves the breaker's BackOff on by an interval, so a backend
+// that failed its canary window is retried later than it was last time.
+func (cb *Breaker) escalateBackOff() {
+	cb.backoffLock.Lock()
+	cb.nextBackOff = cb.BackOff.NextBackOff()
+	cb.backoffLock.Unlock()
+}
@@ -0,0 +1,64 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/facebookgo/clock"
+)
+
+func TestCanaryWindow(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	b := &backoff.ExponentialBackOff{
+		InitialInterval: time.Second,
+		Multiplier:      2,
+		MaxInterval:     time.Hour,
+		Clock:           c,
+	}
+	b.Reset()
+	cb := NewBreakerWithOptions(&Options{
+		BackOff:    b,
+		Clock:      c,
+		ShouldTrip: ConsecutiveTripFunc(5),
+		Canary:     &CanaryPolicy{Window: time.Second, Rate: 0.5, MinSamples: 2},
+	})
+
+	recoverBreaker := func() {
+		c.Add(cb.BackOffInterval() + time.Millisecond)
+		if !cb.Ready() {
+			t.Fatal("expected breaker to be ready to retry")
+		}
+		cb.Success()
+		if cb.Tripped() {
+			t.Fatal("expected the successful trial call to reset the breaker")
+		}
+	}
+
+	cb.Trip()
+	recoverBreaker()
+	if d := cb.BackOffInterval(); d != 2*time.Second {
+		t.Fatalf("expected the backoff not to be reset during the canary window, got %v", d)
+	}
+
+	cb.Success()
+	cb.Fail(nil)
+	if !cb.Tripped() {
+		t.Fatal("expected failing the canary window to reopen the breaker")
+	}
+	if d := cb.BackOffInterval(); d != 4*time.Second {
+		t.Fatalf("expected failing the canary window to escalate the backoff, got %v", d)
+	}
+
+	recoverBreaker()
+	c.Add(2 * time.Second)
+	cb.Success()
+	cb.Fail(nil)
+	if cb.Tripped() {
+		t.Fatal("expected failures after the canary window to be left to ShouldTrip")
+	}
+	if d := cb.BackOffInterval(); d != time.Second {
+		t.Fatalf("expected a success after the canary window to reset the backoff, got %v", d)
+	}
+}
@@ -0,0 +1,132 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// FailureCategory is the kind of failure a call failed with. Breakers count
+// the failures in their window by category, so a TripFunc can tell a
+// dependency that is down from one that is slow or answering with errors. See
+// Breaker.CategoryRate.
+type FailureCategory string
+
+// The categories of failure CategorizeError tells apart. Options.Categorize
+// may return categories of its own as well.
+const (
+	FailureTimeout     FailureCategory = "timeout"
+	FailureConnection  FailureCategory = "connection"
+	FailureServerError FailureCategory = "server_error"
+	FailureCanceled    FailureCategory = "canceled"
+	FailureOther       FailureCategory = "other"
+)
+
+// CategorizeError returns the category of err:
+//
+//   - FailureTimeout for ErrBreakerTimeout, context.DeadlineExceeded and
+//     network errors that timed out
+//   - FailureCanceled for context.Canceled
+//   - FailureConnection for other network errors, such as refused or reset
+//     connections and failed DNS lookups
+//   - FailureServerError for errors with a StatusCode or HTTPStatusCode
+//     method returning a 5xx status
+//   - FailureOther for anything else, including a nil error
+func CategorizeError(err error) FailureCategory {
+	if err == nil {
+		return FailureOther
+	}
+	if errors.Is(err, ErrBreakerTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return FailureTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return FailureCanceled
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return FailureTimeout
+	}
+	if netErr != nil || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return FailureConnection
+	}
+	var status interface{ StatusCode() int }
+	if errors.As(err, &status) && status.StatusCode() >= 500 {
+		return FailureServerError
+	}
+	var httpStatus interface{ HTTPStatusCode() int }
+	if errors.As(err, &httpStatus) && httpStatus.HTTPStatusCode() >= 500 {
+		return FailureServerError
+	}
+	return FailureOther
+}
+
+// categorize returns the category of a failure with err, using the breaker's
+// Options.Categorize if it has one.
+func (cb *Breaker) categorize(err error) FailureCategory {
+	if cb.categorizer != nil {
+		if c := cb.categorizer(err); c != "" {
+			return c
+		}
+	}
+	return CategorizeError(err)
+}
+
+// CategoryFailures returns the number of failures of category c in the
+// breaker's window.
+func (cb *Breaker) CategoryFailures(c FailureCategory) int64 {
+	return cb.counts.CategoryFailures(c)
+}
+
+// CategoryRate returns the fraction of the calls in the breaker's window that
+// failed with a failure of category c, or 0 if there have been no calls.
+func (cb *Breaker) CategoryRate(c FailureCategory) float64 {
+	samples := cb.Samples()
+	if samples == 0 {
+		return 0
+	}
+	return float64(cb.CategoryFailures(c)) / float64(samples)
+}
+
+// CategoryRateTripFunc returns a TripFunc that trips whenever the rate of
+// failures of category c reaches rate, once there have been minSamples calls
+// in the window. For example, to trip when a tenth of calls fail to connect,
+// however many time out:
+//
+//	circuit.CategoryRateTripFunc(circuit.FailureConnection, 0.1, 20)
+func CategoryRateTripFunc(c FailureCategory, rate float64, minSamples int64) TripFunc {
+	return func(cb *Breaker) bool {
+		return cb.Samples() >= minSamples && cb.CategoryRate(c) >= rate
+	}
+}
+
+// CategoryFailures returns the failures of category c in all buckets.
+func (w *window) CategoryFailures(c FailureCategory) int64 {
+	w.bucketLock.RLock()
+	var failures int64
+	w.buckets.Do(func(x interface{}) {
+		failures += x.(*bucket).category[c]
+	})
+	w.bucketLock.RUnlock()
+	return failures
+}
+
+// Categories returns the failures in all buckets by category, or nil if there
+// are none.
+func (w *window) Categories() map[FailureCategory]int64 {
+	w.bucketLock.RLock()
+	var categories map[FailureCategory]int64
+	w.buckets.Do(func(x interface{}) {
+		for c, n := range x.(*bucket).category {
+			if n == 0 {
+				continue
+			}
+			if categories == nil {
+				categories = make(map[FailureCategory]int64)
+			}
+			categories[c] += n
+		}
+	})
+	w.bucketLock.RUnlock()
+	return categories
+}
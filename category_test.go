@@ -0,0 +1,74 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+)
+
+type statusError int
+
+func (e statusError) Error() string   { return fmt.Sprintf("status %d", int(e)) }
+func (e statusError) StatusCode() int { return int(e) }
+
+func TestCategorizeError(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		want FailureCategory
+	}{
+		{ErrBreakerTimeout, FailureTimeout},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), FailureTimeout},
+		{context.Canceled, FailureCanceled},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, FailureConnection},
+		{&net.DNSError{Err: "no such host", IsTimeout: true}, FailureTimeout},
+		{syscall.ECONNRESET, FailureConnection},
+		{statusError(503), FailureServerError},
+		{statusError(404), FailureOther},
+		{errors.New("boom"), FailureOther},
+		{nil, FailureOther},
+	} {
+		if got := CategorizeError(test.err); got != test.want {
+			t.Errorf("CategorizeError(%v) = %q, want %q", test.err, got, test.want)
+		}
+	}
+}
+
+func TestCategoryRate(t *testing.T) {
+	errQuota := errors.New("quota exceeded")
+	cb := NewBreakerWithOptions(&Options{
+		Categorize: func(err error) FailureCategory {
+			if err == errQuota {
+				return "quota"
+			}
+			return ""
+		},
+		ShouldTrip: CategoryRateTripFunc(FailureConnection, 0.5, 4),
+	})
+
+	cb.Fail(ErrBreakerTimeout)
+	cb.Fail(errQuota)
+	cb.Fail(syscall.ECONNREFUSED)
+	cb.Success()
+	if cb.Tripped() {
+		t.Fatal("expected 1 connection failure in 4 calls not to trip the breaker")
+	}
+	if got := cb.CategoryRate(FailureTimeout); got != 0.25 {
+		t.Fatalf("expected a timeout rate of 0.25, got %v", got)
+	}
+	if got := cb.CategoryFailures("quota"); got != 1 {
+		t.Fatalf("expected 1 quota failure, got %d", got)
+	}
+	want := map[FailureCategory]int64{FailureTimeout: 1, FailureConnection: 1, "quota": 1}
+	if got := cb.Stats().FailureCategories; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected Stats to report %v, got %v", want, got)
+	}
+
+	cb.Fail(syscall.ECONNREFUSED)
+	cb.Fail(syscall.ECONNREFUSED)
+	if !cb.Tripped() {
+		t.Fatal("expected 3 connection failures in 6 calls to trip the breaker")
+	}
+}
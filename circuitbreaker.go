@@ -33,6 +33,8 @@ package circuit
 import (
 	"context"
 	"errors"
+	"math"
+	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -67,12 +69,36 @@ const (
 
 	// BreakerReady is sent when the breaker enters the half open state and is ready to retry
 	BreakerReady BreakerEvent = iota
+
+	// BreakerAdded is sent by a Panel when a breaker is added to it
+	BreakerAdded BreakerEvent = iota
+
+	// BreakerRemoved is sent by a Panel when a breaker is removed from it
+	BreakerRemoved BreakerEvent = iota
+
+	// BreakerCallComplete is sent when a call made by Call returns or times
+	// out, if the breaker was created with Options.CallEvents
+	BreakerCallComplete BreakerEvent = iota
 )
 
 // ListenerEvent includes a reference to the circuit breaker and the event.
 type ListenerEvent struct {
 	CB    *Breaker
 	Event BreakerEvent
+
+	// NextAttempt is when the breaker will allow a trial call, as returned by
+	// NextAttempt when the event was sent.
+	NextAttempt time.Time
+
+	// Err and Metadata are the error and metadata of the failed call that
+	// caused a BreakerFail or BreakerTripped event, if any. See WithMetadata.
+	// For BreakerCallComplete, they are those of the call, and Err is nil if
+	// it succeeded.
+	Err      error
+	Metadata Metadata
+
+	// Duration is how long the call of a BreakerCallComplete event took.
+	Duration time.Duration
 }
 
 type state int
@@ -83,6 +109,9 @@ const (
 	closed   state = iota
 )
 
+// subscriptionBuffer is the size of the channels returned by Subscribe.
+const subscriptionBuffer = 100
+
 var (
 	defaultInitialBackOffInterval = 500 * time.Millisecond
 	defaultBackoffMaxElapsedTime  = 0 * time.Second
@@ -92,13 +121,103 @@ var (
 var (
 	ErrBreakerOpen    = errors.New("breaker open")
 	ErrBreakerTimeout = errors.New("breaker time out")
+
+	// ErrDeadlineTooShort is returned by CallContext when the context's
+	// deadline would be exceeded before a typical call finishes. See
+	// Options.RejectShortDeadlines.
+	ErrDeadlineTooShort = errors.New("breaker deadline would be exceeded")
+
+	// ErrTooManyAbandoned is returned by Call when the breaker has too many
+	// abandoned calls. See Options.MaxAbandoned.
+	ErrTooManyAbandoned = errors.New("breaker has too many abandoned calls")
+)
+
+// StreakEffect describes how an outcome affects a Breaker's consecutive failure
+// count.
+type StreakEffect int
+
+const (
+	// StreakIncrement counts the outcome as a consecutive failure.
+	StreakIncrement StreakEffect = iota
+
+	// StreakReset resets the consecutive failure count to 0.
+	StreakReset
+
+	// StreakIgnore leaves the consecutive failure count unchanged.
+	StreakIgnore
+)
+
+// ConsecutivePolicy controls how outcomes other than plain failures and
+// successes affect ConsecFailures(). A plain failure always increments the
+// count and a success always resets it.
+type ConsecutivePolicy struct {
+	// Timeouts is applied when a failure is recorded for ErrBreakerTimeout,
+	// which is what Call does when the wrapped function times out.
+	Timeouts StreakEffect
+
+	// Rejections is applied when a call is rejected without being run, which
+	// is what Call does when the breaker is open or turns the call away for
+	// another reason, such as ErrDeadlineTooShort, and what Reject records.
+	Rejections StreakEffect
+}
+
+// DefaultConsecutivePolicy is used by breakers created without a
+// ConsecutivePolicy. Timeouts count as consecutive failures and rejections
+// leave the count unchanged.
+var DefaultConsecutivePolicy = ConsecutivePolicy{
+	Timeouts:   StreakIncrement,
+	Rejections: StreakIgnore,
+}
+
+// EmptyRate describes what a Breaker's ErrorRate() returns when its window
+// holds no calls.
+type EmptyRate int
+
+const (
+	// EmptyRateZero returns 0, as if the breaker were healthy.
+	EmptyRateZero EmptyRate = iota
+
+	// EmptyRateUnknown returns NaN. Comparisons with NaN are always false,
+	// so trip functions such as RateTripFunc do not trip on it, but they can
+	// tell it apart from a healthy rate with math.IsNaN.
+	EmptyRateUnknown
+
+	// EmptyRateLast returns the error rate last computed while the window
+	// held calls, or 0 if it never has.
+	EmptyRateLast
+)
+
+// RateDecay describes how a Breaker's ErrorRate() weighs the buckets of its
+// window.
+type RateDecay int
+
+const (
+	// DecayNone weighs every call in the window equally.
+	DecayNone RateDecay = iota
+
+	// DecayLinear weighs the calls in the newest of n buckets n times as much
+	// as those in the oldest, with the weight falling by 1 per bucket.
+	DecayLinear
+
+	// DecayExponential weighs the calls in each bucket Options.DecayFactor
+	// times as much as those in the next newer bucket.
+	DecayExponential
 )
 
+// DefaultDecayFactor is the DecayFactor used with DecayExponential when
+// Options.DecayFactor is 0.
+const DefaultDecayFactor = 0.5
+
 // TripFunc is a function called by a Breaker's Fail() function and determines whether
 // the breaker should trip. It will receive the Breaker as an argument and returns a
 // boolean. By default, a Breaker has no TripFunc.
 type TripFunc func(*Breaker) bool
 
+// WeightFunc returns the weight of a failure caused by err, so that severe
+// failure modes can count for more than one ordinary failure. Fail adds the
+// weight to the breaker's failure score. Weights below 0 are treated as 0.
+type WeightFunc func(err error) float64
+
 // Breaker is the base of a circuit breaker. It maintains failure and success counters
 // as well as the event subscribers.
 type Breaker struct {
@@ -112,38 +231,338 @@ type Breaker struct {
 	// never automatically trip.
 	ShouldTrip TripFunc
 
-	// Clock is used for controlling time in tests.
+	// Clock is used for controlling time in tests. Set Options.Clock instead
+	// to also drive the breaker's window.
 	Clock clock.Clock
 
 	_              [4]byte // pad to fix golang issue #599
 	consecFailures int64
 	lastFailure    int64 // stored as nanoseconds since the Unix epoch
 	halfOpens      int64
+	probes         int64 // trial calls made since the last trip
+	probing        int64 // trial calls in flight
+	maxProbes      int64
+	queued         int64 // calls parked while the breaker is open
+	inflight       int64
+	flaps          int64
+	cacheServed    int64
+	pressure       uint64 // math.Float64bits of Pressure
+	trips          int64
+	recoveries     int64
+	timeOpen       int64 // nanoseconds spent open before the last reset
+	trippedAt      int64 // nanoseconds since the Unix epoch, 0 while closed
+	closedAt       int64 // nanoseconds since the Unix epoch of the last reset
+	lastTripCheck  int64 // nanoseconds since the Unix epoch
+	tlsFailures    int64
+	tlsStreak      int64
+	tlsThreshold   int64
+	abandoned      int64
+	droppedEvents  int64
+	generation     int64 // incremented by ResetStats
+	maxAbandoned   int64
 	counts         *window
 	nextBackOff    time.Duration
+	consecPolicy   ConsecutivePolicy
+	weightFunc     WeightFunc
+	faults         *FaultInjector
+	backOffReset   time.Duration
+	rejectShort    bool
+	callEvents     bool
+	profLabels     bool
+	cache          ResultCache
+	revalidate     bool
+	closeRate      float64
+	probeTimeout   time.Duration
+	adaptive       *AdaptiveTimeout
+	reconcile      bool
+	rng            *rand.Rand
+	categorizer    func(error) FailureCategory
+	queueSize      int64
+	pressureConc   int64
+	minOpen        time.Duration
+	minClosed      time.Duration
+	queueTimeout   time.Duration
+	closedSignal   chan struct{} // closed when the breaker next resets
+	tripCheck      time.Duration
+	onAbandon      AbandonedPolicy
+	onPanic        PanicPolicy
+	onReentry      ReentrancyPolicy
+	emptyRate      EmptyRate
+	decay          func(age int) float64
+	lastRate       uint64 // math.Float64bits of the last non-empty ErrorRate
+	canary         *CanaryPolicy
+	sla            *SLA
+	canaryCounts   canaryCounts
 	tripped        int32
 	broken         int32
-	eventReceivers []chan BreakerEvent
+	disabled       int32
+	unavailable    int32
+	unavailReason  string
+	eventReceivers []subscription
+	eventQueue     chan dispatch
+	dispatching    int32
 	listeners      []chan ListenerEvent
+	replay         []BreakerEvent
+	replaySize     int
+	tripCause      *TripCause
+	upstream       *TripToken
+	parent         *Breaker
+	children       []*Breaker
+	rollups        atomic.Value // []*Breaker, see Panel.Rollup
+	eventLock      sync.Mutex
 	backoffLock    sync.Mutex
+	queueLock      sync.Mutex
+	openTimes      histogram
+	closedTimes    histogram
 	logger         Logger
 	name           string
 }
 
 // Options holds breaker configuration options.
 type Options struct {
-	BackOff       backoff.BackOff
-	Clock         clock.Clock
+	BackOff backoff.BackOff
+
+	// Clock is the source of time for everything the breaker times: its
+	// window, its default BackOff, call timeouts and latencies. A mock or
+	// simulated clock lets applications running under simulated time, such
+	// as discrete event tests, drive breakers deterministically. The real
+	// clock is used if it is nil.
+	Clock clock.Clock
+
 	ShouldTrip    TripFunc
 	WindowTime    time.Duration
 	WindowBuckets int
 
+	// CoarseClock makes the window read the time from a CoarseClock with the
+	// resolution of a bucket, shared with the other breakers using the same
+	// Clock and bucket duration. This saves reading the clock for every call
+	// recorded, which matters for breakers recording millions of calls per
+	// second, at the cost of buckets ending up to a bucket late.
+	CoarseClock bool
+
+	// AlignBuckets makes the buckets of the window start on multiples of
+	// the bucket duration of the wall clock, such as on every second for
+	// one-second buckets, rather than when the first call after the previous
+	// bucket is recorded. Buckets then cover the same intervals on every
+	// instance, and line up with those of external monitoring.
+	AlignBuckets bool
+
+	// BucketRollover, if non-nil, is called with the counts of each bucket of
+	// the window once it is complete, so metrics can be exported per interval
+	// rather than scraped from rolling totals. A bucket is complete when the
+	// next call is recorded after its time is up; buckets skipped while no
+	// calls were made are not reported.
+	BucketRollover func(BucketCounts)
+
+	// RateDecay makes ErrorRate() weigh newer buckets of the window more
+	// heavily than older ones, so the breaker responds faster to a sudden
+	// degradation without shrinking the window. DecayFactor is the factor
+	// for DecayExponential, DefaultDecayFactor if it is 0.
+	RateDecay   RateDecay
+	DecayFactor float64
+
+	// EmptyRate controls what ErrorRate() returns when the window holds no
+	// calls. It is EmptyRateZero by default.
+	EmptyRate EmptyRate
+
+	// TripCheckInterval, if non-zero, is the least time between calls to
+	// ShouldTrip, which is otherwise called on every failure. Expensive trip
+	// functions, such as those computing latency quantiles, can be limited
+	// to once per bucket by setting it to WindowTime / WindowBuckets.
+	// Failures in between are recorded but do not trip the breaker.
+	TripCheckInterval time.Duration
+
+	// TLSTripThreshold, if non-zero, trips the breaker after that many
+	// consecutive failures are failed TLS handshakes, whatever ShouldTrip
+	// says. Certificate problems do not go away on their own, so there is
+	// no point waiting for them to push up the error rate. See IsTLSError.
+	TLSTripThreshold int64
+
+	// ConsecutivePolicy controls how timeouts and rejections affect
+	// ConsecFailures(). DefaultConsecutivePolicy is used if it is nil.
+	ConsecutivePolicy *ConsecutivePolicy
+
+	// WeightFunc weighs the failures recorded by Fail. Without a WeightFunc
+	// every failure has a weight of 1, and the failure score equals the
+	// number of failures.
+	WeightFunc WeightFunc
+
+	// BackOffResetAfter is how long the breaker must stay closed after a
+	// reset before a success resets its BackOff. Until then, another trip
+	// carries on from the backoff interval reached before, so a dependency
+	// that keeps failing shortly after recovering is retried less and less
+	// often. If it is 0, every success resets the BackOff.
+	BackOffResetAfter time.Duration
+
+	// RejectShortDeadlines makes CallContext fail with ErrDeadlineTooShort,
+	// without making the call, when less time is left before the context's
+	// deadline than the median latency of the calls in the window. Such
+	// calls would most likely be abandoned anyway, wasting work downstream.
+	// Rejected calls are counted in Rejects, not as failures.
+	RejectShortDeadlines bool
+
+	// ProbeTimeout, if non-zero, replaces the timeout of the trial calls Call
+	// and CallContext make while the breaker is half-open. Recovering
+	// services are often slower than usual, and trial calls held to the
+	// usual timeout fail spuriously and keep the breaker open. Calls made
+	// without a timeout still run without one.
+	ProbeTimeout time.Duration
+
+	// AdaptiveTimeout, if non-nil, replaces the non-zero timeout passed to
+	// Call and CallContext with one derived from the latency of the calls in
+	// the window. See AdaptiveTimeout and Breaker.EffectiveTimeout.
+	AdaptiveTimeout *AdaptiveTimeout
+
+	// Rand seeds the source of the breaker's random choices: the jitter of
+	// its default BackOff and whether its FaultInjector injects a fault into
+	// a call. A seeded Rand makes them reproducible, and breakers created in
+	// the same order from it make the same choices. Each breaker draws from
+	// a source of its own, so breakers do not contend for the global one. A
+	// BackOff given in Options randomizes its intervals itself.
+	Rand rand.Source
+
+	// Categorize, if non-nil, returns the category a failure with err is
+	// counted under in the window. Where it returns an empty category,
+	// CategorizeError is used. See Breaker.CategoryRate.
+	Categorize func(err error) FailureCategory
+
+	// BucketExtensions keep values of their own in each bucket of the
+	// window. See BucketExtension.
+	BucketExtensions []BucketExtension
+
+	// MinOpenDuration, if non-zero, keeps a tripped breaker open for at
+	// least that long, whatever its BackOff, and MinClosedDuration keeps a
+	// reset breaker closed for at least that long, whatever its ShouldTrip.
+	// Together they stop a breaker flapping between states on a dependency
+	// that is on the edge, and the events and alerts each flap would send.
+	// Suppressed trips and resets are counted in Stats.Flaps. Trip and Reset
+	// still change the state at once.
+	MinOpenDuration   time.Duration
+	MinClosedDuration time.Duration
+
+	// PressureConcurrency, if non-zero, is the number of calls in flight at
+	// which the breaker's Pressure reaches 1. Otherwise Pressure leaves
+	// concurrency out.
+	PressureConcurrency int
+
+	// QueueSize, if non-zero, is the number of calls Call and CallContext
+	// park while the breaker is open, instead of rejecting them. Parked
+	// calls are made once the breaker closes, or as its trial calls when it
+	// is ready to let them through. A call still parked after QueueTimeout
+	// fails with ErrQueueTimeout. Queuing suits background work, such as
+	// syncing, that can wait out an outage rather than fail. See
+	// Breaker.Queued.
+	QueueSize    int
+	QueueTimeout time.Duration
+
+	// ReconcileLateResults records the true outcome of calls that time out
+	// but whose function goes on to succeed: the timeout recorded as a
+	// failure is turned into a success once the function returns. A breaker
+	// the timeout already tripped stays tripped. Calls that time out after
+	// the stats are reset are not reconciled.
+	ReconcileLateResults bool
+
+	// MaxProbes is the number of trial calls a half-open breaker lets
+	// through at once. Further calls are rejected until one completes. It
+	// is 1 if it is 0. See Breaker.TryProbe.
+	MaxProbes int
+
+	// MaxAbandoned, if non-zero, is the number of abandoned calls at which
+	// AbandonedPolicy applies. A call is abandoned when it times out, but its
+	// function keeps running in its own goroutine until it returns; a
+	// dependency that hangs can pile up goroutines this way without bound.
+	// See Breaker.Abandoned.
+	MaxAbandoned    int64
+	AbandonedPolicy AbandonedPolicy
+
+	// PanicPolicy controls what Call does when the function it runs panics.
+	// By default, panics propagate untouched.
+	PanicPolicy PanicPolicy
+
+	// ReentrancyPolicy controls what the breaker does with calls made
+	// through it from inside a call already made through it with Do. By
+	// default, they are made as usual.
+	ReentrancyPolicy ReentrancyPolicy
+
+	// CloseRate, if non-zero, is the error rate the window must fall below
+	// before a tripped breaker closes again. Until then, successful trial
+	// calls are recorded but leave the breaker open. Closing at a lower error
+	// rate than the ShouldTrip opens at stops a breaker oscillating while the
+	// error rate hovers around a single threshold. See NewHysteresisBreaker.
+	CloseRate float64
+
+	// Canary, if non-nil, puts the breaker on canary for a while after it
+	// closes: if the calls made in that time fail at the canary's stricter
+	// Rate, the breaker reopens at once, and its BackOff is escalated rather
+	// than reset. Successes during the canary window do not reset the BackOff.
+	Canary *CanaryPolicy
+
+	// SLA, if non-nil, is the service level expected of the dependency. The
+	// breaker reports its compliance in Stats. See SLA.
+	SLA *SLA
+
+	// FaultInjector, if non-nil, injects faults into the breaker's calls for
+	// testing. It can be controlled at runtime through the AdminHandler.
+	FaultInjector *FaultInjector
+
+	// CallEvents makes the breaker send a BreakerCallComplete event, with
+	// the duration and error of the call, for every call made by Call, so
+	// that latency and error logging can be done in one listener rather than
+	// around every call. Calls rejected without being made send none.
+	CallEvents bool
+
+	// ProfileLabels sets pprof labels naming the breaker and its state on
+	// the goroutines running its calls, so that CPU and goroutine profiles
+	// can be broken down by dependency. See ProfileLabelBreaker.
+	ProfileLabels bool
+
+	// Cache, if non-nil, stores the results of calls made with CachedCall,
+	// which serves them while the breaker is open instead of failing. See
+	// NewLRUCache.
+	Cache ResultCache
+
+	// StaleWhileRevalidate makes CachedCall serve cached results at once
+	// while the breaker is not closed, rather than waiting on trial calls:
+	// when the breaker is ready for one, the call is made in the background
+	// as the trial, and updates the cache if it succeeds.
+	StaleWhileRevalidate bool
+
+	// EventQueue, if non-zero, moves logging and the delivery of events to
+	// subscribers and listeners off the goroutines making calls, onto a
+	// worker with a queue of EventQueue events, so a slow Logger, Statter or
+	// subscriber cannot slow calls down. Events arriving while the queue is
+	// full are dropped and counted, see Breaker.DroppedEvents.
+	EventQueue int
+
+	// EventReplay is the number of recent state change events (trips, resets
+	// and readies) to keep and deliver to new subscribers when they Subscribe,
+	// so monitoring started after a trip still learns the breaker is open. It
+	// is capped at the size of the subscription buffer, 100. 0 disables replay.
+	EventReplay int
+
+	// Parent, if non-nil, makes the breaker a child of Parent, such as an
+	// endpoint's breaker under its service's breaker. The outcome of every
+	// call is also recorded on the parent, and the child rejects calls while
+	// the parent is open. See TrippedChildrenTripFunc.
+	Parent *Breaker
+
 	// Logger is used to log when events occur.
 	Logger Logger
 	// Name is used with Logger if Logger is non-nil.
 	Name string
+
+	// Unset lists boolean and policy fields that Panel.AddWithOptions and
+	// WithOptions set to their zero value instead of keeping the defaults',
+	// since a zero value otherwise means the field is not set. For example,
+	// FieldCallEvents turns off CallEvents for a breaker whose panel's
+	// Defaults turn it on. Fields that are set take precedence.
+	// NewBreakerWithOptions ignores Unset.
+	Unset OptionFields
 }
 
+// realClock is the clock of breakers created without one.
+var realClock = clock.New()
+
 // NewBreakerWithOptions creates a base breaker with a specified backoff, clock and TripFunc
 func NewBreakerWithOptions(options *Options) *Breaker {
 	if options == nil {
@@ -151,16 +570,18 @@ func NewBreakerWithOptions(options *Options) *Breaker {
 	}
 
 	if options.Clock == nil {
-		options.Clock = clock.New()
+		options.Clock = realClock
 	}
 
+	rng := newRand(options.Rand)
 	if options.BackOff == nil {
 		b := backoff.NewExponentialBackOff()
 		b.InitialInterval = defaultInitialBackOffInterval
 		b.MaxElapsedTime = defaultBackoffMaxElapsedTime
+		b.RandomizationFactor = 0
 		b.Clock = options.Clock
 		b.Reset()
-		options.BackOff = b
+		options.BackOff = &jitterBackOff{b, backoff.DefaultRandomizationFactor, rng}
 	}
 
 	if options.WindowTime == 0 {
@@ -171,52 +592,151 @@ func NewBreakerWithOptions(options *Options) *Breaker {
 		options.WindowBuckets = DefaultWindowBuckets
 	}
 
-	return &Breaker{
-		BackOff:     options.BackOff,
-		Clock:       options.Clock,
-		ShouldTrip:  options.ShouldTrip,
-		nextBackOff: options.BackOff.NextBackOff(),
-		counts:      newWindow(options.WindowTime, options.WindowBuckets),
-		logger:      options.Logger,
-		name:        options.Name,
+	if options.ConsecutivePolicy == nil {
+		options.ConsecutivePolicy = &DefaultConsecutivePolicy
+	}
+
+	if options.QueueTimeout == 0 {
+		options.QueueTimeout = DefaultQueueTimeout
+	}
+
+	if options.MaxProbes == 0 {
+		options.MaxProbes = 1
 	}
+
+	if options.EventReplay > subscriptionBuffer {
+		options.EventReplay = subscriptionBuffer
+	}
+
+	counts := newWindow(options.WindowTime, options.WindowBuckets)
+	counts.clock = options.Clock
+	if options.CoarseClock {
+		counts.clock = sharedCoarseClock(options.Clock, counts.bucketTime)
+	}
+	counts.aligned = options.AlignBuckets
+	counts.lastAccess = counts.bucketStart(counts.clock.Now())
+	counts.onRollover = options.BucketRollover
+	if len(options.BucketExtensions) > 0 {
+		counts.extensions = make(map[string]BucketExtension, len(options.BucketExtensions))
+		for _, ext := range options.BucketExtensions {
+			counts.extensions[ext.Key()] = ext
+		}
+	}
+
+	cb := &Breaker{
+		BackOff:      options.BackOff,
+		decay:        decayFunc(options.RateDecay, options.DecayFactor, options.WindowBuckets),
+		Clock:        options.Clock,
+		ShouldTrip:   options.ShouldTrip,
+		nextBackOff:  options.BackOff.NextBackOff(),
+		counts:       counts,
+		consecPolicy: *options.ConsecutivePolicy,
+		weightFunc:   options.WeightFunc,
+		faults:       options.FaultInjector,
+		backOffReset: options.BackOffResetAfter,
+		rejectShort:  options.RejectShortDeadlines,
+		callEvents:   options.CallEvents,
+		profLabels:   options.ProfileLabels,
+		cache:        options.Cache,
+		revalidate:   options.StaleWhileRevalidate,
+		closeRate:    options.CloseRate,
+		probeTimeout: options.ProbeTimeout,
+		maxProbes:    int64(options.MaxProbes),
+		reconcile:    options.ReconcileLateResults,
+		rng:          rng,
+		categorizer:  options.Categorize,
+		queueSize:    int64(options.QueueSize),
+		pressureConc: int64(options.PressureConcurrency),
+		minOpen:      options.MinOpenDuration,
+		minClosed:    options.MinClosedDuration,
+		queueTimeout: options.QueueTimeout,
+		adaptive:     options.AdaptiveTimeout,
+		tripCheck:    options.TripCheckInterval,
+		tlsThreshold: options.TLSTripThreshold,
+		maxAbandoned: options.MaxAbandoned,
+		onAbandon:    options.AbandonedPolicy,
+		onPanic:      options.PanicPolicy,
+		onReentry:    options.ReentrancyPolicy,
+		emptyRate:    options.EmptyRate,
+		canary:       options.Canary,
+		sla:          options.SLA,
+		replaySize:   options.EventReplay,
+		closedAt:     options.Clock.Now().UnixNano(),
+		parent:       options.Parent,
+		logger:       options.Logger,
+		name:         options.Name,
+	}
+	if options.EventQueue > 0 {
+		cb.eventQueue = make(chan dispatch, options.EventQueue)
+	}
+	if cb.parent != nil {
+		cb.parent.addChild(cb)
+	}
+	return cb
 }
 
 // NewBreaker creates a base breaker with an exponential backoff and no TripFunc
 func NewBreaker() *Breaker {
-	return NewBreakerWithOptions(nil)
+	return New()
 }
 
 // NewThresholdBreaker creates a Breaker with a ThresholdTripFunc.
 func NewThresholdBreaker(threshold int64) *Breaker {
-	return NewBreakerWithOptions(&Options{
-		ShouldTrip: ThresholdTripFunc(threshold),
-	})
+	return New(WithThreshold(threshold))
 }
 
 // NewConsecutiveBreaker creates a Breaker with a ConsecutiveTripFunc.
 func NewConsecutiveBreaker(threshold int64) *Breaker {
-	return NewBreakerWithOptions(&Options{
-		ShouldTrip: ConsecutiveTripFunc(threshold),
-	})
+	return New(WithConsecutive(threshold))
 }
 
 // NewRateBreaker creates a Breaker with a RateTripFunc.
 func NewRateBreaker(rate float64, minSamples int64) *Breaker {
-	return NewBreakerWithOptions(&Options{
-		ShouldTrip: RateTripFunc(rate, minSamples),
-	})
+	return New(WithRate(rate, minSamples))
+}
+
+// NewHysteresisBreaker creates a Breaker that trips when the error rate
+// reaches openRate over at least minSamples calls, and only closes again once
+// it has fallen below closeRate.
+func NewHysteresisBreaker(openRate, closeRate float64, minSamples int64) *Breaker {
+	return New(WithRate(openRate, minSamples), WithCloseRate(closeRate))
 }
 
 // Subscribe returns a channel of BreakerEvents. Whenever the breaker changes state,
 // the state will be sent over the channel. See BreakerEvent for the types of events.
+// If the breaker was created with Options.EventReplay, the channel starts out
+// holding the most recent state change events.
 // Note that events may be dropped or not sent so clients should not rely on
 // events for program correctness.
 func (cb *Breaker) Subscribe() <-chan BreakerEvent {
-	eventReader := make(chan BreakerEvent)
-	output := make(chan BreakerEvent, 100)
+	output, _ := cb.subscribe()
+	return output
+}
+
+// subscription is the channel events are sent to for a subscriber, and a
+// channel closed when the subscriber unsubscribes.
+type subscription struct {
+	events chan BreakerEvent
+	done   chan struct{}
+}
+
+// subscribe is Subscribe, but also returns a function that ends the
+// subscription and closes the channel.
+func (cb *Breaker) subscribe() (<-chan BreakerEvent, func()) {
+	sub := subscription{
+		events: make(chan BreakerEvent),
+		done:   make(chan struct{}),
+	}
+	output := make(chan BreakerEvent, subscriptionBuffer)
 	go func() {
-		for v := range eventReader {
+		defer close(output)
+		for {
+			var v BreakerEvent
+			select {
+			case v = <-sub.events:
+			case <-sub.done:
+				return
+			}
 		trySend:
 			select {
 			case output <- v:
@@ -229,8 +749,29 @@ func (cb *Breaker) Subscribe() <-chan BreakerEvent {
 			}
 		}
 	}()
-	cb.eventReceivers = append(cb.eventReceivers, eventReader)
-	return output
+	cb.eventLock.Lock()
+	for _, event := range cb.replay {
+		output <- event
+	}
+	cb.eventReceivers = append(cb.eventReceivers, sub)
+	cb.eventLock.Unlock()
+
+	unsubscribe := func() {
+		cb.eventLock.Lock()
+		defer cb.eventLock.Unlock()
+		for i, receiver := range cb.eventReceivers {
+			if receiver == sub {
+				// Copy rather than shift in place, as emit may be ranging
+				// over the old slice.
+				receivers := make([]subscription, 0, len(cb.eventReceivers)-1)
+				receivers = append(receivers, cb.eventReceivers[:i]...)
+				cb.eventReceivers = append(receivers, cb.eventReceivers[i+1:]...)
+				close(sub.done)
+				return
+			}
+		}
+	}
+	return output, unsubscribe
 }
 
 // AddListener adds a channel of ListenerEvents on behalf of a listener.
@@ -238,16 +779,25 @@ func (cb *Breaker) Subscribe() <-chan BreakerEvent {
 // Note that events may be dropped or not sent so clients should not rely on
 // events for program correctness.
 func (cb *Breaker) AddListener(listener chan ListenerEvent) {
+	cb.eventLock.Lock()
 	cb.listeners = append(cb.listeners, listener)
+	cb.eventLock.Unlock()
 }
 
 // RemoveListener removes a channel previously added via AddListener.
 // Once removed, the channel will no longer receive ListenerEvents.
 // Returns true if the listener was found and removed.
 func (cb *Breaker) RemoveListener(listener chan ListenerEvent) bool {
+	cb.eventLock.Lock()
+	defer cb.eventLock.Unlock()
+
 	for i, receiver := range cb.listeners {
 		if listener == receiver {
-			cb.listeners = append(cb.listeners[:i], cb.listeners[i+1:]...)
+			// Copy rather than shift in place, as sendEvent may be ranging
+			// over the old slice.
+			listeners := make([]chan ListenerEvent, 0, len(cb.listeners)-1)
+			listeners = append(listeners, cb.listeners[:i]...)
+			cb.listeners = append(listeners, cb.listeners[i+1:]...)
 			return true
 		}
 	}
@@ -257,33 +807,140 @@ func (cb *Breaker) RemoveListener(listener chan ListenerEvent) bool {
 // Trip will trip the circuit breaker. After Trip() is called, Tripped() will
 // return true.
 func (cb *Breaker) Trip() {
-	atomic.StoreInt32(&cb.tripped, 1)
+	cb.trip(nil)
+}
+
+// trip trips the breaker because of the failure described by cause, which is
+// nil if the breaker is tripped by hand.
+func (cb *Breaker) trip(cause *TripCause) {
 	now := cb.Clock.Now()
+	fresh := atomic.SwapInt32(&cb.tripped, 1) == 0
+	if fresh || cause != nil {
+		if cause != nil {
+			cause.Time = now
+		}
+		cb.eventLock.Lock()
+		cb.tripCause = cause
+		cb.eventLock.Unlock()
+	}
+	if fresh {
+		atomic.AddInt64(&cb.trips, 1)
+		atomic.StoreInt64(&cb.probes, 0)
+		atomic.StoreInt64(&cb.trippedAt, now.UnixNano())
+		if closedAt := atomic.LoadInt64(&cb.closedAt); closedAt != 0 && now.UnixNano() >= closedAt {
+			cb.closedTimes.Observe(time.Duration(now.UnixNano() - closedAt))
+		}
+	}
 	atomic.StoreInt64(&cb.lastFailure, now.UnixNano())
-	cb.sendEvent(BreakerTripped)
+	cb.updatePressure()
+	if cause != nil {
+		cb.emit(BreakerTripped, cause.err, cause.Metadata)
+	} else {
+		cb.sendEvent(BreakerTripped)
+	}
 }
 
 // Reset will reset the circuit breaker. After Reset() is called, Tripped() will
-// return false.
+// return false. Reset clears the breaker's statistics as ResetStats does, so
+// calls in flight when it is called are not recorded when they complete.
 func (cb *Breaker) Reset() {
+	cb.ResetStats()
 	atomic.StoreInt32(&cb.broken, 0)
-	atomic.StoreInt32(&cb.tripped, 0)
+	if atomic.SwapInt32(&cb.tripped, 0) == 1 {
+		now := cb.Clock.Now().UnixNano()
+		if trippedAt := atomic.SwapInt64(&cb.trippedAt, 0); trippedAt != 0 {
+			atomic.AddInt64(&cb.timeOpen, now-trippedAt)
+			atomic.AddInt64(&cb.recoveries, 1)
+			cb.openTimes.Observe(time.Duration(now - trippedAt))
+		}
+		atomic.StoreInt64(&cb.closedAt, now)
+	}
 	atomic.StoreInt64(&cb.halfOpens, 0)
-	cb.ResetCounters()
+	atomic.StoreInt64(&cb.canaryCounts.failures, 0)
+	atomic.StoreInt64(&cb.canaryCounts.successes, 0)
+	cb.flushQueue()
+	cb.eventLock.Lock()
+	cb.upstream = nil
+	cb.eventLock.Unlock()
+	cb.updatePressure()
 	cb.sendEvent(BreakerReset)
 }
 
-// ResetCounters will reset only the failures, consecFailures, and success counters
-func (cb *Breaker) ResetCounters() {
+// ResetStats clears the failures, successes and rejects in the breaker's
+// window and its consecutive failure count, without changing its state or
+// sending events. Calls made by Call that are in flight when it is called are
+// not recorded when they complete, so their outcomes, which predate the reset,
+// do not count toward the fresh window. Outcomes passed to Fail, Success or
+// Record are always recorded.
+func (cb *Breaker) ResetStats() {
+	atomic.AddInt64(&cb.generation, 1)
 	atomic.StoreInt64(&cb.consecFailures, 0)
+	atomic.StoreInt64(&cb.tlsStreak, 0)
 	cb.counts.Reset()
 }
 
+// ResetCounters will reset only the failures, consecFailures, and success counters
+//
+// Deprecated: Use ResetStats, which ResetCounters calls.
+func (cb *Breaker) ResetCounters() {
+	cb.ResetStats()
+}
+
 // Tripped returns true if the circuit breaker is tripped, false if it is reset.
 func (cb *Breaker) Tripped() bool {
 	return atomic.LoadInt32(&cb.tripped) == 1
 }
 
+// RetryAfter returns how long it will be until a tripped breaker allows a
+// trial call. It returns 0 if the breaker is not tripped or is ready to retry.
+// A breaker that was broken with Break(), or whose BackOff has stopped, will
+// not retry on its own and returns a negative duration.
+func (cb *Breaker) RetryAfter() time.Duration {
+	if !cb.Tripped() {
+		return 0
+	}
+	next := cb.NextAttempt()
+	if next.IsZero() {
+		return -1
+	}
+	if remaining := next.Sub(cb.Clock.Now()); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// NextAttempt returns when a tripped breaker will allow a trial call, which is
+// in the past if it is ready to retry. It returns the zero time if the breaker
+// is not tripped, or will not retry on its own.
+func (cb *Breaker) NextAttempt() time.Time {
+	if !cb.Tripped() || atomic.LoadInt32(&cb.broken) == 1 {
+		return time.Time{}
+	}
+
+	last := atomic.LoadInt64(&cb.lastFailure)
+	next := cb.BackOffInterval()
+	if next == backoff.Stop {
+		return time.Time{}
+	}
+	attempt := time.Unix(0, last).Add(next)
+	if cb.minOpen != 0 {
+		if dwellEnd := time.Unix(0, atomic.LoadInt64(&cb.trippedAt)).Add(cb.minOpen); dwellEnd.After(attempt) {
+			attempt = dwellEnd
+		}
+	}
+	return attempt
+}
+
+// BackOffInterval returns the current backoff interval, the time a tripped
+// breaker waits after its last failure before allowing a trial call. It grows
+// with each failed trial call according to the breaker's BackOff, and is
+// backoff.Stop if the BackOff has given up.
+func (cb *Breaker) BackOffInterval() time.Duration {
+	cb.backoffLock.Lock()
+	defer cb.backoffLock.Unlock()
+	return cb.nextBackOff
+}
+
 // Break trips the circuit breaker and prevents it from auto resetting. Use this when
 // manual control over the circuit breaker state is needed.
 func (cb *Breaker) Break() {
@@ -291,12 +948,26 @@ func (cb *Breaker) Break() {
 	cb.Trip()
 }
 
+// FaultInjector returns the breaker's FaultInjector, or nil if it was created
+// without one.
+func (cb *Breaker) FaultInjector() *FaultInjector {
+	return cb.faults
+}
+
 // Failures returns the number of failures for this circuit breaker.
 func (cb *Breaker) Failures() int64 {
 	return cb.counts.Failures()
 }
 
+// FailureScore returns the sum of the weights of the failures in the breaker's
+// window. See Options.WeightFunc.
+func (cb *Breaker) FailureScore() float64 {
+	return cb.counts.FailureScore()
+}
+
 // ConsecFailures returns the number of consecutive failures that have occured.
+// How timeouts and rejected calls affect the count is controlled by the
+// breaker's ConsecutivePolicy.
 func (cb *Breaker) ConsecFailures() int64 {
 	return atomic.LoadInt64(&cb.consecFailures)
 }
@@ -306,61 +977,364 @@ func (cb *Breaker) Successes() int64 {
 	return cb.counts.Successes()
 }
 
+// Rejects returns the number of calls rejected without being made, such as
+// while the breaker was open. Rejected calls are counted apart from failures
+// and do not affect the error rate.
+func (cb *Breaker) Rejects() int64 {
+	return cb.counts.Rejects()
+}
+
+// Reject records a call that was rejected without being made. The breaker
+// records the calls it rejects itself; Reject is for integrations that turn
+// calls away for their own reasons, such as concurrency or rate limits. As
+// with the calls the breaker rejects, ConsecutivePolicy.Rejections applies.
+func (cb *Breaker) Reject() {
+	cb.reject()
+}
+
 // Fail is used to indicate a failure condition the Breaker should record. It will
 // increment the failure counters and store the time of the last failure. If the
 // breaker has a TripFunc it will be called, tripping the breaker if necessary.
-// Fail takes an error argument to be used in conjunction with the logger. If no
-// logger exists, err is ignored.
+// Fail takes an error argument to be used in conjunction with the logger and the
+// breaker's WeightFunc.
 func (cb *Breaker) Fail(err error) {
-	cb.counts.Fail()
-	atomic.AddInt64(&cb.consecFailures, 1)
+	cb.fail(context.Background(), err, 1, 1)
+}
+
+// FailN records n failures at once, for callers that learn the outcomes of
+// calls in bulk, such as from a batch API reporting the status of each item.
+// The breaker's TripFunc is checked once, after all n have been recorded.
+func (cb *Breaker) FailN(n int64) {
+	if n > 0 {
+		cb.fail(context.Background(), nil, n, 1)
+	}
+}
+
+// fail records a failure of a call with the given context and cost. It
+// returns the period of the window the failure was recorded in.
+func (cb *Breaker) fail(ctx context.Context, err error, n int64, cost float64) uint64 {
+	period := cb.recordFail(ctx, err, n, cost)
+	for _, r := range cb.callRollups() {
+		r.fail(ctx, err, n, cost)
+	}
+	return period
+}
+
+// recordFail is fail without the roll-ups, for the breaker and its ancestors.
+func (cb *Breaker) recordFail(ctx context.Context, err error, n int64, cost float64) uint64 {
+	weight := 1.0
+	if cb.weightFunc != nil {
+		weight = math.Max(cb.weightFunc(err), 0)
+	}
+	period := cb.counts.FailCategory(cb.categorize(err), n, weight, cost)
+	cb.noteRate()
+	cb.updatePressure()
+	if errors.Is(err, ErrBreakerTimeout) {
+		cb.updateStreak(cb.consecPolicy.Timeouts, n)
+	} else {
+		cb.updateStreak(StreakIncrement, n)
+	}
 	now := cb.Clock.Now()
 	atomic.StoreInt64(&cb.lastFailure, now.UnixNano())
-	cb.sendEvent(BreakerFail)
-	if cb.ShouldTrip != nil && cb.ShouldTrip(cb) {
-		if cb.logger != nil {
-			cb.logger.Infof("circuitbreaker: %s tripped: %v", cb.name, err)
+	md := MetadataFromContext(ctx)
+	cb.emit(BreakerFail, err, md)
+	tlsTrip := cb.tlsFail(err, n)
+	shouldTrip := false
+	if !cb.Disabled() {
+		if cb.canaryFail(n) {
+			cb.escalateBackOff()
+			shouldTrip = true
+		} else if tlsTrip {
+			shouldTrip = true
+		} else {
+			shouldTrip = cb.ShouldTrip != nil && cb.tripCheckDue(now) && cb.ShouldTrip(cb)
 		}
-		cb.Trip()
-	} else {
-		if cb.logger != nil {
-			cb.logger.Debugf("circuitbreaker: %s fail (not tripped): %v", cb.name, err)
+	}
+	if shouldTrip && !cb.Tripped() && cb.closedDwelling() {
+		cb.flap("trip")
+		shouldTrip = false
+	}
+	if shouldTrip {
+		cb.log(func(l Logger) {
+			l.Infof("circuitbreaker: %s tripped: %v", cb.name, err)
+		})
+		cause := &TripCause{Metadata: md, err: err}
+		if err != nil {
+			cause.Error = err.Error()
 		}
+		cb.trip(cause)
+	} else {
+		cb.log(func(l Logger) {
+			l.Debugf("circuitbreaker: %s fail (not tripped): %v", cb.name, err)
+		})
+	}
+	if cb.parent != nil {
+		cb.parent.recordFail(ctx, err, n, cost)
 	}
+	return period
 }
 
 // Success is used to indicate a success condition the Breaker should record. If
 // the success was triggered by a retry attempt, the breaker will be Reset().
+// The BackOff is reset too, unless Options.BackOffResetAfter delays that.
 func (cb *Breaker) Success() {
-	cb.backoffLock.Lock()
-	cb.BackOff.Reset()
-	cb.nextBackOff = cb.BackOff.NextBackOff()
-	cb.backoffLock.Unlock()
+	cb.success(1, 1)
+}
+
+// SuccessN records n successes at once. See FailN.
+func (cb *Breaker) SuccessN(n int64) {
+	if n > 0 {
+		cb.success(n, 1)
+	}
+}
 
-	state := cb.state()
-	if state != closed {
+// success records the success of a call with the given cost.
+func (cb *Breaker) success(n int64, cost float64) {
+	cb.recordSuccess(n, cost)
+	for _, r := range cb.callRollups() {
+		r.rollupSuccess(n, cost)
+	}
+}
+
+// recordSuccess is success without the roll-ups.
+func (cb *Breaker) recordSuccess(n int64, cost float64) {
+	cb.canarySuccess(n)
+	resetAfter := cb.backOffReset
+	if cb.canary != nil && cb.canary.Window > resetAfter {
+		resetAfter = cb.canary.Window
+	}
+	if resetAfter == 0 || cb.closedFor() >= resetAfter {
+		cb.backoffLock.Lock()
+		cb.BackOff.Reset()
+		cb.nextBackOff = cb.BackOff.NextBackOff()
+		cb.backoffLock.Unlock()
+	}
+
+	// A disabled breaker's state is left alone until it is enabled again.
+	tripped := cb.Tripped() && !cb.Disabled()
+	if tripped && cb.openDwell(cb.Clock.Now()) > 0 {
+		cb.flap("reset")
+		tripped = false
+	}
+	if tripped && cb.closeRate == 0 {
 		cb.Reset()
 	}
 	atomic.StoreInt64(&cb.consecFailures, 0)
-	cb.counts.Success()
+	atomic.StoreInt64(&cb.tlsStreak, 0)
+	cb.counts.SuccessN(n, cost)
+	cb.noteRate()
+	cb.updatePressure()
+	if tripped && cb.closeRate != 0 && cb.rate() < cb.closeRate {
+		// Only close once the successes have brought the error rate down.
+		cb.Reset()
+	}
+	if cb.parent != nil {
+		cb.parent.recordSuccess(n, cost)
+	}
+}
+
+// closedFor returns how long the breaker has been closed, or 0 if it is
+// tripped.
+func (cb *Breaker) closedFor() time.Duration {
+	if cb.Tripped() {
+		return 0
+	}
+	return cb.Clock.Now().Sub(time.Unix(0, atomic.LoadInt64(&cb.closedAt)))
+}
+
+// tripCheckDue reports whether ShouldTrip may be called at now, given the
+// breaker's TripCheckInterval, and if so records that it was.
+func (cb *Breaker) tripCheckDue(now time.Time) bool {
+	if cb.tripCheck == 0 {
+		return true
+	}
+	last := atomic.LoadInt64(&cb.lastTripCheck)
+	if last != 0 && now.Sub(time.Unix(0, last)) < cb.tripCheck {
+		return false
+	}
+	return atomic.CompareAndSwapInt64(&cb.lastTripCheck, last, now.UnixNano())
+}
+
+// reconcileLate turns the timeout recorded in the window's period for a call
+// that went on to succeed into a success, unless the stats have been reset
+// since the call started or the bucket holding it has moved on.
+func (cb *Breaker) reconcileLate(generation int64, period uint64, cost float64) {
+	if atomic.LoadInt64(&cb.generation) != generation {
+		return
+	}
+	weight := 1.0
+	if cb.weightFunc != nil {
+		weight = math.Max(cb.weightFunc(ErrBreakerTimeout), 0)
+	}
+	if !cb.counts.Amend(period, weight, cost) {
+		return
+	}
+	if cb.consecPolicy.Timeouts == StreakIncrement {
+		for {
+			n := atomic.LoadInt64(&cb.consecFailures)
+			if n <= 0 || atomic.CompareAndSwapInt64(&cb.consecFailures, n, n-1) {
+				break
+			}
+		}
+	}
+}
+
+// updateStreak applies effect to the consecutive failure count.
+func (cb *Breaker) updateStreak(effect StreakEffect, n int64) {
+	switch effect {
+	case StreakIncrement:
+		atomic.AddInt64(&cb.consecFailures, n)
+	case StreakReset:
+		atomic.StoreInt64(&cb.consecFailures, 0)
+	}
+}
+
+// CountsSince returns the failures and successes recorded over the most recent
+// d of the window, rounded up to whole buckets. Trip functions can use it to
+// compare recent outcomes with the whole window:
+//
+//	recentFailures, recentSuccesses := cb.CountsSince(2 * time.Second)
+func (cb *Breaker) CountsSince(d time.Duration) (failures, successes int64) {
+	return cb.counts.CountsSince(d)
 }
 
 // ErrorRate returns the current error rate of the Breaker, expressed as a floating
 // point number (e.g. 0.9 for 90%), since the last time the breaker was Reset.
+// Recent calls weigh more if Options.RateDecay is set. When there have been
+// no calls, it returns what Options.EmptyRate asks for.
 func (cb *Breaker) ErrorRate() float64 {
-	return cb.counts.ErrorRate()
+	if cb.emptyRate == EmptyRateZero || cb.Samples() > 0 {
+		return cb.rate()
+	}
+	if cb.emptyRate == EmptyRateUnknown {
+		return math.NaN()
+	}
+	return math.Float64frombits(atomic.LoadUint64(&cb.lastRate))
+}
+
+// rate returns the error rate of the window, weighed by the breaker's decay.
+func (cb *Breaker) rate() float64 {
+	if cb.decay == nil {
+		return cb.counts.ErrorRate()
+	}
+	return cb.counts.DecayedErrorRate(cb.decay)
+}
+
+// decayFunc returns the weight of a bucket by age, the current bucket being of
+// age 0, for decay over a window of n buckets, or nil for no decay.
+func decayFunc(decay RateDecay, factor float64, n int) func(age int) float64 {
+	switch decay {
+	case DecayLinear:
+		return func(age int) float64 {
+			return float64(n - age)
+		}
+	case DecayExponential:
+		if factor == 0 {
+			factor = DefaultDecayFactor
+		}
+		return func(age int) float64 {
+			return math.Pow(factor, float64(age))
+		}
+	}
+	return nil
+}
+
+// Samples returns the number of failures and successes in the window, which
+// tells a window with no calls apart from a healthy one.
+func (cb *Breaker) Samples() int64 {
+	return cb.Failures() + cb.Successes()
+}
+
+// noteRate remembers the current error rate for EmptyRateLast.
+func (cb *Breaker) noteRate() {
+	if cb.emptyRate == EmptyRateLast {
+		atomic.StoreUint64(&cb.lastRate, math.Float64bits(cb.rate()))
+	}
+}
+
+// WeightedErrorRate returns the current error rate of the Breaker with each
+// failure counted by its weight, that is score / (score + successes). Without a
+// WeightFunc it is the same as ErrorRate.
+func (cb *Breaker) WeightedErrorRate() float64 {
+	return cb.counts.WeightedErrorRate()
+}
+
+// CostErrorRate returns the current error rate of the Breaker with each call
+// counted by its cost, that is failed cost / total cost. Calls made other than
+// with CallWithCost have a cost of 1.
+func (cb *Breaker) CostErrorRate() float64 {
+	rate, _ := cb.counts.CostErrorRate()
+	return rate
+}
+
+// MeanLatency returns the mean time taken by the functions run by Call over the
+// breaker's window, or 0 if no calls have completed. Calls that time out are
+// counted as taking the full time out.
+func (cb *Breaker) MeanLatency() time.Duration {
+	return cb.counts.MeanLatency()
+}
+
+// LatencyQuantile estimates the q-quantile of the time taken by the functions
+// run by Call over the breaker's window, e.g. the median for 0.5, or returns 0
+// if no calls have completed.
+func (cb *Breaker) LatencyQuantile(q float64) time.Duration {
+	return cb.counts.LatencyQuantile(q)
 }
 
 // Ready will return true if the circuit breaker is ready to call the function.
 // It will be ready if the breaker is in a reset state, or if it is time to retry
-// the call for auto resetting.
+// the call for auto resetting. It is never ready while its FaultInjector forces
+// it open.
 func (cb *Breaker) Ready() bool {
-	state := cb.state()
-	if state == halfopen {
+	ready, _ := cb.ready()
+	return ready
+}
+
+// ready is Ready, but also reports whether the call is a half-open trial.
+func (cb *Breaker) ready() (ready, probe bool) {
+	ready, probe, _ = cb.readySlot(false)
+	return ready, probe
+}
+
+// readySlot is ready, but if slot is set, a half-open breaker only lets the
+// call through as a trial if it can take a trial call slot for it. If it
+// cannot, the breaker stays open for the call without using up a backoff step
+// or sending BreakerReady. A call through a child of a half-open parent is
+// one of the parent's trial calls, and takes one of its slots too. The slots
+// taken are returned in held, which the caller must release.
+func (cb *Breaker) readySlot(slot bool) (ready, probe bool, held trialSlots) {
+	if atomic.LoadInt32(&cb.unavailable) == 1 {
+		return false, false, nil
+	}
+	if cb.Disabled() {
+		return true, false, nil
+	}
+	if cb.faults != nil && cb.faults.open() {
+		return false, false, nil
+	}
+	if cb.parent != nil {
+		// Check the breaker itself first, so that a call it would reject
+		// does not use up the parent's backoff step.
+		if cb.State() == StateOpen {
+			return false, false, nil
+		}
+		if ready, probe, held = cb.parent.readySlot(slot); !ready {
+			return false, false, nil
+		}
+	}
+	switch cb.state(slot) {
+	case open:
+		held.release()
+		return false, false, nil
+	case halfopen:
 		atomic.StoreInt64(&cb.halfOpens, 0)
 		cb.sendEvent(BreakerReady)
+		if slot {
+			held = append(held, cb)
+		}
+		return true, true, held
 	}
-	return state == closed || state == halfopen
+	return true, probe, held
 }
 
 // Call wraps a function the Breaker will protect. A failure is recorded
@@ -370,24 +1344,141 @@ func (cb *Breaker) Call(circuit func() error, timeout time.Duration) error {
 	return cb.CallContext(context.Background(), circuit, timeout)
 }
 
+// CallWithCost is the same as Call, but records the outcome with the given
+// cost rather than a cost of 1, so that expensive calls such as batch
+// operations count for more than cheap ones such as health pings in
+// CostErrorRate. See CostRateTripFunc.
+func (cb *Breaker) CallWithCost(circuit func() error, cost float64, timeout time.Duration) error {
+	return cb.callContext(context.Background(), circuit, cost, timeout)
+}
+
 // CallContext is same as Call but if the ctx is canceled after the circuit returned an error,
 // the error will not be marked as a failure because the call was canceled intentionally.
 func (cb *Breaker) CallContext(
 	ctx context.Context, circuit func() error, timeout time.Duration,
+) error {
+	return cb.callContext(ctx, circuit, 1, timeout)
+}
+
+func (cb *Breaker) callContext(
+	ctx context.Context, circuit func() error, cost float64, timeout time.Duration,
+) error {
+	return cb.call(ctx, func(CallInfo) error { return circuit() }, cost, timeout)
+}
+
+// call makes a call through the breaker, passing circuit the call's CallInfo.
+func (cb *Breaker) call(
+	ctx context.Context, circuit func(info CallInfo) error, cost float64, timeout time.Duration,
+) error {
+	return cb.callWith(ctx, circuit, cost, timeout, nil)
+}
+
+// callWith is call, but if token is non-nil, the call is the one token was
+// taken for: it is not admitted again, and token is released once the call
+// returns or is abandoned.
+func (cb *Breaker) callWith(
+	ctx context.Context, circuit func(info CallInfo) error, cost float64, timeout time.Duration,
+	token *ProbeToken,
 ) error {
 	var err error
+	if token != nil {
+		defer token.Release()
+	}
+
+	if err := cb.checkReentry(ctx); err != nil {
+		return err
+	}
+
+	if cb.rejectShort {
+		if deadline, ok := ctx.Deadline(); ok {
+			median := cb.LatencyQuantile(0.5)
+			if median > 0 && deadline.Sub(cb.Clock.Now()) < median {
+				cb.reject()
+				return ErrDeadlineTooShort
+			}
+		}
+	}
 
-	if !cb.Ready() {
-		return ErrBreakerOpen
+	if cb.tooManyAbandoned() {
+		cb.reject()
+		return ErrTooManyAbandoned
 	}
 
+	var remaining time.Duration
+	budget, _ := LatencyBudgetFromContext(ctx)
+	if budget != nil {
+		if remaining = budget.Remaining(); remaining <= 0 {
+			cb.reject()
+			return budget.exhausted(cb.name)
+		}
+	}
+
+	var probe bool
+	if token != nil {
+		probe = token.Probe()
+	} else {
+		var held trialSlots
+		probe, held, err = cb.admit(ctx)
+		if err != nil {
+			return err
+		}
+		defer held.release()
+	}
+	// A timeout of 0 runs the call synchronously, which callers rely on
+	// when circuit writes to their variables, so only a timeout the caller
+	// set is replaced or shortened.
+	timeout = cb.EffectiveTimeout(timeout)
+	if probe && cb.probeTimeout != 0 && timeout != 0 {
+		timeout = cb.probeTimeout
+	}
+	if budget != nil && timeout > remaining {
+		timeout = remaining
+	}
+	info := CallInfo{Breaker: cb.name, Probe: probe}
+	if probe {
+		info.Attempt = atomic.AddInt64(&cb.probes, 1)
+	}
+	fn := func() error { return circuit(info) }
+	if cb.faults != nil {
+		fn = cb.faults.wrap(ctx, cb, fn)
+	}
+	if cb.profLabels {
+		fn = cb.withProfileLabels(ctx, probe, fn)
+	}
+	if cb.onPanic != PanicPropagate {
+		fn = recoverPanics(fn)
+	}
+
+	generation := atomic.LoadInt64(&cb.generation)
+	cb.enter()
+	start := cb.Clock.Now()
+	// late receives the period of the window its abandoned call's failure
+	// was recorded in, or 0 if it was not recorded, for its late result to
+	// be reconciled with.
+	var late chan uint64
+	abandoned := false
 	if timeout == 0 {
-		err = circuit()
+		err = fn()
 	} else {
 		c := make(chan error, 1)
+		// status is 0 while fn runs, 1 once it has returned and 2 once it
+		// has been abandoned.
+		var status int32
+		if cb.reconcile {
+			late = make(chan uint64, 1)
+		}
 		go func() {
-			c <- circuit()
+			e := fn()
+			c <- e
 			close(c)
+			if !atomic.CompareAndSwapInt32(&status, 0, 1) {
+				if late != nil {
+					if period := <-late; period != 0 && e == nil {
+						cb.reconcileLate(generation, period, cost)
+					}
+				}
+				atomic.AddInt64(&cb.abandoned, -1)
+			}
 		}()
 
 		select {
@@ -395,17 +1486,45 @@ func (cb *Breaker) CallContext(
 			err = e
 		case <-cb.Clock.After(timeout):
 			err = ErrBreakerTimeout
+			if atomic.CompareAndSwapInt32(&status, 0, 2) {
+				abandoned = true
+				cb.abandon()
+			}
 		}
 	}
+	latency := cb.Clock.Now().Sub(start)
+	cb.exit()
+	if budget != nil {
+		_, nested := CallInfoFromContext(ctx)
+		budget.record(cb.name, latency, err, nested)
+	}
+	if cb.callEvents {
+		cb.send(dispatch{event: BreakerCallComplete, err: err, md: MetadataFromContext(ctx), duration: latency})
+	}
+
+	// Outcomes of calls that started before the stats were reset are not
+	// recorded.
+	current := atomic.LoadInt64(&cb.generation) == generation
 
 	if err != nil {
-		if ctx.Err() != context.Canceled {
-			cb.Fail(err)
+		var period uint64
+		if current && ctx.Err() != context.Canceled {
+			cb.counts.Observe(latency)
+			period = cb.fail(ctx, err, 1, cost)
+		}
+		if abandoned && late != nil {
+			late <- period
+		}
+		if pe, ok := err.(*PanicError); ok && cb.onPanic == PanicRepanic {
+			panic(pe.Value)
 		}
 		return err
 	}
 
-	cb.Success()
+	if current {
+		cb.counts.Observe(latency)
+		cb.success(1, cost)
+	}
 	return nil
 }
 
@@ -413,12 +1532,15 @@ func (cb *Breaker) CallContext(
 // closed - the circuit is in a reset state and is operational
 // open - the circuit is in a tripped state
 // halfopen - the circuit is in a tripped state but the reset timeout has passed
-func (cb *Breaker) state() state {
+func (cb *Breaker) state(slot bool) state {
 	tripped := cb.Tripped()
 	if tripped {
 		if atomic.LoadInt32(&cb.broken) == 1 {
 			return open
 		}
+		if cb.openDwell(cb.Clock.Now()) > 0 {
+			return open
+		}
 
 		last := atomic.LoadInt64(&cb.lastFailure)
 		since := cb.Clock.Now().Sub(time.Unix(0, last))
@@ -427,10 +1549,16 @@ func (cb *Breaker) state() state {
 		defer cb.backoffLock.Unlock()
 
 		if cb.nextBackOff != backoff.Stop && since > cb.nextBackOff {
+			if slot && !cb.acquireProbe() {
+				return open
+			}
 			if atomic.CompareAndSwapInt64(&cb.halfOpens, 0, 1) {
 				cb.nextBackOff = cb.BackOff.NextBackOff()
 				return halfopen
 			}
+			if slot {
+				cb.releaseProbe()
+			}
 			return open
 		}
 		return open
@@ -451,31 +1579,42 @@ func (cb *Breaker) logEvent(event BreakerEvent) {
 }
 
 func (cb *Breaker) sendEvent(event BreakerEvent) {
-	cb.logEvent(event)
-	for _, receiver := range cb.eventReceivers {
-		receiver <- event
-	}
-	for _, listener := range cb.listeners {
-		le := ListenerEvent{CB: cb, Event: event}
-	trySend:
-		select {
-		case listener <- le:
-		default:
-			// The channel was full so attempt to pull off of it and send again.
-			select {
-			case <-listener:
-			default:
-			}
-			goto trySend
+	cb.emit(event, nil, nil)
+}
+
+// emit sends event, caused by a call that failed with err, to the subscribers
+// and listeners.
+func (cb *Breaker) emit(event BreakerEvent, err error, md Metadata) {
+	cb.send(dispatch{event: event, err: err, md: md})
+}
+
+// send sends d to the breaker's current subscribers and listeners.
+func (cb *Breaker) send(d dispatch) {
+	cb.eventLock.Lock()
+	if cb.replaySize > 0 && d.event != BreakerFail && d.event != BreakerCallComplete {
+		if len(cb.replay) == cb.replaySize {
+			cb.replay = append(cb.replay[:0], cb.replay[1:]...)
 		}
+		cb.replay = append(cb.replay, d.event)
 	}
+	d.receivers, d.listeners = cb.eventReceivers, cb.listeners
+	cb.eventLock.Unlock()
+
+	if len(d.listeners) > 0 {
+		d.nextAttempt = cb.NextAttempt()
+	}
+	if cb.eventQueue != nil {
+		cb.enqueue(d)
+		return
+	}
+	cb.dispatch(d)
 }
 
 // ThresholdTripFunc returns a TripFunc with that trips whenever
 // the failure count meets the threshold.
 func ThresholdTripFunc(threshold int64) TripFunc {
 	return func(cb *Breaker) bool {
-		return cb.Failures() == threshold
+		return !cb.Tripped() && cb.Failures() >= threshold
 	}
 }
 
@@ -483,7 +1622,28 @@ func ThresholdTripFunc(threshold int64) TripFunc {
 // the consecutive failure count meets the threshold.
 func ConsecutiveTripFunc(threshold int64) TripFunc {
 	return func(cb *Breaker) bool {
-		return cb.ConsecFailures() == threshold
+		return !cb.Tripped() && cb.ConsecFailures() >= threshold
+	}
+}
+
+// WeightedThresholdTripFunc returns a TripFunc that trips whenever the failure
+// score reaches the threshold. See Options.WeightFunc.
+func WeightedThresholdTripFunc(threshold float64) TripFunc {
+	return func(cb *Breaker) bool {
+		return !cb.Tripped() && cb.FailureScore() >= threshold
+	}
+}
+
+// LatencyTripFunc returns a TripFunc that trips whenever the q-quantile of the
+// latency of the calls in the window reaches threshold, once there have been
+// at least minSamples calls. Like every TripFunc it is only consulted when a
+// call fails, so it is meant for breakers whose calls have a timeout: calls
+// that time out count as taking the full timeout, and a dependency that slows
+// down trips the breaker before every call times out.
+func LatencyTripFunc(q float64, threshold time.Duration, minSamples int64) TripFunc {
+	return func(cb *Breaker) bool {
+		samples := cb.Failures() + cb.Successes()
+		return !cb.Tripped() && samples >= minSamples && cb.LatencyQuantile(q) >= threshold
 	}
 }
 
@@ -500,3 +1660,22 @@ func RateTripFunc(rate float64, minSamples int64) TripFunc {
 		return samples >= minSamples && cb.ErrorRate() >= rate
 	}
 }
+
+// CostRateTripFunc returns a TripFunc that trips whenever the cost-weighted
+// error rate hits the threshold, once the calls in the window have a total cost
+// of at least minCost. See Breaker.CallWithCost.
+func CostRateTripFunc(rate, minCost float64) TripFunc {
+	return func(cb *Breaker) bool {
+		errRate, total := cb.counts.CostErrorRate()
+		return total >= minCost && errRate >= rate
+	}
+}
+
+// WeightedRateTripFunc is like RateTripFunc, but compares the weighted error
+// rate to the threshold. See Breaker.WeightedErrorRate.
+func WeightedRateTripFunc(rate float64, minSamples int64) TripFunc {
+	return func(cb *Breaker) bool {
+		samples := cb.Failures() + cb.Successes()
+		return samples >= minSamples && cb.WeightedErrorRate() >= rate
+	}
+}
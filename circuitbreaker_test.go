@@ -2,7 +2,10 @@ package circuit
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"sync"
 	"sync/atomic"
@@ -68,6 +71,66 @@ func TestBreakerCounts(t *testing.T) {
 	}
 }
 
+func TestTripStats(t *testing.T) {
+	c := clock.NewMock()
+	cb := NewBreaker()
+	cb.Clock = c
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+
+	cb.Trip()
+	c.Add(10 * time.Second)
+	cb.Trip() // Tripping while tripped is not a new trip.
+	c.Add(10 * time.Second)
+	if s := cb.Stats(); s.Trips != 1 || s.TimeOpen != 20*time.Second || s.MTTR != 0 {
+		t.Fatalf("expected 1 trip open for 20s with no recoveries, got %+v", s)
+	}
+	cb.Reset()
+
+	c.Add(time.Minute)
+	cb.Trip()
+	c.Add(40 * time.Second)
+	cb.Reset()
+
+	s := cb.Stats()
+	if s.Trips != 2 || s.Recoveries != 2 {
+		t.Fatalf("expected 2 trips and recoveries, got %+v", s)
+	}
+	if s.TimeOpen != time.Minute {
+		t.Fatalf("expected 1m open, got %v", s.TimeOpen)
+	}
+	if s.MTTR != 30*time.Second {
+		t.Fatalf("expected MTTR of 30s, got %v", s.MTTR)
+	}
+	if !s.TrippedAt.IsZero() {
+		t.Fatalf("expected closed breaker to have no trip time, got %v", s.TrippedAt)
+	}
+}
+
+func TestRestoreTimeOpen(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := NewBreakerWithOptions(&Options{Clock: c})
+	for _, d := range []time.Duration{10 * time.Second, 10 * time.Second, 11 * time.Second} {
+		cb.Trip()
+		c.Add(d)
+		cb.Reset()
+	}
+	cb.Trip()
+	c.Add(5 * time.Second)
+
+	restored := NewBreakerWithOptions(&Options{Clock: c})
+	restored.restoreStats(cb.Stats())
+	if s := restored.Stats(); s.TimeOpen != 36*time.Second {
+		t.Fatalf("expected the time open to be restored exactly, got %v", s.TimeOpen)
+	}
+	c.Add(time.Minute)
+	restored = NewBreakerWithOptions(&Options{Clock: c})
+	restored.restoreStats(cb.Stats())
+	if s := restored.Stats(); s.TimeOpen != 96*time.Second {
+		t.Fatalf("expected the current trip to be counted once, got %v", s.TimeOpen)
+	}
+}
+
 func TestErrorRate(t *testing.T) {
 	cb := NewBreaker()
 	if er := cb.ErrorRate(); er != 0.0 {
@@ -103,6 +166,30 @@ func TestBreakerEvents(t *testing.T) {
 	}
 }
 
+func TestBreakerEventReplay(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{EventReplay: 2})
+	cb.Fail(nil)
+	cb.Trip()
+	cb.Reset()
+	cb.Break()
+
+	events := cb.Subscribe()
+	for _, expected := range []BreakerEvent{BreakerReset, BreakerTripped} {
+		if e := <-events; e != expected {
+			t.Fatalf("expected to replay %v, got %v", expected, e)
+		}
+	}
+
+	cb.Reset()
+	if e := <-events; e != BreakerReset {
+		t.Fatalf("expected to receive a reset event after replay, got %v", e)
+	}
+
+	if events := NewBreaker().Subscribe(); len(events) != 0 {
+		t.Fatalf("expected no replay by default, got %d events", len(events))
+	}
+}
+
 func TestAddRemoveListener(t *testing.T) {
 	c := clock.NewMock()
 	cb := NewBreaker()
@@ -166,6 +253,146 @@ func TestTrippableBreakerState(t *testing.T) {
 	}
 }
 
+func TestRetryAfter(t *testing.T) {
+	c := clock.NewMock()
+	cb := NewBreaker()
+	cb.Clock = c
+
+	if d := cb.RetryAfter(); d != 0 {
+		t.Fatalf("expected closed breaker to have no retry delay, got %v", d)
+	}
+
+	cb.Trip()
+	cb.nextBackOff = time.Second
+	if d := cb.RetryAfter(); d != time.Second {
+		t.Fatalf("expected retry delay of 1s, got %v", d)
+	}
+
+	c.Add(400 * time.Millisecond)
+	if d := cb.RetryAfter(); d != 600*time.Millisecond {
+		t.Fatalf("expected retry delay of 600ms, got %v", d)
+	}
+
+	c.Add(time.Second)
+	if d := cb.RetryAfter(); d != 0 {
+		t.Fatalf("expected breaker ready to retry, got %v", d)
+	}
+
+	cb.Break()
+	if d := cb.RetryAfter(); d >= 0 {
+		t.Fatalf("expected broken breaker to never retry, got %v", d)
+	}
+}
+
+func TestBackOffResetAfter(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	b := &backoff.ExponentialBackOff{
+		InitialInterval: time.Second,
+		Multiplier:      2,
+		MaxInterval:     time.Hour,
+		Clock:           c,
+	}
+	b.Reset()
+	cb := NewBreakerWithOptions(&Options{
+		BackOff:           b,
+		Clock:             c,
+		BackOffResetAfter: time.Minute,
+	})
+
+	// Fail two trial calls, then recover.
+	cb.Trip()
+	for i := 0; i < 2; i++ {
+		c.Add(cb.BackOffInterval() + time.Millisecond)
+		if !cb.Ready() {
+			t.Fatal("expected breaker to be ready to retry")
+		}
+		cb.Fail(nil)
+	}
+	c.Add(cb.BackOffInterval() + time.Millisecond)
+	cb.Ready()
+	cb.Success()
+	if cb.Tripped() {
+		t.Fatal("expected the successful trial call to reset the breaker")
+	}
+	grown := cb.BackOffInterval()
+	if grown <= time.Second {
+		t.Fatalf("expected the backoff to have grown, got %v", grown)
+	}
+
+	c.Add(30 * time.Second)
+	cb.Success()
+	if d := cb.BackOffInterval(); d != grown {
+		t.Fatalf("expected the backoff to be kept shortly after recovering, got %v", d)
+	}
+
+	c.Add(time.Minute)
+	cb.Success()
+	if d := cb.BackOffInterval(); d != time.Second {
+		t.Fatalf("expected the backoff to be reset after a minute closed, got %v", d)
+	}
+}
+
+func TestRejectShortDeadlines(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{RejectShortDeadlines: true})
+	for i := 0; i < 10; i++ {
+		cb.counts.Observe(time.Second)
+	}
+
+	called := false
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := cb.CallContext(ctx, func() error {
+		called = true
+		return nil
+	}, 0)
+	if err != ErrDeadlineTooShort || called {
+		t.Fatalf("expected the call to be rejected without running, got %v", err)
+	}
+	if cb.Failures() != 0 || cb.Successes() != 0 {
+		t.Fatal("expected the rejected call not to be recorded")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := cb.CallContext(ctx, func() error { return nil }, 0); err != nil {
+		t.Fatalf("expected a call with a long deadline to be made, got %v", err)
+	}
+	if err := cb.CallContext(context.Background(), func() error { return nil }, 0); err != nil {
+		t.Fatalf("expected a call without a deadline to be made, got %v", err)
+	}
+}
+
+func TestNextAttempt(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	cb := NewBreakerWithOptions(&Options{Clock: c})
+	listener := make(chan ListenerEvent, 10)
+	cb.AddListener(listener)
+
+	if next := cb.NextAttempt(); !next.IsZero() {
+		t.Fatalf("expected closed breaker to have no next attempt, got %v", next)
+	}
+
+	cb.nextBackOff = 42 * time.Second
+	cb.Trip()
+	want := c.Now().Add(42 * time.Second)
+	if next := cb.NextAttempt(); !next.Equal(want) {
+		t.Fatalf("expected next attempt at %v, got %v", want, next)
+	}
+	if e := <-listener; e.Event != BreakerTripped || !e.NextAttempt.Equal(want) {
+		t.Fatalf("expected trip event with next attempt at %v, got %+v", want, e)
+	}
+	if s := cb.Stats(); s.BackOff != 42*time.Second || !s.NextAttempt.Equal(want) {
+		t.Fatalf("expected stats to include the backoff, got %+v", s)
+	}
+
+	cb.Break()
+	if next := cb.NextAttempt(); !next.IsZero() {
+		t.Fatalf("expected broken breaker to have no next attempt, got %v", next)
+	}
+}
+
 func TestTrippableBreakerManualBreak(t *testing.T) {
 	c := clock.NewMock()
 	cb := NewBreaker()
@@ -231,6 +458,154 @@ func TestConsecutiveBreaker(t *testing.T) {
 	}
 }
 
+func TestConsecutivePolicy(t *testing.T) {
+	cb := NewConsecutiveBreaker(3)
+
+	cb.Fail(nil)
+	cb.Fail(ErrBreakerTimeout)
+	if consecFailures := cb.ConsecFailures(); consecFailures != 2 {
+		t.Fatalf("expected timeouts to count by default, got %d consecutive failures", consecFailures)
+	}
+
+	cb.Break()
+	cb.Call(func() error { return nil }, 0)
+	if consecFailures := cb.ConsecFailures(); consecFailures != 2 {
+		t.Fatalf("expected rejections to be ignored by default, got %d consecutive failures", consecFailures)
+	}
+
+	cb = NewBreakerWithOptions(&Options{
+		ShouldTrip: ConsecutiveTripFunc(3),
+		ConsecutivePolicy: &ConsecutivePolicy{
+			Timeouts:   StreakReset,
+			Rejections: StreakIncrement,
+		},
+	})
+
+	cb.Fail(nil)
+	cb.Fail(nil)
+	cb.Fail(ErrBreakerTimeout)
+	if consecFailures := cb.ConsecFailures(); consecFailures != 0 {
+		t.Fatalf("expected timeout to reset the streak, got %d consecutive failures", consecFailures)
+	}
+	if cb.Tripped() {
+		t.Fatal("expected breaker not to trip on a timeout")
+	}
+
+	cb.Break()
+	cb.Call(func() error { return nil }, 0)
+	if consecFailures := cb.ConsecFailures(); consecFailures != 1 {
+		t.Fatalf("expected rejection to increment the streak, got %d consecutive failures", consecFailures)
+	}
+}
+
+func TestConsecutivePolicyRejections(t *testing.T) {
+	policy := &ConsecutivePolicy{Rejections: StreakIncrement}
+	tests := []struct {
+		name   string
+		opts   Options
+		reject func(cb *Breaker) error
+		want   error
+	}{
+		{"open", Options{}, func(cb *Breaker) error {
+			cb.Break()
+			return cb.Call(func() error { return nil }, 0)
+		}, ErrBreakerOpen},
+		{"integration", Options{}, func(cb *Breaker) error {
+			cb.Reject()
+			return nil
+		}, nil},
+		{"concurrency", Options{}, func(cb *Breaker) error {
+			p := NewPanel()
+			p.Add("cmd", cb)
+			c := &Command{Name: "cmd", Panel: p, MaxConcurrent: 1, Run: func(context.Context) error { return nil }}
+			c.Breaker()
+			c.sem <- struct{}{}
+			return c.Execute(context.Background())
+		}, ErrMaxConcurrency},
+		{"short deadline", Options{RejectShortDeadlines: true}, func(cb *Breaker) error {
+			cb.counts.Observe(time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancel()
+			return cb.CallContext(ctx, func() error { return nil }, 0)
+		}, ErrDeadlineTooShort},
+		{"abandoned", Options{MaxAbandoned: 1}, func(cb *Breaker) error {
+			atomic.StoreInt64(&cb.abandoned, 1)
+			return cb.Call(func() error { return nil }, 0)
+		}, ErrTooManyAbandoned},
+		{"reentrant", Options{ReentrancyPolicy: ReentrantReject}, func(cb *Breaker) error {
+			// The context of a call made through cb with Do.
+			ctx := context.WithValue(context.Background(), callKey{cb}, true)
+			return cb.Do(ctx, func(context.Context) error { return nil }, 0)
+		}, ErrReentrantCall},
+	}
+
+	for _, test := range tests {
+		opts := test.opts
+		opts.ConsecutivePolicy = policy
+		cb := NewBreakerWithOptions(&opts)
+		if err := test.reject(cb); err != test.want {
+			t.Fatalf("%s: expected %v, got %v", test.name, test.want, err)
+		}
+		if n := cb.Rejects(); n != 1 {
+			t.Fatalf("%s: expected 1 rejection, got %d", test.name, n)
+		}
+		if n := cb.ConsecFailures(); n != 1 {
+			t.Fatalf("%s: expected the rejection to increment the streak, got %d consecutive failures", test.name, n)
+		}
+	}
+}
+
+func TestWeightedFailures(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	weights := func(err error) float64 {
+		switch {
+		case errors.Is(err, errRefused):
+			return 3
+		case errors.Is(err, ErrBreakerTimeout):
+			return 2
+		}
+		return 1
+	}
+
+	cb := NewBreakerWithOptions(&Options{
+		ShouldTrip: WeightedThresholdTripFunc(5),
+		WeightFunc: weights,
+	})
+	cb.Fail(nil)
+	cb.Fail(ErrBreakerTimeout)
+	if score := cb.FailureScore(); score != 3 {
+		t.Fatalf("expected failure score of 3, got %v", score)
+	}
+	if cb.Tripped() {
+		t.Fatal("expected breaker not to trip below the threshold")
+	}
+	cb.Fail(errRefused)
+	if !cb.Tripped() {
+		t.Fatal("expected breaker to trip once the score reached the threshold")
+	}
+	if failures := cb.Failures(); failures != 3 {
+		t.Fatalf("expected weights not to affect the failure count, got %d", failures)
+	}
+
+	cb = NewBreakerWithOptions(&Options{
+		ShouldTrip: WeightedRateTripFunc(0.5, 4),
+		WeightFunc: weights,
+	})
+	cb.Success()
+	cb.Success()
+	cb.Success()
+	cb.Fail(errRefused)
+	if rate := cb.ErrorRate(); rate != 0.25 {
+		t.Fatalf("expected unweighted error rate of 0.25, got %v", rate)
+	}
+	if rate := cb.WeightedErrorRate(); rate != 0.5 {
+		t.Fatalf("expected weighted error rate of 0.5, got %v", rate)
+	}
+	if !cb.Tripped() {
+		t.Fatal("expected breaker to trip on the weighted error rate")
+	}
+}
+
 func TestThresholdBreakerCalling(t *testing.T) {
 	circuit := func() error {
 		return fmt.Errorf("error")
@@ -654,3 +1029,327 @@ func (l *testLogger) Infof(format string, args ...interface{}) {
 func (l *testLogger) Debugf(format string, args ...interface{}) {
 	l.debugCalls = append(l.debugCalls, logCall{format, args})
 }
+
+func TestCostRateTripFunc(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{ShouldTrip: CostRateTripFunc(0.5, 10)})
+	ping := func() error { return nil }
+	batch := func() error { return errors.New("batch failed") }
+
+	for i := 0; i < 20; i++ {
+		cb.Call(ping, 0)
+	}
+	cb.CallWithCost(batch, 10, 0)
+	if cb.Tripped() {
+		t.Fatalf("expected breaker not to trip at a cost error rate of %v", cb.CostErrorRate())
+	}
+	cb.CallWithCost(batch, 10, 0)
+	if rate := cb.CostErrorRate(); rate != 0.5 {
+		t.Fatalf("expected cost error rate of 0.5, got %v", rate)
+	}
+	if rate := cb.ErrorRate(); rate >= 0.5 {
+		t.Fatalf("expected the unweighted error rate to stay low, got %v", rate)
+	}
+	if !cb.Tripped() {
+		t.Fatal("expected the expensive failures to trip the breaker")
+	}
+}
+
+func TestLatencyTripFunc(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := NewBreakerWithOptions(&Options{Clock: c, ShouldTrip: LatencyTripFunc(0.5, 100*time.Millisecond, 4)})
+	call := func(latency time.Duration, err error) {
+		cb.Call(func() error {
+			c.Add(latency)
+			return err
+		}, 0)
+	}
+
+	call(time.Second, errors.New("slow"))
+	if cb.Tripped() {
+		t.Fatal("expected breaker not to trip before minSamples calls")
+	}
+	call(time.Millisecond, nil)
+	call(time.Millisecond, nil)
+	call(time.Millisecond, errors.New("fast"))
+	if cb.Tripped() {
+		t.Fatal("expected breaker not to trip while the median latency is low")
+	}
+	call(time.Second, nil)
+	call(time.Second, nil)
+	call(time.Second, errors.New("slow"))
+	if !cb.Tripped() {
+		t.Fatalf("expected breaker to trip at a median latency of %v", cb.LatencyQuantile(0.5))
+	}
+}
+
+func TestOptionsClockDrivesWindow(t *testing.T) {
+	c := clock.NewMock()
+	cb := NewBreakerWithOptions(&Options{Clock: c, WindowTime: 10 * time.Second, WindowBuckets: 10})
+
+	cb.Fail(nil)
+	c.Add(5 * time.Second)
+	cb.Fail(nil)
+	if f := cb.Failures(); f != 2 {
+		t.Fatalf("expected 2 failures in the window, got %d", f)
+	}
+
+	c.Add(8 * time.Second)
+	if f := cb.Failures(); f != 2 {
+		t.Fatalf("expected the window to slide only when accessed, got %d failures", f)
+	}
+	cb.Success()
+	if f := cb.Failures(); f != 1 {
+		t.Fatalf("expected the first failure to have left the window, got %d failures", f)
+	}
+}
+
+func TestHysteresisBreaker(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := NewHysteresisBreaker(0.5, 0.2, 4)
+	cb.Clock = c
+	cb.counts.clock = c
+
+	fail := func() error { return errors.New("failed") }
+	ok := func() error { return nil }
+	cb.Call(ok, 0)
+	cb.Call(ok, 0)
+	cb.Call(fail, 0)
+	cb.Call(fail, 0)
+	if !cb.Tripped() {
+		t.Fatal("expected breaker to trip at the open rate")
+	}
+
+	trials := 0
+	for cb.Tripped() && trials < 20 {
+		c.Add(10 * time.Millisecond)
+		if err := cb.Call(ok, 0); err != nil {
+			t.Fatalf("expected trial call to be let through, got %v", err)
+		}
+		trials++
+	}
+	// 2 failures in 11 calls is the first error rate below 0.2.
+	if cb.Tripped() || trials != 7 {
+		t.Fatalf("expected breaker to close after 7 successful trials, closed after %d", trials)
+	}
+}
+
+func TestRejects(t *testing.T) {
+	cb := NewBreaker()
+	cb.Fail(nil)
+	cb.Break()
+	for i := 0; i < 3; i++ {
+		if err := cb.Call(func() error { return nil }, 0); err != ErrBreakerOpen {
+			t.Fatalf("expected ErrBreakerOpen, got %v", err)
+		}
+	}
+	cb.Reject()
+
+	if r := cb.Rejects(); r != 4 {
+		t.Fatalf("expected 4 rejects, got %d", r)
+	}
+	if f := cb.Failures(); f != 1 {
+		t.Fatalf("expected rejects not to count as failures, got %d failures", f)
+	}
+	if r := cb.ErrorRate(); r != 1 {
+		t.Fatalf("expected rejects not to affect the error rate, got %v", r)
+	}
+	if s := cb.Stats(); s.Rejects != 4 {
+		t.Fatalf("expected rejects in stats, got %d", s.Rejects)
+	}
+}
+
+func TestEmptyRate(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{EmptyRate: EmptyRateUnknown})
+	if r := cb.ErrorRate(); !math.IsNaN(r) || cb.Samples() != 0 {
+		t.Fatalf("expected an unknown rate with no samples, got %v and %d samples", r, cb.Samples())
+	}
+	if _, err := json.Marshal(cb.Stats()); err != nil {
+		t.Fatalf("expected stats with an unknown rate to marshal, got %v", err)
+	}
+	if RateTripFunc(0.5, 0)(cb) {
+		t.Fatal("expected an unknown rate not to trip")
+	}
+
+	cb = NewBreakerWithOptions(&Options{EmptyRate: EmptyRateLast})
+	cb.Fail(nil)
+	cb.Fail(nil)
+	cb.Success()
+	cb.Success()
+	cb.ResetCounters()
+	if r := cb.ErrorRate(); r != 0.5 {
+		t.Fatalf("expected the last known rate, got %v", r)
+	}
+
+	cb = NewBreaker()
+	if r := cb.ErrorRate(); r != 0 {
+		t.Fatalf("expected a rate of 0 by default, got %v", r)
+	}
+}
+
+func TestProbeTimeout(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{ProbeTimeout: time.Second})
+	slow := func() error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}
+
+	cb.Trip()
+	time.Sleep(cb.BackOffInterval() + 5*time.Millisecond)
+	if err := cb.Call(slow, time.Millisecond); err != nil {
+		t.Fatalf("expected the trial call to be given the probe timeout, got %v", err)
+	}
+	if cb.Tripped() {
+		t.Fatal("expected the trial call to reset the breaker")
+	}
+	if err := cb.Call(slow, time.Millisecond); err != ErrBreakerTimeout {
+		t.Fatalf("expected calls to a closed breaker to use their own timeout, got %v", err)
+	}
+}
+
+func TestTimeoutsKeepSynchronousCalls(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{
+		ProbeTimeout:    time.Millisecond,
+		AdaptiveTimeout: &AdaptiveTimeout{Max: time.Millisecond},
+	})
+	ctx := WithLatencyBudget(context.Background(), time.Millisecond)
+	slow := Wrap(cb, func(ctx context.Context) (int, error) {
+		time.Sleep(20 * time.Millisecond)
+		return 42, nil
+	})
+
+	for i := 0; i < 2; i++ {
+		if i == 1 {
+			cb.Trip()
+			time.Sleep(cb.BackOffInterval() + 5*time.Millisecond)
+		}
+		if v, err := slow(ctx); v != 42 || err != nil {
+			t.Fatalf("expected call %d without a timeout to run to completion, got %v, %v", i, v, err)
+		}
+		ctx = context.Background()
+	}
+}
+
+func TestFailNSuccessN(t *testing.T) {
+	cb := NewConsecutiveBreaker(5)
+	cb.SuccessN(3)
+	cb.FailN(4)
+	if cb.Tripped() {
+		t.Fatal("expected breaker not to trip below the threshold")
+	}
+	if f, s := cb.Failures(), cb.Successes(); f != 4 || s != 3 {
+		t.Fatalf("expected 4 failures and 3 successes, got %d and %d", f, s)
+	}
+
+	cb.FailN(3)
+	if !cb.Tripped() {
+		t.Fatal("expected a batch past the threshold to trip the breaker")
+	}
+	if c := cb.ConsecFailures(); c != 7 {
+		t.Fatalf("expected 7 consecutive failures, got %d", c)
+	}
+
+	cb.FailN(0)
+	cb.SuccessN(-1)
+	if f, s := cb.Failures(), cb.Successes(); f != 7 || s != 3 {
+		t.Fatalf("expected empty batches to be ignored, got %d failures and %d successes", f, s)
+	}
+}
+
+func TestTripCheckInterval(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	checks := 0
+	cb := NewBreakerWithOptions(&Options{
+		Clock:             c,
+		TripCheckInterval: time.Second,
+		ShouldTrip: func(cb *Breaker) bool {
+			checks++
+			return cb.Failures() >= 3
+		},
+	})
+
+	for i := 0; i < 5; i++ {
+		cb.Fail(nil)
+	}
+	if checks != 1 || cb.Tripped() {
+		t.Fatalf("expected one trip check without tripping, got %d checks", checks)
+	}
+
+	c.Add(time.Second)
+	cb.Fail(nil)
+	if checks != 2 || !cb.Tripped() {
+		t.Fatalf("expected the next check to trip the breaker, got %d checks", checks)
+	}
+}
+
+func TestCallEvents(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := NewBreakerWithOptions(&Options{Clock: c, CallEvents: true})
+	listener := make(chan ListenerEvent, 10)
+	cb.AddListener(listener)
+
+	failed := errors.New("failed")
+	ctx := WithMetadata(context.Background(), Metadata{"request": "1"})
+	cb.CallContext(ctx, func() error {
+		c.Add(20 * time.Millisecond)
+		return failed
+	}, 0)
+	cb.Call(func() error { return nil }, 0)
+	cb.Break()
+	cb.Call(func() error { return nil }, 0)
+
+	var calls []ListenerEvent
+	for len(listener) > 0 {
+		if e := <-listener; e.Event == BreakerCallComplete {
+			calls = append(calls, e)
+		}
+	}
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 call events, not counting the rejected call, got %d", len(calls))
+	}
+	if e := calls[0]; e.Err != failed || e.Duration != 20*time.Millisecond || e.Metadata["request"] != "1" {
+		t.Fatalf("expected the failed call's error, duration and metadata, got %+v", e)
+	}
+	if calls[1].Err != nil {
+		t.Fatalf("expected no error for the successful call, got %v", calls[1].Err)
+	}
+	if s := BreakerCallComplete.String(); s != "BreakerCallComplete" {
+		t.Fatalf("expected BreakerCallComplete, got %s", s)
+	}
+}
+
+func TestResetDropsCallsInFlight(t *testing.T) {
+	cb := NewThresholdBreaker(1)
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- cb.Call(func() error {
+			close(started)
+			<-release
+			return errors.New("failed")
+		}, 0)
+	}()
+
+	<-started
+	cb.Reset()
+	close(release)
+	if err := <-done; err == nil {
+		t.Fatal("expected the call's error to be returned")
+	}
+	if cb.Failures() != 0 || cb.Tripped() {
+		t.Fatal("expected the failure of a call started before the reset not to be recorded")
+	}
+
+	cb.Call(func() error { return nil }, 0)
+	cb.Trip()
+	cb.ResetStats()
+	if cb.Successes() != 0 {
+		t.Fatalf("expected ResetStats to clear the window, got %d successes", cb.Successes())
+	}
+	if !cb.Tripped() {
+		t.Fatal("expected ResetStats to leave the breaker tripped")
+	}
+}
@@ -0,0 +1,123 @@
+package circuittest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+// AutoClockInterval is how often, in real time, an AutoClock advances.
+const AutoClockInterval = time.Millisecond
+
+// AutoClock is a mock clock that advances on its own, Multiplier times as fast
+// as real time, so that scenarios spanning minutes of backoff and recovery run
+// in milliseconds without the test scripting every step of time:
+//
+//	c := circuittest.NewAutoClock(1000)
+//	defer c.Stop()
+//	cb := circuit.NewBreakerWithOptions(&circuit.Options{Clock: c})
+//
+// Timers fire as the clock passes them, as with clock.Mock. Unlike a
+// clock.Mock, which is frozen until the test advances it, an AutoClock suits
+// code that waits on the clock from goroutines the test does not control.
+// Freeze stops it advancing on its own, and Add advances it by hand either
+// way. Like clock.Mock, it starts at the Unix epoch.
+type AutoClock struct {
+	mock       *clock.Mock
+	multiplier float64
+	frozen     bool
+	lock       sync.Mutex // serializes advancing the mock
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+var _ clock.Clock = (*AutoClock)(nil)
+
+// NewAutoClock creates an AutoClock advancing multiplier times as fast as real
+// time, and starts it. Stop it once the test is done with it.
+func NewAutoClock(multiplier float64) *AutoClock {
+	c := &AutoClock{
+		mock:       clock.NewMock(),
+		multiplier: multiplier,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *AutoClock) run() {
+	defer close(c.done)
+	t := time.NewTicker(AutoClockInterval)
+	defer t.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case now := <-t.C:
+			elapsed := now.Sub(last)
+			last = now
+			c.lock.Lock()
+			if !c.frozen {
+				c.mock.Add(time.Duration(float64(elapsed) * c.multiplier))
+			}
+			c.lock.Unlock()
+		}
+	}
+}
+
+// Stop stops the clock advancing on its own for good.
+func (c *AutoClock) Stop() {
+	select {
+	case <-c.stop:
+	default:
+		close(c.stop)
+	}
+	<-c.done
+}
+
+// Freeze stops the clock advancing on its own until Resume is called.
+func (c *AutoClock) Freeze() {
+	c.lock.Lock()
+	c.frozen = true
+	c.lock.Unlock()
+}
+
+// Resume lets a frozen clock advance on its own again.
+func (c *AutoClock) Resume() {
+	c.lock.Lock()
+	c.frozen = false
+	c.lock.Unlock()
+}
+
+// Add advances the clock by d, firing the timers it passes.
+func (c *AutoClock) Add(d time.Duration) {
+	c.lock.Lock()
+	c.mock.Add(d)
+	c.lock.Unlock()
+}
+
+// After implements clock.Clock.
+func (c *AutoClock) After(d time.Duration) <-chan time.Time { return c.mock.After(d) }
+
+// AfterFunc implements clock.Clock.
+func (c *AutoClock) AfterFunc(d time.Duration, f func()) *clock.Timer {
+	return c.mock.AfterFunc(d, f)
+}
+
+// Now implements clock.Clock.
+func (c *AutoClock) Now() time.Time { return c.mock.Now() }
+
+// Sleep implements clock.Clock. It returns once the clock has advanced by d.
+func (c *AutoClock) Sleep(d time.Duration) { c.mock.Sleep(d) }
+
+// Tick implements clock.Clock.
+func (c *AutoClock) Tick(d time.Duration) <-chan time.Time { return c.mock.Tick(d) }
+
+// Ticker implements clock.Clock.
+func (c *AutoClock) Ticker(d time.Duration) *clock.Ticker { return c.mock.Ticker(d) }
+
+// Timer implements clock.Clock.
+func (c *AutoClock) Timer(d time.Duration) *clock.Timer { return c.mock.Timer(d) }
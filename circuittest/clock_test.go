@@ -0,0 +1,58 @@
+package circuittest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+func TestAutoClock(t *testing.T) {
+	c := NewAutoClock(10000)
+	defer c.Stop()
+
+	start := c.Now()
+	c.Sleep(time.Minute)
+	if elapsed := c.Now().Sub(start); elapsed < time.Minute {
+		t.Fatalf("expected Sleep to return once a minute had passed, got %s", elapsed)
+	}
+
+	cb := circuit.NewBreakerWithOptions(&circuit.Options{
+		Clock:      c,
+		ShouldTrip: circuit.ConsecutiveTripFunc(1),
+	})
+	cb.Fail(errors.New("boom"))
+	if !cb.Tripped() {
+		t.Fatal("expected the breaker to trip")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !cb.Ready() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the breaker to become ready as the clock advanced")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAutoClockFreeze(t *testing.T) {
+	c := NewAutoClock(1000)
+	defer c.Stop()
+
+	c.Freeze()
+	frozen := c.Now()
+	time.Sleep(10 * time.Millisecond)
+	if !c.Now().Equal(frozen) {
+		t.Fatal("expected a frozen clock not to advance on its own")
+	}
+	c.Add(time.Second)
+	if got := c.Now().Sub(frozen); got != time.Second {
+		t.Fatalf("expected Add to advance a frozen clock by 1s, got %s", got)
+	}
+
+	c.Resume()
+	time.Sleep(10 * time.Millisecond)
+	if !c.Now().After(frozen.Add(time.Second)) {
+		t.Fatal("expected a resumed clock to advance on its own")
+	}
+}
@@ -0,0 +1,281 @@
+// Package circuittest provides helpers for testing code that uses circuit
+// breakers.
+package circuittest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+	"github.com/facebookgo/clock"
+)
+
+// ErrScripted is the error returned by calls a ScriptedBreaker is scripted to
+// fail.
+var ErrScripted = errors.New("circuittest: scripted failure")
+
+type stepKind int
+
+const (
+	failStep stepKind = iota
+	openStep
+	succeedStep
+)
+
+type step struct {
+	kind  stepKind
+	calls int
+	err   error
+	open  time.Duration
+}
+
+// ScriptedBreaker behaves as a script of steps declares, so that tests can
+// express complex breaker behavior declaratively instead of driving a real
+// breaker into the right state:
+//
+//	cb := circuittest.Script().Fails(3).ThenOpenFor(30 * time.Second).ThenSucceeds()
+//
+// Steps are run in order. Time only passes when the test advances Clock, so
+// scripts are deterministic. Once the script is finished, calls are passed
+// through to the wrapped function.
+//
+// ScriptedBreaker implements circuit.CircuitBreaker, so it can stand in for a
+// circuit.Breaker in code written against the interface.
+type ScriptedBreaker struct {
+	// Clock times the open steps. It starts at the Unix epoch.
+	Clock *clock.Mock
+
+	steps     []step
+	current   int
+	calls     int // in the current step
+	openUntil time.Time
+	started   bool // whether the current step has started
+	lock      sync.Mutex
+
+	failures, successes, rejects, consecFailures int64
+	subscribers                                  []chan circuit.BreakerEvent
+}
+
+var _ circuit.CircuitBreaker = (*ScriptedBreaker)(nil)
+
+// Script creates an empty ScriptedBreaker, which passes every call through.
+func Script() *ScriptedBreaker {
+	return &ScriptedBreaker{Clock: clock.NewMock()}
+}
+
+// Fails adds a step failing the next n calls with ErrScripted, without running
+// the wrapped function.
+func (s *ScriptedBreaker) Fails(n int) *ScriptedBreaker {
+	return s.FailsWith(n, ErrScripted)
+}
+
+// FailsWith adds a step failing the next n calls with err, without running the
+// wrapped function.
+func (s *ScriptedBreaker) FailsWith(n int, err error) *ScriptedBreaker {
+	return s.add(step{kind: failStep, calls: n, err: err})
+}
+
+// OpenFor adds a step rejecting calls with circuit.ErrBreakerOpen until Clock
+// has advanced by d from when the step started. A step starts when the one
+// before it finishes, or, for the first step, when the breaker is first used.
+func (s *ScriptedBreaker) OpenFor(d time.Duration) *ScriptedBreaker {
+	return s.add(step{kind: openStep, open: d})
+}
+
+// Succeeds adds a step passing every call through to the wrapped function.
+// Steps added after it are never reached.
+func (s *ScriptedBreaker) Succeeds() *ScriptedBreaker {
+	return s.add(step{kind: succeedStep})
+}
+
+// ThenFails is Fails, for readability when chaining steps.
+func (s *ScriptedBreaker) ThenFails(n int) *ScriptedBreaker {
+	return s.Fails(n)
+}
+
+// ThenFailsWith is FailsWith, for readability when chaining steps.
+func (s *ScriptedBreaker) ThenFailsWith(n int, err error) *ScriptedBreaker {
+	return s.FailsWith(n, err)
+}
+
+// ThenOpenFor is OpenFor, for readability when chaining steps.
+func (s *ScriptedBreaker) ThenOpenFor(d time.Duration) *ScriptedBreaker {
+	return s.OpenFor(d)
+}
+
+// ThenSucceeds is Succeeds, for readability when chaining steps.
+func (s *ScriptedBreaker) ThenSucceeds() *ScriptedBreaker {
+	return s.Succeeds()
+}
+
+func (s *ScriptedBreaker) add(st step) *ScriptedBreaker {
+	s.lock.Lock()
+	s.steps = append(s.steps, st)
+	s.lock.Unlock()
+	return s
+}
+
+// Call runs the next step of the script for a call to fn. The timeout is
+// ignored.
+func (s *ScriptedBreaker) Call(fn func() error, timeout time.Duration) error {
+	return s.CallContext(context.Background(), fn, timeout)
+}
+
+// CallContext is the same as Call.
+func (s *ScriptedBreaker) CallContext(ctx context.Context, fn func() error, timeout time.Duration) error {
+	if err := s.Allow(); err != nil {
+		return err
+	}
+	err := fn()
+	s.Record(err)
+	return err
+}
+
+// Allow runs the next step of the script for a call made in two steps. In an
+// open step it returns circuit.ErrBreakerOpen, and in a failing step the
+// step's error, in place of the call's. Otherwise the call may be made, and
+// its outcome must be passed to Record.
+func (s *ScriptedBreaker) Allow() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := s.step()
+	switch {
+	case st == nil || st.kind == succeedStep:
+		return nil
+	case st.kind == openStep:
+		s.rejects++
+		return circuit.ErrBreakerOpen
+	}
+
+	// A failing step.
+	s.fail()
+	s.calls++
+	if s.calls == st.calls {
+		s.advance()
+	}
+	return st.err
+}
+
+// Record records the outcome of a call allowed by Allow in Stats. It does not
+// affect the script.
+func (s *ScriptedBreaker) Record(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err != nil {
+		s.fail()
+		return
+	}
+	s.successes++
+	s.consecFailures = 0
+}
+
+// fail records a failure. fail assumes the caller holds the lock.
+func (s *ScriptedBreaker) fail() {
+	s.failures++
+	s.consecFailures++
+	s.send(circuit.BreakerFail)
+}
+
+// State returns circuit.StateOpen while the script is in an open step, and
+// circuit.StateClosed otherwise.
+func (s *ScriptedBreaker) State() circuit.State {
+	if s.Tripped() {
+		return circuit.StateOpen
+	}
+	return circuit.StateClosed
+}
+
+// Stats returns the outcomes of the calls made so far, and whether the script
+// is in an open step.
+func (s *ScriptedBreaker) Stats() circuit.Stats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := s.step()
+	stats := circuit.Stats{
+		Tripped:        st != nil && st.kind == openStep,
+		Failures:       s.failures,
+		Successes:      s.successes,
+		Rejects:        s.rejects,
+		ConsecFailures: s.consecFailures,
+	}
+	if stats.Tripped {
+		stats.NextAttempt = s.openUntil
+		stats.RetryAfter = s.openUntil.Sub(s.Clock.Now())
+	}
+	return stats
+}
+
+// Subscribe returns a channel of the breaker's events: BreakerFail for each
+// failure, BreakerTripped when an open step starts and BreakerReset when it
+// ends. As with circuit.Breaker, events are dropped if the channel is full.
+func (s *ScriptedBreaker) Subscribe() <-chan circuit.BreakerEvent {
+	ch := make(chan circuit.BreakerEvent, 100)
+	s.lock.Lock()
+	s.subscribers = append(s.subscribers, ch)
+	s.lock.Unlock()
+	return ch
+}
+
+// send sends event to the subscribers. send assumes the caller holds the
+// lock.
+func (s *ScriptedBreaker) send(event circuit.BreakerEvent) {
+	for _, ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Ready reports whether a call would be let through, that is, whether the
+// script is not in an open step.
+func (s *ScriptedBreaker) Ready() bool {
+	return !s.Tripped()
+}
+
+// Tripped reports whether the script is in an open step.
+func (s *ScriptedBreaker) Tripped() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := s.step()
+	return st != nil && st.kind == openStep
+}
+
+// step returns the current step, moving past finished steps, or nil if the
+// script is finished. step assumes the caller holds the lock.
+func (s *ScriptedBreaker) step() *step {
+	for s.current < len(s.steps) {
+		st := &s.steps[s.current]
+		switch st.kind {
+		case failStep:
+			if s.calls < st.calls {
+				return st
+			}
+		case openStep:
+			if !s.started {
+				s.started = true
+				s.openUntil = s.Clock.Now().Add(st.open)
+				s.send(circuit.BreakerTripped)
+			}
+			if s.Clock.Now().Before(s.openUntil) {
+				return st
+			}
+			s.send(circuit.BreakerReset)
+		default:
+			return st
+		}
+		s.advance()
+	}
+	return nil
+}
+
+// advance moves to the next step, starting it. advance assumes the caller
+// holds the lock.
+func (s *ScriptedBreaker) advance() {
+	s.current++
+	s.calls = 0
+	s.started = false
+	s.step()
+}
@@ -0,0 +1,93 @@
+package circuittest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+func TestScript(t *testing.T) {
+	cb := Script().Fails(2).ThenOpenFor(30 * time.Second).ThenSucceeds()
+	called := 0
+	fn := func() error {
+		called++
+		return nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := cb.Call(fn, 0); err != ErrScripted {
+			t.Fatalf("expected call %d to fail, got %v", i, err)
+		}
+	}
+	if !cb.Tripped() || cb.Ready() {
+		t.Fatal("expected breaker to be open after the failures")
+	}
+	if err := cb.Call(fn, 0); err != circuit.ErrBreakerOpen {
+		t.Fatalf("expected call to be rejected, got %v", err)
+	}
+
+	cb.Clock.Add(29 * time.Second)
+	if err := cb.Call(fn, 0); err != circuit.ErrBreakerOpen {
+		t.Fatalf("expected call to be rejected before 30s, got %v", err)
+	}
+	cb.Clock.Add(time.Second)
+	for i := 0; i < 3; i++ {
+		if err := cb.Call(fn, 0); err != nil {
+			t.Fatalf("expected call to succeed, got %v", err)
+		}
+	}
+	if called != 3 {
+		t.Fatalf("expected only succeeding calls to run, got %d", called)
+	}
+}
+
+func TestScriptFailsWith(t *testing.T) {
+	errDown := errors.New("down")
+	cb := Script().OpenFor(time.Second).ThenFailsWith(1, errDown)
+
+	if !cb.Tripped() {
+		t.Fatal("expected breaker to start open")
+	}
+	cb.Clock.Add(time.Second)
+	if err := cb.Call(func() error { return nil }, 0); err != errDown {
+		t.Fatalf("expected scripted error, got %v", err)
+	}
+	// The script is finished, so calls are passed through.
+	if err := cb.Call(func() error { return errDown }, 0); err != errDown {
+		t.Fatalf("expected the call's own error, got %v", err)
+	}
+}
+
+func TestScriptTwoStep(t *testing.T) {
+	cb := Script().Fails(1).ThenOpenFor(time.Second)
+	var breaker circuit.CircuitBreaker = cb
+	events := breaker.Subscribe()
+
+	if err := breaker.Allow(); err != ErrScripted {
+		t.Fatalf("expected Allow to return the scripted failure, got %v", err)
+	}
+	if s := breaker.State(); s != circuit.StateOpen {
+		t.Fatalf("expected the open step to open the breaker, got %v", s)
+	}
+	if err := breaker.Allow(); err != circuit.ErrBreakerOpen {
+		t.Fatalf("expected Allow to reject the call, got %v", err)
+	}
+
+	cb.Clock.Add(time.Second)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("expected the finished script to allow the call, got %v", err)
+	}
+	breaker.Record(nil)
+
+	stats := breaker.Stats()
+	if stats.Failures != 1 || stats.Successes != 1 || stats.Rejects != 1 || stats.Tripped {
+		t.Fatalf("expected 1 failure, 1 success and 1 rejection, got %+v", stats)
+	}
+	for _, want := range []circuit.BreakerEvent{circuit.BreakerFail, circuit.BreakerTripped, circuit.BreakerReset} {
+		if got := <-events; got != want {
+			t.Fatalf("expected event %v, got %v", want, got)
+		}
+	}
+}
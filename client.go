@@ -1,9 +1,14 @@
 package circuit
 
 import (
+	"context"
+	"errors"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,16 +17,54 @@ import (
 // By default, the client will use its defaultBreaker. A BreakerLookup function may be
 // provided to allow different breakers to be used based on the circumstance. See the
 // implementation of NewHostBasedHTTPClient for an example of this.
+//
+// If WriteBreakerLookup is set, requests using methods other than GET, HEAD,
+// OPTIONS and TRACE are governed by the breaker it returns instead, so that
+// reads and writes to the same service can trip independently. See
+// NewMethodBasedHTTPClient.
+//
+// Requests rejected by an open breaker are never sent. They are counted
+// separately from requests that were sent and failed, see Rejected and Failed,
+// reported to the Panel's Statter as "rejected", and passed to the
+// BreakerRejected hook if it is set.
+//
+// Failures to reach the target through a proxy can be kept off the target's
+// breaker with IsolateProxy.
+//
+// If ProbeHeader is set, such as to DefaultProbeHeader, the trial requests
+// made while a breaker is half-open carry that header with the value "1", so
+// that the services and load balancers they reach can treat them specially,
+// such as by skipping caches or routing them to a canary.
 type HTTPClient struct {
-	Client         *http.Client
-	BreakerTripped func()
-	BreakerReset   func()
-	BreakerLookup  func(*HTTPClient, interface{}) *Breaker
-	Panel          *Panel
-	timeout        time.Duration
+	// rejected and failed are updated atomically and come first so they are
+	// 64-bit aligned on 32-bit platforms.
+	rejected int64
+	failed   int64
+
+	Client             *http.Client
+	BreakerTripped     func()
+	BreakerReset       func()
+	BreakerRejected    func(method, url string)
+	BreakerLookup      func(*HTTPClient, interface{}) *Breaker
+	WriteBreakerLookup func(*HTTPClient, interface{}) *Breaker
+	Panel              *Panel
+	ProbeHeader        string
+	timeout            time.Duration
+	proxy              *Breaker
 }
 
-var defaultBreakerName = "_default"
+// DefaultProbeHeader is the conventional HTTPClient.ProbeHeader.
+const DefaultProbeHeader = "X-Circuit-Probe"
+
+var (
+	defaultBreakerName = "_default"
+	writeBreakerName   = "_write"
+	proxyBreakerName   = "_proxy"
+)
+
+// errProxyOpen is returned to the request's breaker when the proxy breaker
+// rejects the request.
+var errProxyOpen = errors.New("proxy breaker open")
 
 // NewHTTPClient provides a circuit breaker wrapper around http.Client.
 // It wraps all of the regular http.Client functions. Specifying 0 for timeout will
@@ -59,6 +102,22 @@ func NewHostBasedHTTPClient(timeout time.Duration, threshold int64, client *http
 	return brclient
 }
 
+// NewMethodBasedHTTPClient provides a circuit breaker wrapper around http.Client
+// that uses separate breakers for reads and writes. GET, HEAD, OPTIONS and TRACE
+// requests use the read breaker, which is added to the Panel as "_default".
+// All other requests use the write breaker, which is added as "_write". This
+// allows reads, which can often fall back to a cache, to keep flowing while
+// writes fail fast, or vice versa.
+func NewMethodBasedHTTPClient(read, write *Breaker, timeout time.Duration, client *http.Client) *HTTPClient {
+	brclient := NewHTTPClientWithBreaker(read, timeout, client)
+	brclient.Panel.Add(writeBreakerName, write)
+	brclient.WriteBreakerLookup = func(c *HTTPClient, val interface{}) *Breaker {
+		cb, _ := c.Panel.Get(writeBreakerName)
+		return cb
+	}
+	return brclient
+}
+
 // NewHTTPClientWithBreaker provides a circuit breaker wrapper around http.Client.
 // It wraps all of the regular http.Client functions using the provided Breaker.
 func NewHTTPClientWithBreaker(breaker *Breaker, timeout time.Duration, client *http.Client) *HTTPClient {
@@ -91,61 +150,145 @@ func NewHTTPClientWithBreaker(breaker *Breaker, timeout time.Duration, client *h
 
 // Do wraps http.Client Do()
 func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
-	var err error
-	breaker := c.breakerLookup(req.URL.String())
-	err = breaker.Call(func() error {
-		resp, err = c.Client.Do(req)
-		return err
-	}, c.timeout)
-	return resp, err
+	return c.call(req.Method, req.URL.String(), func() (*http.Request, error) {
+		return req, nil
+	})
 }
 
 // Get wraps http.Client Get()
 func (c *HTTPClient) Get(url string) (*http.Response, error) {
-	var resp *http.Response
-	breaker := c.breakerLookup(url)
-	err := breaker.Call(func() error {
-		aresp, err := c.Client.Get(url)
-		resp = aresp
-		return err
-	}, c.timeout)
-	return resp, err
+	return c.call(http.MethodGet, url, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, url, nil)
+	})
 }
 
 // Head wraps http.Client Head()
 func (c *HTTPClient) Head(url string) (*http.Response, error) {
-	var resp *http.Response
-	breaker := c.breakerLookup(url)
-	err := breaker.Call(func() error {
-		aresp, err := c.Client.Head(url)
-		resp = aresp
-		return err
-	}, c.timeout)
-	return resp, err
+	return c.call(http.MethodHead, url, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodHead, url, nil)
+	})
 }
 
 // Post wraps http.Client Post()
 func (c *HTTPClient) Post(url string, bodyType string, body io.Reader) (*http.Response, error) {
-	var resp *http.Response
-	breaker := c.breakerLookup(url)
-	err := breaker.Call(func() error {
-		aresp, err := c.Client.Post(url, bodyType, body)
-		resp = aresp
-		return err
-	}, c.timeout)
-	return resp, err
+	return c.call(http.MethodPost, url, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, url, body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", bodyType)
+		return req, nil
+	})
 }
 
 // PostForm wraps http.Client PostForm()
 func (c *HTTPClient) PostForm(url string, data url.Values) (*http.Response, error) {
+	return c.Post(url, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+}
+
+// IsolateProxy makes the client record failures of the proxy hop, such as a
+// proxy refusing connections or failing a CONNECT, on cb rather than on the
+// breaker for the request, so that a flaky proxy does not open the breakers of
+// every host reached through it. While cb is open, requests are rejected
+// without being sent. Requests that get through the proxy are recorded on cb as
+// successes, whatever their outcome.
+//
+// cb is added to the Panel as "_proxy". IsolateProxy must be called before the
+// client is used.
+func (c *HTTPClient) IsolateProxy(cb *Breaker) {
+	c.proxy = cb
+	c.Panel.Add(proxyBreakerName, cb)
+}
+
+// ProxyBreaker returns the breaker set with IsolateProxy, or nil.
+func (c *HTTPClient) ProxyBreaker() *Breaker {
+	return c.proxy
+}
+
+// isProxyError reports whether err is a failure to reach the target through a
+// proxy, which net/http reports as a net.OpError for the "proxyconnect"
+// operation for both HTTP and SOCKS5 proxies.
+func isProxyError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "proxyconnect"
+}
+
+// Rejected returns the number of requests that were rejected by an open or
+// unavailable breaker without being sent.
+func (c *HTTPClient) Rejected() int64 {
+	return atomic.LoadInt64(&c.rejected)
+}
+
+// Failed returns the number of requests that were sent but failed, including
+// requests that timed out.
+func (c *HTTPClient) Failed() int64 {
+	return atomic.LoadInt64(&c.failed)
+}
+
+// call makes the request built by newRequest, using method to url, through
+// the breaker for the request, and records rejected and failed requests.
+// Errors building the request are recorded as failures, as http.Client
+// returns them from Get and the like.
+func (c *HTTPClient) call(method, url string, newRequest func() (*http.Request, error)) (*http.Response, error) {
 	var resp *http.Response
-	breaker := c.breakerLookup(url)
-	err := breaker.Call(func() error {
-		aresp, err := c.Client.PostForm(url, data)
+	breaker := c.breakerLookupMethod(method, url)
+
+	// Canceling ctx keeps the outcome off breaker, for requests that never
+	// reached the target because of the proxy.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := breaker.call(ctx, func(info CallInfo) error {
+		var proxyToken ProbeToken
+		if c.proxy != nil {
+			token, err := c.proxy.Acquire()
+			if err != nil {
+				cancel()
+				return errProxyOpen
+			}
+			proxyToken = token
+		}
+		req, err := newRequest()
+		if err != nil {
+			proxyToken.Release()
+			return err
+		}
+		if info.Probe && c.ProbeHeader != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set(c.ProbeHeader, "1")
+		}
+		aresp, err := c.Client.Do(req)
 		resp = aresp
+		breaker.noteUpstreamTrip(resp)
+		if c.proxy != nil {
+			if isProxyError(err) {
+				cancel()
+				proxyToken.Done(err)
+			} else {
+				proxyToken.Done(nil)
+			}
+		}
 		return err
-	}, c.timeout)
+	}, 1, c.timeout)
+
+	rejectedBy := breaker
+	if err == errProxyOpen {
+		err = ErrBreakerOpen
+		rejectedBy = c.proxy
+	}
+
+	switch {
+	case err == nil:
+	case rejectedOpen(err):
+		atomic.AddInt64(&c.rejected, 1)
+		if name, ok := c.Panel.nameOf(rejectedBy); ok {
+			c.Panel.breakerRejected(name)
+		}
+		if c.BreakerRejected != nil {
+			c.BreakerRejected(method, url)
+		}
+	default:
+		atomic.AddInt64(&c.failed, 1)
+	}
 	return resp, err
 }
 
@@ -157,6 +300,18 @@ func (c *HTTPClient) breakerLookup(val interface{}) *Breaker {
 	return cb
 }
 
+// breakerLookupMethod is breakerLookup for a request using method.
+func (c *HTTPClient) breakerLookupMethod(method string, val interface{}) *Breaker {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return c.breakerLookup(val)
+	}
+	if c.WriteBreakerLookup != nil {
+		return c.WriteBreakerLookup(c, val)
+	}
+	return c.breakerLookup(val)
+}
+
 func (c *HTTPClient) runBreakerTripped() {
 	if c.BreakerTripped != nil {
 		c.BreakerTripped()
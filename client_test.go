@@ -0,0 +1,190 @@
+package circuit
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/facebookgo/clock"
+)
+
+func TestMethodBasedHTTPClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	read, write := NewBreaker(), NewBreaker()
+	client := NewMethodBasedHTTPClient(read, write, 0, nil)
+
+	write.Break()
+	if _, err := client.Post(ts.URL, "text/plain", nil); err != ErrBreakerOpen {
+		t.Fatalf("expected write to fail with open write breaker, got %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL, nil)
+	if _, err := client.Do(req); err != ErrBreakerOpen {
+		t.Fatalf("expected DELETE to use the write breaker, got %v", err)
+	}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("expected read to succeed with open write breaker, got %v", err)
+	}
+	resp.Body.Close()
+
+	write.Reset()
+	read.Break()
+	if _, err := client.Head(ts.URL); err != ErrBreakerOpen {
+		t.Fatalf("expected read to fail with open read breaker, got %v", err)
+	}
+	resp, err = client.PostForm(ts.URL, nil)
+	if err != nil {
+		t.Fatalf("expected write to succeed with open read breaker, got %v", err)
+	}
+	resp.Body.Close()
+
+	if cb, ok := client.Panel.Get("_write"); !ok || cb != write {
+		t.Fatal("expected write breaker to be added to the panel")
+	}
+}
+
+func TestHTTPClientRejected(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	statter := newTestStatter()
+	cb := NewBreaker()
+	client := NewHTTPClientWithBreaker(cb, 0, nil)
+	client.Panel.Statter = statter
+	var rejected []string
+	client.BreakerRejected = func(method, url string) {
+		rejected = append(rejected, method+" "+url)
+	}
+
+	if _, err := client.Get("http://127.0.0.1:0/"); err == nil {
+		t.Fatal("expected request to an invalid address to fail")
+	}
+
+	cb.Break()
+	if _, err := client.Get(ts.URL); err != ErrBreakerOpen {
+		t.Fatalf("expected ErrBreakerOpen, got %v", err)
+	}
+
+	if n := client.Failed(); n != 1 {
+		t.Fatalf("expected 1 failed request, got %d", n)
+	}
+	if n := client.Rejected(); n != 1 {
+		t.Fatalf("expected 1 rejected request, got %d", n)
+	}
+	if len(rejected) != 1 || rejected[0] != "GET "+ts.URL {
+		t.Fatalf("expected rejection hook to be called for GET %s, got %v", ts.URL, rejected)
+	}
+	if c := statter.Count("circuit._default.rejected"); c != 1 {
+		t.Fatalf("expected rejected count to be 1, got %d", c)
+	}
+
+	cb.Reset()
+	cb.SetUnavailable("maintenance")
+	if _, err := client.Get(ts.URL); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if n := client.Rejected(); n != 2 {
+		t.Fatalf("expected the request to an unavailable breaker to be rejected, got %d rejected", n)
+	}
+	if n := client.Failed(); n != 1 {
+		t.Fatalf("expected the rejected request not to count as failed, got %d failed", n)
+	}
+	if c := statter.Count("circuit._default.rejected"); c != 2 {
+		t.Fatalf("expected rejected count to be 2, got %d", c)
+	}
+}
+
+func TestHTTPClientIsolateProxy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	// A proxy that refuses connections.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyURL := &url.URL{Scheme: "http", Host: l.Addr().String()}
+	l.Close()
+
+	statter := newTestStatter()
+	cb := NewThresholdBreaker(1)
+	client := NewHTTPClientWithBreaker(cb, 0, &http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)},
+	})
+	client.Panel.Statter = statter
+	proxy := NewThresholdBreaker(1)
+	client.IsolateProxy(proxy)
+
+	if _, err := client.Get(ts.URL); !isProxyError(err) {
+		t.Fatalf("expected a proxy error, got %v", err)
+	}
+	if !proxy.Tripped() {
+		t.Fatal("expected proxy breaker to trip")
+	}
+	if cb.Tripped() || cb.Failures() != 0 {
+		t.Fatalf("expected request breaker to be unaffected, got %d failures", cb.Failures())
+	}
+
+	if _, err := client.Get(ts.URL); err != ErrBreakerOpen {
+		t.Fatalf("expected ErrBreakerOpen while the proxy breaker is open, got %v", err)
+	}
+	if n := client.Rejected(); n != 1 {
+		t.Fatalf("expected 1 rejected request, got %d", n)
+	}
+	if c := statter.Count("circuit._proxy.rejected"); c != 1 {
+		t.Fatalf("expected proxy rejected count to be 1, got %d", c)
+	}
+	if cb.Failures() != 0 || cb.Successes() != 0 {
+		t.Fatal("expected request breaker to record nothing for rejected requests")
+	}
+	if got, ok := client.Panel.Get("_proxy"); !ok || got != proxy {
+		t.Fatal("expected proxy breaker to be added to the panel")
+	}
+	if client.ProxyBreaker() != proxy {
+		t.Fatal("expected ProxyBreaker to return the proxy breaker")
+	}
+}
+
+func TestHTTPClientProbeHeader(t *testing.T) {
+	probes := make(chan string, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes <- r.Header.Get(DefaultProbeHeader)
+	}))
+	defer ts.Close()
+
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	cb := NewBreakerWithOptions(&Options{
+		Clock:   c,
+		BackOff: &backoff.ConstantBackOff{Interval: time.Second},
+	})
+	client := NewHTTPClientWithBreaker(cb, 0, nil)
+	client.ProbeHeader = DefaultProbeHeader
+
+	if _, err := client.Get(ts.URL); err != nil {
+		t.Fatal(err)
+	}
+	if h := <-probes; h != "" {
+		t.Fatalf("expected no probe header while closed, got %q", h)
+	}
+
+	cb.Trip()
+	c.Add(2 * time.Second)
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	if _, err := client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if h := <-probes; h != "1" {
+		t.Fatalf("expected the probe header on the trial request, got %q", h)
+	}
+	if req.Header.Get(DefaultProbeHeader) != "" {
+		t.Fatal("expected the caller's request to be left unchanged")
+	}
+}
@@ -0,0 +1,80 @@
+package circuit
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+// CoarseClock is a clock.Clock whose Now returns a time cached by a ticker, so
+// reading it costs an atomic load rather than a call into the operating
+// system. The time it returns lags the clock it wraps by up to its
+// resolution. Its other methods are those of the clock it wraps.
+type CoarseClock struct {
+	clock.Clock
+
+	now    int64 // nanoseconds since the Unix epoch
+	ticker *clock.Ticker
+	done   chan struct{}
+}
+
+// NewCoarseClock returns a CoarseClock that reads c every resolution. It must
+// be stopped with Stop when it is no longer needed.
+func NewCoarseClock(c clock.Clock, resolution time.Duration) *CoarseClock {
+	cc := &CoarseClock{
+		Clock:  c,
+		now:    c.Now().UnixNano(),
+		ticker: c.Ticker(resolution),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case t := <-cc.ticker.C:
+				atomic.StoreInt64(&cc.now, t.UnixNano())
+			case <-cc.done:
+				return
+			}
+		}
+	}()
+	return cc
+}
+
+// Now returns the time as of the last tick.
+func (cc *CoarseClock) Now() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&cc.now))
+}
+
+// Stop stops the clock's ticker. Now keeps returning the time of the last
+// tick.
+func (cc *CoarseClock) Stop() {
+	cc.ticker.Stop()
+	close(cc.done)
+}
+
+type coarseClockKey struct {
+	clock      clock.Clock
+	resolution time.Duration
+}
+
+var (
+	coarseClocks     = make(map[coarseClockKey]*CoarseClock)
+	coarseClocksLock sync.Mutex
+)
+
+// sharedCoarseClock returns a CoarseClock reading c every resolution, shared
+// by every breaker asking for the same, so that one ticker serves them all.
+func sharedCoarseClock(c clock.Clock, resolution time.Duration) *CoarseClock {
+	coarseClocksLock.Lock()
+	defer coarseClocksLock.Unlock()
+
+	key := coarseClockKey{c, resolution}
+	cc, ok := coarseClocks[key]
+	if !ok {
+		cc = NewCoarseClock(c, resolution)
+		coarseClocks[key] = cc
+	}
+	return cc
+}
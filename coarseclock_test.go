@@ -0,0 +1,50 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestCoarseClock(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	start := c.Now()
+	cc := NewCoarseClock(c, time.Second)
+	defer cc.Stop()
+
+	c.Add(500 * time.Millisecond)
+	if now := cc.Now(); !now.Equal(start) {
+		t.Fatalf("expected the time not to change between ticks, got %v", now.Sub(start))
+	}
+
+	c.Add(600 * time.Millisecond)
+	want := start.Add(time.Second)
+	for deadline := time.Now().Add(time.Second); !cc.Now().Equal(want); {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the time of the tick, got %v", cc.Now().Sub(start))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOptionsCoarseClock(t *testing.T) {
+	c := clock.NewMock()
+	opts := func() *Options {
+		return &Options{Clock: c, CoarseClock: true, WindowTime: time.Second, WindowBuckets: 10}
+	}
+	a := NewBreakerWithOptions(opts())
+	b := NewBreakerWithOptions(opts())
+
+	cc, ok := a.counts.clock.(*CoarseClock)
+	if !ok {
+		t.Fatalf("expected the window to use a coarse clock, got %T", a.counts.clock)
+	}
+	if b.counts.clock != cc || cc.Clock != c {
+		t.Fatal("expected breakers with the same clock and buckets to share a coarse clock")
+	}
+	if a.Clock != c {
+		t.Fatal("expected the breaker itself to keep the precise clock")
+	}
+}
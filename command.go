@@ -0,0 +1,97 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrMaxConcurrency is returned by Command.Execute when the command is already
+// running MaxConcurrent times.
+var ErrMaxConcurrency = errors.New("breaker max concurrency reached")
+
+// Command bundles a call with the breaker protecting it, a fallback, a
+// timeout and a concurrency limit, in the manner of hystrix-go's commands:
+//
+//	cmd := &circuit.Command{
+//		Name:          "users.get",
+//		Panel:         panel,
+//		Timeout:       time.Second,
+//		MaxConcurrent: 10,
+//		Run: func(ctx context.Context) error {
+//			return fetchUser(ctx, id)
+//		},
+//		Fallback: func(ctx context.Context, err error) error {
+//			return fetchCachedUser(id)
+//		},
+//	}
+//	err := cmd.Execute(ctx)
+//
+// A Command may be executed any number of times, concurrently.
+type Command struct {
+	// Name is the name of the command's breaker in Panel. If Panel has no
+	// breaker by that name, one is added with the panel's Defaults.
+	Name string
+
+	// Panel holds the command's breaker.
+	Panel *Panel
+
+	// Run makes the call. Its context is canceled once Execute returns.
+	Run func(ctx context.Context) error
+
+	// Fallback, if non-nil, is called with the error of a call that could
+	// not be made or failed, and its result is returned by Execute instead.
+	Fallback func(ctx context.Context, err error) error
+
+	// Timeout, if non-zero, is how long to wait for Run before failing with
+	// ErrBreakerTimeout.
+	Timeout time.Duration
+
+	// MaxConcurrent, if non-zero, is the number of calls that may run at
+	// once. Executions beyond it fail with ErrMaxConcurrency without making
+	// the call, and are recorded as rejected rather than failed.
+	MaxConcurrent int
+
+	once    sync.Once
+	breaker *Breaker
+	sem     chan struct{}
+}
+
+// Breaker returns the command's breaker from its Panel, adding it if needed.
+func (c *Command) Breaker() *Breaker {
+	c.once.Do(func() {
+		c.breaker = c.Panel.getOrAdd(c.Name, nil)
+		if c.MaxConcurrent > 0 {
+			c.sem = make(chan struct{}, c.MaxConcurrent)
+		}
+	})
+	return c.breaker
+}
+
+// Execute runs the command through its breaker. If the call is rejected or
+// fails, the error is passed to Fallback if there is one.
+func (c *Command) Execute(ctx context.Context) error {
+	err := c.execute(ctx)
+	if err != nil && c.Fallback != nil {
+		return c.Fallback(ctx, err)
+	}
+	return err
+}
+
+func (c *Command) execute(ctx context.Context) error {
+	cb := c.Breaker()
+	if c.sem != nil {
+		select {
+		case c.sem <- struct{}{}:
+			defer func() { <-c.sem }()
+		default:
+			cb.Reject()
+			return ErrMaxConcurrency
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	return cb.Do(ctx, c.Run, c.Timeout)
+}
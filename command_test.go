@@ -0,0 +1,66 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	p := NewPanel()
+	p.Defaults = &Options{ShouldTrip: ThresholdTripFunc(1)}
+	errFailed := errors.New("failed")
+	var fallbackErr error
+	cmd := &Command{
+		Name:  "users",
+		Panel: p,
+		Run:   func(ctx context.Context) error { return errFailed },
+		Fallback: func(ctx context.Context, err error) error {
+			fallbackErr = err
+			return nil
+		},
+	}
+
+	if err := cmd.Execute(context.Background()); err != nil || fallbackErr != errFailed {
+		t.Fatalf("expected the fallback to handle the failure, got %v and %v", err, fallbackErr)
+	}
+	cb, ok := p.Get("users")
+	if !ok || cb != cmd.Breaker() || !cb.Tripped() {
+		t.Fatal("expected the failure to trip a breaker added to the panel")
+	}
+
+	other := &Command{Name: "users", Panel: p, Run: func(ctx context.Context) error { return nil }}
+	if err := other.Execute(context.Background()); err != ErrBreakerOpen {
+		t.Fatalf("expected commands with the same name to share a breaker, got %v", err)
+	}
+}
+
+func TestCommandMaxConcurrent(t *testing.T) {
+	running := make(chan struct{})
+	release := make(chan struct{})
+	cmd := &Command{
+		Name:          "slow",
+		Panel:         NewPanel(),
+		MaxConcurrent: 1,
+		Run: func(ctx context.Context) error {
+			close(running)
+			<-release
+			return nil
+		},
+	}
+
+	done := make(chan error)
+	go func() { done <- cmd.Execute(context.Background()) }()
+	<-running
+	if err := cmd.Execute(context.Background()); err != ErrMaxConcurrency {
+		t.Fatalf("expected ErrMaxConcurrency, got %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if cb := cmd.Breaker(); cb.Failures() != 0 || cb.Rejects() != 1 {
+		t.Fatalf("expected the execution to be recorded as rejected, got %d failures and %d rejects",
+			cb.Failures(), cb.Rejects())
+	}
+}
@@ -0,0 +1,114 @@
+// Package connectcircuit integrates circuit breakers with Connect-RPC
+// clients.
+//
+// Connect clients make their requests with an HTTP client, and unary Connect
+// errors are error responses with a JSON body giving a code. NewTransport
+// returns an http.RoundTripper that records the responses whose code is the
+// server's fault as failures, for the client of a Connect service:
+//
+//	httpClient := &http.Client{Transport: connectcircuit.NewTransport(cb, nil)}
+//	client := greetv1connect.NewGreetServiceClient(httpClient, addr)
+//
+// It needs no import of Connect itself. Only the Connect protocol is
+// classified: errors of streaming calls, and of clients using the gRPC or
+// gRPC-Web protocols, arrive in the body of a successful response and are
+// recorded as successes.
+package connectcircuit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+// FailureCodes are the Connect error codes that NewTransport records as
+// failures: those meaning the server or something on the way to it is in
+// trouble. Other codes, such as "invalid_argument" or "not_found", mean the
+// request itself was at fault, and are recorded as successes. The table may be
+// changed before transports are created.
+var FailureCodes = map[string]bool{
+	"unknown":            true,
+	"deadline_exceeded":  true,
+	"resource_exhausted": true,
+	"internal":           true,
+	"unavailable":        true,
+	"data_loss":          true,
+}
+
+// maxErrorBody is the most of an error response body read for its code.
+const maxErrorBody = 64 << 10
+
+// Error is a Connect unary error response.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return "connect error " + e.Code
+	}
+	return "connect error " + e.Code + ": " + e.Message
+}
+
+// NewTransport returns a Transport protecting Connect requests made with rt
+// with cb, recording responses with an error code in FailureCodes as
+// failures. Passing in a nil rt will wrap http.DefaultTransport.
+func NewTransport(cb *circuit.Breaker, rt http.RoundTripper) *circuit.Transport {
+	codes := make(map[string]bool, len(FailureCodes))
+	for code, fail := range FailureCodes {
+		codes[code] = fail
+	}
+	t := circuit.NewTransport(cb, rt)
+	t.ResponseError = func(resp *http.Response) error {
+		if err := ResponseError(resp); err != nil && codes[err.Code] {
+			return err
+		}
+		return nil
+	}
+	return t
+}
+
+// ResponseError returns the Connect error of resp, or nil if it is a success.
+// The start of the body of an error response is read, and put back so that
+// the caller can read it again. Error responses without a Connect error body,
+// such as from a proxy, are given the code Connect clients give them based on
+// their status.
+func ResponseError(resp *http.Response) *Error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+
+	var e Error
+	if err != nil || json.Unmarshal(body, &e) != nil || e.Code == "" {
+		return &Error{Code: statusCode(resp.StatusCode), Message: resp.Status}
+	}
+	return &e
+}
+
+// statusCode returns the code Connect clients give an error response without
+// a Connect error body.
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "internal"
+	case http.StatusUnauthorized:
+		return "unauthenticated"
+	case http.StatusForbidden:
+		return "permission_denied"
+	case http.StatusNotFound:
+		return "unimplemented"
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return "unavailable"
+	}
+	return "unknown"
+}
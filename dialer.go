@@ -0,0 +1,72 @@
+package circuit
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// Dialer wraps a net.Dialer with one circuit breaker per address, so repeated
+// dial failures to an endpoint short-circuit at the connection layer. Its
+// DialContext method has the signature expected by http.Transport, database
+// drivers and most other clients that accept a custom dialer.
+type Dialer struct {
+	// Dialer is used to make connections. A zero net.Dialer is used if nil.
+	Dialer *net.Dialer
+
+	// Panel holds the breakers, named by address.
+	Panel *Panel
+
+	// NewBreaker creates the breaker for an address the first time it is
+	// dialed.
+	NewBreaker func(address string) *Breaker
+
+	breakerLock sync.Mutex
+}
+
+// NewDialer provides a circuit breaker wrapper around net.Dialer. Each address
+// gets a breaker that trips after threshold consecutive dial failures. Passing
+// a nil dialer will use a zero net.Dialer.
+func NewDialer(dialer *net.Dialer, threshold int64) *Dialer {
+	return &Dialer{
+		Dialer: dialer,
+		Panel:  NewPanel(),
+		NewBreaker: func(string) *Breaker {
+			return NewConsecutiveBreaker(threshold)
+		},
+	}
+}
+
+// Dial wraps net.Dialer Dial()
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext wraps net.Dialer DialContext(). If the breaker for address is
+// open, it returns ErrBreakerOpen without dialing.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	var conn net.Conn
+	err := d.breaker(address).CallContext(ctx, func() error {
+		var err error
+		conn, err = dialer.DialContext(ctx, network, address)
+		return err
+	}, 0)
+	return conn, err
+}
+
+func (d *Dialer) breaker(address string) *Breaker {
+	d.breakerLock.Lock()
+	defer d.breakerLock.Unlock()
+
+	cb, ok := d.Panel.Get(address)
+	if !ok {
+		cb = d.NewBreaker(address)
+		d.Panel.Add(address, cb)
+	}
+	return cb
+}
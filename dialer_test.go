@@ -0,0 +1,59 @@
+package circuit
+
+import (
+	"net"
+	"testing"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestDialer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// Grab an address nothing is listening on.
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	d := NewDialer(nil, 2)
+	d.NewBreaker = func(string) *Breaker {
+		cb := NewConsecutiveBreaker(2)
+		cb.Clock = clock.NewMock()
+		return cb
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := d.Dial("tcp", closedAddr); err == nil || err == ErrBreakerOpen {
+			t.Fatalf("expected dial error, got %v", err)
+		}
+	}
+	if _, err := d.Dial("tcp", closedAddr); err != ErrBreakerOpen {
+		t.Fatalf("expected ErrBreakerOpen after repeated dial failures, got %v", err)
+	}
+
+	conn, err := d.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("expected dial to a healthy address to succeed, got %v", err)
+	}
+	conn.Close()
+
+	if cb, ok := d.Panel.Get(closedAddr); !ok || !cb.Tripped() {
+		t.Fatal("expected the failing address's breaker to be tripped in the panel")
+	}
+}
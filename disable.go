@@ -0,0 +1,35 @@
+package circuit
+
+import "sync/atomic"
+
+// disabledAll is set by DisableAll.
+var disabledAll int32
+
+// DisableAll disables every breaker, as Breaker.Disable does, until EnableAll
+// is called. It is a kill switch for when breakers misbehave, such as after a
+// bad change to their thresholds.
+func DisableAll() {
+	atomic.StoreInt32(&disabledAll, 1)
+}
+
+// EnableAll undoes DisableAll. Breakers disabled individually stay disabled.
+func EnableAll() {
+	atomic.StoreInt32(&disabledAll, 0)
+}
+
+// Disable makes the breaker let every call through, whatever its state, until
+// Enable is called. Outcomes are still recorded in the window, but do not
+// change its state: failures do not trip it, and successes do not reset it.
+func (cb *Breaker) Disable() {
+	atomic.StoreInt32(&cb.disabled, 1)
+}
+
+// Enable undoes Disable.
+func (cb *Breaker) Enable() {
+	atomic.StoreInt32(&cb.disabled, 0)
+}
+
+// Disabled reports whether the breaker is disabled, by Disable or DisableAll.
+func (cb *Breaker) Disabled() bool {
+	return atomic.LoadInt32(&cb.disabled) == 1 || atomic.LoadInt32(&disabledAll) == 1
+}
@@ -0,0 +1,40 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDisable(t *testing.T) {
+	cb := NewThresholdBreaker(1)
+	cb.Break()
+	cb.Disable()
+
+	if !cb.Ready() || cb.State() != StateClosed {
+		t.Fatal("expected a disabled breaker to let calls through")
+	}
+	errFailed := errors.New("failed")
+	if err := cb.Call(func() error { return errFailed }, 0); err != errFailed {
+		t.Fatalf("expected the call to be made, got %v", err)
+	}
+	cb.Call(func() error { return nil }, 0)
+	if cb.Failures() != 1 || cb.Successes() != 1 || !cb.Stats().Disabled {
+		t.Fatal("expected a disabled breaker to record stats")
+	}
+
+	cb.Enable()
+	if cb.Ready() {
+		t.Fatal("expected an enabled breaker to be open again")
+	}
+
+	cb = NewThresholdBreaker(1)
+	DisableAll()
+	cb.Fail(nil)
+	if cb.Tripped() {
+		t.Fatal("expected failures not to trip a disabled breaker")
+	}
+	EnableAll()
+	if cb.Disabled() {
+		t.Fatal("expected EnableAll to enable breakers")
+	}
+}
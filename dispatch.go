@@ -0,0 +1,103 @@
+package circuit
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// dispatch is an event on its way to a breaker's logger, subscribers and
+// listeners, or a message on its way to the logger if log is set.
+type dispatch struct {
+	log         func(Logger)
+	event       BreakerEvent
+	err         error
+	md          Metadata
+	nextAttempt time.Time
+	duration    time.Duration
+	receivers   []subscription
+	listeners   []chan ListenerEvent
+}
+
+// DroppedEvents returns the number of events that were not delivered because
+// the breaker's event queue was full. See Options.EventQueue.
+func (cb *Breaker) DroppedEvents() int64 {
+	return atomic.LoadInt64(&cb.droppedEvents)
+}
+
+// enqueue queues d for the breaker's dispatch worker, starting the worker if
+// it is not running, or drops d if the queue is full.
+func (cb *Breaker) enqueue(d dispatch) {
+	select {
+	case cb.eventQueue <- d:
+	default:
+		atomic.AddInt64(&cb.droppedEvents, 1)
+		return
+	}
+	if atomic.CompareAndSwapInt32(&cb.dispatching, 0, 1) {
+		go cb.dispatchQueued()
+	}
+}
+
+// dispatchQueued delivers queued events until the queue is empty. The worker
+// only runs while there are events to deliver, so idle breakers hold no
+// goroutine.
+func (cb *Breaker) dispatchQueued() {
+	for {
+		select {
+		case d := <-cb.eventQueue:
+			cb.dispatch(d)
+			continue
+		default:
+		}
+		atomic.StoreInt32(&cb.dispatching, 0)
+		// An event queued after the queue was found empty but before the
+		// flag was cleared would otherwise wait for the next one.
+		if len(cb.eventQueue) == 0 || !atomic.CompareAndSwapInt32(&cb.dispatching, 0, 1) {
+			return
+		}
+	}
+}
+
+// log calls fn with the breaker's Logger, if it has one, on the dispatch
+// worker if the breaker has an event queue.
+func (cb *Breaker) log(fn func(Logger)) {
+	if cb.logger == nil {
+		return
+	}
+	if cb.eventQueue != nil {
+		cb.enqueue(dispatch{log: fn})
+		return
+	}
+	fn(cb.logger)
+}
+
+// dispatch logs d and delivers it to the subscribers and listeners it was
+// emitted to.
+func (cb *Breaker) dispatch(d dispatch) {
+	if d.log != nil {
+		d.log(cb.logger)
+		return
+	}
+	cb.logEvent(d.event)
+
+	for _, receiver := range d.receivers {
+		select {
+		case receiver.events <- d.event:
+		case <-receiver.done:
+		}
+	}
+	for _, listener := range d.listeners {
+		le := ListenerEvent{CB: cb, Event: d.event, NextAttempt: d.nextAttempt, Err: d.err, Metadata: d.md, Duration: d.duration}
+	trySend:
+		select {
+		case listener <- le:
+		default:
+			// The channel was full so attempt to pull off of it and send again.
+			select {
+			case <-listener:
+			default:
+			}
+			goto trySend
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingLogger is a Logger that blocks until release is closed.
+type blockingLogger struct {
+	release chan struct{}
+}
+
+func (l blockingLogger) Debugf(format string, v ...interface{}) { <-l.release }
+func (l blockingLogger) Infof(format string, v ...interface{})  { <-l.release }
+
+func TestEventQueue(t *testing.T) {
+	logger := blockingLogger{release: make(chan struct{})}
+	cb := NewBreakerWithOptions(&Options{
+		EventQueue: 1,
+		Logger:     logger,
+		ShouldTrip: ThresholdTripFunc(1),
+	})
+	events := cb.Subscribe()
+
+	done := make(chan struct{})
+	go func() {
+		cb.Fail(errors.New("failed"))
+		cb.Reset()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a blocked logger not to block calls")
+	}
+
+	// Of the fail event, its log message, the trip and the reset, one waits
+	// in the queue, and another may have been taken by the blocked worker.
+	dropped := cb.DroppedEvents()
+	if dropped != 2 && dropped != 3 {
+		t.Fatalf("expected 2 or 3 dropped events, got %d", dropped)
+	}
+
+	close(logger.release)
+	select {
+	case e := <-events:
+		if e != BreakerFail {
+			t.Fatalf("expected BreakerFail first, got %v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected queued events to be delivered once the logger returns")
+	}
+}
+
+func TestEventQueueOrder(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{EventQueue: 100})
+	events := cb.Subscribe()
+	for i := 0; i < 10; i++ {
+		cb.Trip()
+		cb.Reset()
+	}
+	for i := 0; i < 20; i++ {
+		want := BreakerTripped
+		if i%2 == 1 {
+			want = BreakerReset
+		}
+		select {
+		case e := <-events:
+			if e != want {
+				t.Fatalf("expected event %d to be %v, got %v", i, want, e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected event %d to be delivered", i)
+		}
+	}
+	if cb.DroppedEvents() != 0 {
+		t.Fatalf("expected no dropped events, got %d", cb.DroppedEvents())
+	}
+}
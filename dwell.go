@@ -0,0 +1,41 @@
+package circuit
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Flaps returns the number of times the breaker would have tripped or reset
+// but stayed in its state because it had not been in it for
+// Options.MinClosedDuration or Options.MinOpenDuration.
+func (cb *Breaker) Flaps() int64 {
+	return atomic.LoadInt64(&cb.flaps)
+}
+
+// openDwell returns how much longer the tripped breaker must stay open to
+// have been open for MinOpenDuration, or 0 if it has been.
+func (cb *Breaker) openDwell(now time.Time) time.Duration {
+	trippedAt := atomic.LoadInt64(&cb.trippedAt)
+	if cb.minOpen == 0 || trippedAt == 0 {
+		return 0
+	}
+	if left := time.Unix(0, trippedAt).Add(cb.minOpen).Sub(now); left > 0 {
+		return left
+	}
+	return 0
+}
+
+// closedDwelling reports whether the breaker has yet to be closed for
+// MinClosedDuration since it last reset. A breaker that has never tripped is
+// free to.
+func (cb *Breaker) closedDwelling() bool {
+	return cb.minClosed != 0 && atomic.LoadInt64(&cb.trips) > 0 && cb.closedFor() < cb.minClosed
+}
+
+// flap counts a trip or reset suppressed by the breaker's dwell times.
+func (cb *Breaker) flap(what string) {
+	atomic.AddInt64(&cb.flaps, 1)
+	cb.log(func(l Logger) {
+		l.Debugf("circuitbreaker: %s %s suppressed to prevent flapping", cb.name, what)
+	})
+}
@@ -0,0 +1,75 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestMinOpenDuration(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := NewBreakerWithOptions(&Options{
+		Clock:           c,
+		ShouldTrip:      ConsecutiveTripFunc(1),
+		MinOpenDuration: time.Minute,
+	})
+	cb.Fail(errors.New("boom"))
+	if !cb.Tripped() {
+		t.Fatal("expected the breaker to trip")
+	}
+
+	c.Add(10 * time.Second)
+	if cb.Ready() {
+		t.Fatal("expected the breaker to stay open for MinOpenDuration despite its backoff")
+	}
+	if want := time.Minute - 10*time.Second; cb.RetryAfter() != want {
+		t.Fatalf("expected to retry in %s, got %s", want, cb.RetryAfter())
+	}
+	cb.Success()
+	if !cb.Tripped() || cb.Flaps() != 1 {
+		t.Fatalf("expected the reset to be suppressed as a flap, got %d flaps", cb.Flaps())
+	}
+
+	c.Add(time.Minute)
+	if !cb.Ready() {
+		t.Fatal("expected the breaker to let a trial call through after MinOpenDuration")
+	}
+	cb.Success()
+	if cb.Tripped() {
+		t.Fatal("expected the trial call to reset the breaker")
+	}
+}
+
+func TestMinClosedDuration(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := NewBreakerWithOptions(&Options{
+		Clock:             c,
+		ShouldTrip:        ConsecutiveTripFunc(1),
+		MinClosedDuration: time.Minute,
+	})
+	boom := errors.New("boom")
+	cb.Fail(boom)
+	if !cb.Tripped() {
+		t.Fatal("expected a breaker that has never tripped to trip at once")
+	}
+	cb.Reset()
+
+	c.Add(30 * time.Second)
+	cb.Fail(boom)
+	if cb.Tripped() {
+		t.Fatal("expected the breaker to stay closed for MinClosedDuration")
+	}
+	if s := cb.Stats(); s.Flaps != 1 {
+		t.Fatalf("expected Stats to report 1 flap, got %d", s.Flaps)
+	}
+
+	c.Add(30 * time.Second)
+	cb.Fail(boom)
+	if !cb.Tripped() {
+		t.Fatal("expected the breaker to trip after MinClosedDuration")
+	}
+}
@@ -0,0 +1,82 @@
+package circuit
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// EndpointKeyFunc returns the name of the breaker for requests to u. Names end
+// up as metric labels, so they should come from a small set; requests that
+// cannot be named should get "".
+type EndpointKeyFunc func(u *url.URL) string
+
+// HostKey names breakers by the host of the request URL.
+func HostKey(u *url.URL) string {
+	return u.Host
+}
+
+// PathTemplateKey returns an EndpointKeyFunc naming breakers by host and by
+// the first of templates the request path matches, such as "/users/{id}",
+// where a segment in braces matches any one path segment:
+//
+//	circuit.PathTemplateKey("/users/{id}", "/users/{id}/posts")
+//
+// names a request to http://api.example.com/users/42 "api.example.com/users/{id}".
+// Requests whose path matches no template are named by host alone, so the
+// number of breakers stays bounded whatever paths are requested.
+func PathTemplateKey(templates ...string) EndpointKeyFunc {
+	split := make([][]string, len(templates))
+	for i, t := range templates {
+		split[i] = strings.Split(strings.Trim(t, "/"), "/")
+	}
+	return func(u *url.URL) string {
+		segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+		for i, t := range split {
+			if templateMatches(t, segments) {
+				return u.Host + "/" + strings.Trim(templates[i], "/")
+			}
+		}
+		return u.Host
+	}
+}
+
+func templateMatches(template, segments []string) bool {
+	if len(template) != len(segments) {
+		return false
+	}
+	for i, t := range template {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			continue
+		}
+		if t != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewEndpointHTTPClient provides a circuit breaker wrapper around http.Client
+// that uses one breaker per endpoint, as named by key. The breakers are added
+// to the client's Panel on first use, created with opts as the Panel's
+// Defaults. Requests whose URL cannot be parsed or named use the default
+// breaker, created with opts too.
+func NewEndpointHTTPClient(key EndpointKeyFunc, opts *Options, timeout time.Duration, client *http.Client) *HTTPClient {
+	brclient := NewHTTPClientWithBreaker(NewBreakerWithOptions(mergeOptions(opts, nil)), timeout, client)
+	brclient.Panel.Defaults = opts
+
+	brclient.BreakerLookup = func(c *HTTPClient, val interface{}) *Breaker {
+		name := ""
+		if u, err := url.Parse(val.(string)); err == nil {
+			name = key(u)
+		}
+		if name == "" {
+			breaker, _ := c.Panel.Get(defaultBreakerName)
+			return breaker
+		}
+		return c.Panel.getOrAdd(name, nil)
+	}
+
+	return brclient
+}
@@ -0,0 +1,56 @@
+package circuit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPathTemplateKey(t *testing.T) {
+	key := PathTemplateKey("/users/{id}", "/users/{id}/posts/")
+	cases := map[string]string{
+		"http://api.example.com/users/42":        "api.example.com/users/{id}",
+		"http://api.example.com/users/42/posts":  "api.example.com/users/{id}/posts",
+		"http://api.example.com/users/42/photos": "api.example.com",
+		"http://api.example.com/":                "api.example.com",
+	}
+	for raw, want := range cases {
+		u, _ := url.Parse(raw)
+		if got := key(u); got != want {
+			t.Errorf("key(%s) = %q, expected %q", raw, got, want)
+		}
+	}
+}
+
+func TestEndpointHTTPClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	client := NewEndpointHTTPClient(PathTemplateKey("/users/{id}"), &Options{
+		ShouldTrip: ThresholdTripFunc(1),
+	}, 0, nil)
+
+	for _, path := range []string{"/users/1", "/users/2", "/other"} {
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	host := strings.TrimPrefix(ts.URL, "http://")
+	users, ok := client.Panel.Get(host + "/users/{id}")
+	if !ok || users.Successes() != 2 {
+		t.Fatal("expected one breaker for the templated endpoint")
+	}
+	if cb, ok := client.Panel.Get(host); !ok || cb.Successes() != 1 {
+		t.Fatal("expected unmatched paths to use the host's breaker")
+	}
+
+	users.Fail(nil)
+	if _, err := client.Get(ts.URL + "/users/3"); err != ErrBreakerOpen {
+		t.Fatalf("expected the endpoint's breaker to be configured with the options, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package escircuit integrates circuit breakers with the Elasticsearch and
+// OpenSearch Go clients.
+//
+// Both clients spread requests over the nodes of a cluster through an
+// http.RoundTripper given in their configuration. NewTransport returns one
+// with a breaker per node, so that an overloaded node stops receiving queries
+// while the rest of the cluster serves them:
+//
+//	p := circuit.NewPanel()
+//	p.Defaults = escircuit.Options()
+//	es, err := elasticsearch.NewClient(elasticsearch.Config{
+//		Addresses: addrs,
+//		Transport: escircuit.NewTransport(p, nil),
+//	})
+//
+// A request to a node whose breaker is open fails with circuit.ErrBreakerOpen
+// without being sent, which the clients treat as a connection error: they
+// retry the request on another node. It needs no import of either client.
+package escircuit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+// FailureBackpressure is the category of failures with a 429 Too Many
+// Requests response, which nodes send when their thread pool queues are
+// full. Unlike a 5xx, it says the node is healthy but saturated, and that
+// sending it more queries only makes things worse.
+const FailureBackpressure circuit.FailureCategory = "backpressure"
+
+// StatusError is the failure recorded for a 429 or 5xx response from a node.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return "node responded with status " + strconv.Itoa(e.Code) + " " + http.StatusText(e.Code)
+}
+
+// StatusCode returns the status of the response, so that circuit's
+// CategorizeError counts 5xx responses as server errors.
+func (e *StatusError) StatusCode() int {
+	return e.Code
+}
+
+// Categorize returns FailureBackpressure for failures with a 429 response,
+// and what circuit.CategorizeError returns for the rest.
+func Categorize(err error) circuit.FailureCategory {
+	var se *StatusError
+	if errors.As(err, &se) && se.Code == http.StatusTooManyRequests {
+		return FailureBackpressure
+	}
+	return circuit.CategorizeError(err)
+}
+
+// Options returns options for the breaker of a cluster node. The breaker
+// trips when half of at least 20 requests in the window fail, or as soon as a
+// fifth of them are met with backpressure, so that a saturated node gets the
+// time to drain its queues before queries pile up on it. A new set of options
+// is returned on each call, to be adjusted as needed.
+func Options() *circuit.Options {
+	failing := circuit.RateTripFunc(0.5, 20)
+	saturated := circuit.CategoryRateTripFunc(FailureBackpressure, 0.2, 20)
+	return &circuit.Options{
+		ShouldTrip: func(cb *circuit.Breaker) bool {
+			return failing(cb) || saturated(cb)
+		},
+		Categorize: Categorize,
+	}
+}
+
+// NewTransport returns a Transport protecting requests made with rt with a
+// breaker per node, named by host and added to p as by
+// circuit.NewEndpointTransport. Responses with a 429 or 5xx status are
+// recorded as failures with a StatusError, and given to the caller as usual.
+// Passing in a nil rt will wrap http.DefaultTransport.
+func NewTransport(p *circuit.Panel, rt http.RoundTripper) *circuit.Transport {
+	t := circuit.NewEndpointTransport(p, circuit.HostKey, rt)
+	t.ResponseError = ResponseError
+	return t
+}
+
+// ResponseError returns a StatusError for a response with a 429 or 5xx
+// status, and nil for any other. It can be used as a Transport's
+// ResponseError.
+func ResponseError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return &StatusError{Code: resp.StatusCode}
+	}
+	return nil
+}
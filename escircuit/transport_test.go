@@ -0,0 +1,76 @@
+package escircuit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+func TestTransport(t *testing.T) {
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	p := circuit.NewPanel()
+	p.Defaults = Options()
+	client := &http.Client{Transport: NewTransport(p, nil)}
+	get := func() {
+		resp, err := client.Get(ts.URL + "/_search")
+		if err != nil {
+			t.Fatalf("expected the response to be returned, got %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	for i := 0; i < 16; i++ {
+		get()
+	}
+	status = http.StatusNotFound
+	get()
+	status = http.StatusServiceUnavailable
+	get()
+	status = http.StatusTooManyRequests
+	for i := 0; i < 2; i++ {
+		get()
+	}
+
+	u, _ := url.Parse(ts.URL)
+	cb, ok := p.Get(u.Host)
+	if !ok {
+		t.Fatalf("expected a breaker for node %s", u.Host)
+	}
+	if n := cb.CategoryFailures(circuit.FailureServerError); n != 1 {
+		t.Fatalf("expected 1 server error, got %d", n)
+	}
+	if n := cb.CategoryFailures(FailureBackpressure); n != 2 {
+		t.Fatalf("expected 2 backpressure failures, got %d", n)
+	}
+	if cb.Tripped() {
+		t.Fatal("expected the breaker to stay closed below the backpressure rate")
+	}
+
+	for i := 0; i < 3; i++ {
+		get()
+	}
+	if !cb.Tripped() {
+		t.Fatal("expected backpressure to trip the breaker")
+	}
+	if _, err := client.Get(ts.URL + "/_search"); err == nil {
+		t.Fatal("expected requests to the node to fail while its breaker is open")
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	err := &StatusError{Code: http.StatusTooManyRequests}
+	if s := err.Error(); s != "node responded with status 429 Too Many Requests" {
+		t.Fatalf("unexpected error string %q", s)
+	}
+	if c := circuit.CategorizeError(&StatusError{Code: 503}); c != circuit.FailureServerError {
+		t.Fatalf("expected 503 to be a server error, got %s", c)
+	}
+}
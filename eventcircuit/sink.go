@@ -0,0 +1,146 @@
+// Package eventcircuit publishes the state changes of the breakers in a
+// circuit.Panel to a message bus, such as a NATS JetStream subject or a Kafka
+// topic, so that a central service can follow breaker activity across a
+// fleet.
+//
+// The package does not import any client library. A Sink publishes through a
+// PublishFunc, which adapts the client in use:
+//
+//	sink := eventcircuit.New("circuit.events", func(ctx context.Context, subject string, data []byte) error {
+//		_, err := js.Publish(ctx, subject, data) // NATS JetStream
+//		return err
+//	})
+//	go sink.Run(ctx, panel)
+//
+// or, for Kafka:
+//
+//	sink := eventcircuit.New("circuit-events", func(ctx context.Context, topic string, data []byte) error {
+//		return writer.WriteMessages(ctx, kafka.Message{Topic: topic, Value: data})
+//	})
+//
+// Each message is the JSON encoding of an Event.
+package eventcircuit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+// SchemaVersion is the version of the Event schema. It is incremented when
+// fields are removed or change meaning; fields may be added without changing
+// it.
+const SchemaVersion = 1
+
+// Event is the schema of the messages published by a Sink.
+type Event struct {
+	// Version is the SchemaVersion the event was encoded with.
+	Version int `json:"version"`
+
+	// Source identifies the instance that published the event. It defaults
+	// to the host name.
+	Source string `json:"source"`
+
+	// Breaker is the name of the breaker in its panel.
+	Breaker string `json:"breaker"`
+
+	// Event is what happened to the breaker: "tripped", "reset", "ready",
+	// "added" or "removed".
+	Event string `json:"event"`
+
+	// State is the state of the breaker after the event: "closed", "open"
+	// or "half-open". It is empty for "removed".
+	State string `json:"state,omitempty"`
+
+	// Time is when the event was published.
+	Time time.Time `json:"time"`
+
+	// NextAttempt is when a tripped breaker will allow a trial call.
+	NextAttempt time.Time `json:"next_attempt,omitempty"`
+
+	// Cause is the cause of a "tripped" event, if the breaker was tripped by
+	// a failure.
+	Cause *circuit.TripCause `json:"cause,omitempty"`
+}
+
+// eventNames are the names of the events published, by breaker event.
+var eventNames = map[circuit.BreakerEvent]string{
+	circuit.BreakerTripped: "tripped",
+	circuit.BreakerReset:   "reset",
+	circuit.BreakerReady:   "ready",
+	circuit.BreakerAdded:   "added",
+	circuit.BreakerRemoved: "removed",
+}
+
+// PublishFunc publishes data to subject.
+type PublishFunc func(ctx context.Context, subject string, data []byte) error
+
+// Sink publishes the state changes of breakers as Events.
+type Sink struct {
+	// Subject is the subject or topic the events are published to.
+	Subject string
+
+	// Publish publishes the events.
+	Publish PublishFunc
+
+	// Source identifies this instance in the events. It defaults to the
+	// host name.
+	Source string
+
+	// OnError, if non-nil, is called with the errors of failed publishes.
+	// Events that fail to publish are dropped.
+	OnError func(error)
+
+	now func() time.Time
+}
+
+// New creates a Sink publishing to subject with publish.
+func New(subject string, publish PublishFunc) *Sink {
+	source, _ := os.Hostname()
+	return &Sink{Subject: subject, Publish: publish, Source: source, now: time.Now}
+}
+
+// Run publishes the state changes of the breakers in p until ctx is done.
+// Failures, and other events that are not state changes, are not published.
+func (s *Sink) Run(ctx context.Context, p *circuit.Panel) {
+	events, unsubscribe := p.SubscribeWithCancel()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case pe := <-events:
+			if _, ok := eventNames[pe.Event]; !ok {
+				continue
+			}
+			if err := s.publish(ctx, p, pe); err != nil && s.OnError != nil {
+				s.OnError(err)
+			}
+		}
+	}
+}
+
+func (s *Sink) publish(ctx context.Context, p *circuit.Panel, pe circuit.PanelEvent) error {
+	e := Event{
+		Version:     SchemaVersion,
+		Source:      s.Source,
+		Breaker:     pe.Name,
+		Event:       eventNames[pe.Event],
+		Time:        s.now(),
+		NextAttempt: pe.NextAttempt,
+	}
+	if cb, ok := p.Get(pe.Name); ok && pe.Event != circuit.BreakerRemoved {
+		e.State = cb.State().String()
+		if pe.Event == circuit.BreakerTripped {
+			e.Cause = cb.TripCause()
+		}
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.Publish(ctx, s.Subject, data)
+}
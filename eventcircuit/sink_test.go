@@ -0,0 +1,81 @@
+package eventcircuit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+func TestSink(t *testing.T) {
+	published := make(chan Event, 10)
+	sink := New("circuit.events", func(ctx context.Context, subject string, data []byte) error {
+		if subject != "circuit.events" {
+			t.Errorf("expected subject circuit.events, got %s", subject)
+		}
+		var e Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			t.Error(err)
+		}
+		published <- e
+		return nil
+	})
+	sink.Source = "host-1"
+
+	p := circuit.NewPanel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sink.Run(ctx, p)
+	time.Sleep(10 * time.Millisecond) // Let Run subscribe.
+
+	cb := circuit.NewThresholdBreaker(1)
+	p.Add("db", cb)
+	cb.Fail(errors.New("connection refused"))
+
+	next := func() Event {
+		select {
+		case e := <-published:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected an event to be published")
+		}
+		return Event{}
+	}
+	if e := next(); e.Event != "added" || e.Breaker != "db" || e.Source != "host-1" || e.Version != SchemaVersion {
+		t.Fatalf("expected db to be added, got %+v", e)
+	}
+	e := next()
+	if e.Event != "tripped" || e.State != "open" {
+		t.Fatalf("expected db to trip, got %+v", e)
+	}
+	if e.Cause == nil || e.Cause.Error != "connection refused" {
+		t.Fatalf("expected the trip cause, got %+v", e.Cause)
+	}
+}
+
+func TestSinkErrors(t *testing.T) {
+	errs := make(chan error, 1)
+	sink := New("circuit.events", func(ctx context.Context, subject string, data []byte) error {
+		return errors.New("unavailable")
+	})
+	sink.OnError = func(err error) { errs <- err }
+
+	p := circuit.NewPanel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sink.Run(ctx, p)
+	time.Sleep(10 * time.Millisecond)
+
+	p.Add("db", circuit.NewBreaker())
+	select {
+	case err := <-errs:
+		if err.Error() != "unavailable" {
+			t.Fatalf("expected the publish error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected OnError to be called")
+	}
+}
@@ -0,0 +1,199 @@
+package circuit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultEventLogMaxSize is the size in bytes at which an EventLog rotates its
+// file when its MaxSize is 0.
+const DefaultEventLogMaxSize = 10 << 20
+
+// DefaultEventLogMaxFiles is the number of rotated files an EventLog keeps
+// when its MaxFiles is 0.
+const DefaultEventLogMaxFiles = 5
+
+// EventLogEntry is a line of an EventLog.
+type EventLogEntry struct {
+	Time        time.Time  `json:"time"`
+	Breaker     string     `json:"breaker"`
+	Event       string     `json:"event"`
+	State       string     `json:"state,omitempty"`
+	NextAttempt time.Time  `json:"next_attempt,omitempty"`
+	Cause       *TripCause `json:"cause,omitempty"`
+
+	// Stats are the breaker's statistics when the entry was made, which may
+	// be shortly after the event.
+	Stats *Stats `json:"stats,omitempty"`
+}
+
+// EventLog appends the state transitions of the breakers in a Panel to a
+// local file, one JSON EventLogEntry per line, so that there is an on-host
+// record of the breakers' behavior for postmortems:
+//
+//	log, err := circuit.OpenEventLog("/var/log/myapp/breakers.jsonl")
+//	if err != nil {
+//		return err
+//	}
+//	defer log.Close()
+//	go log.Run(ctx, panel)
+//
+// When the file reaches MaxSize it is renamed with the suffix ".1", earlier
+// files are shifted to ".2", ".3" and so on, and those beyond MaxFiles are
+// removed.
+type EventLog struct {
+	// Path is the path of the file.
+	Path string
+
+	// MaxSize is the size in bytes at which the file is rotated.
+	// DefaultEventLogMaxSize is used if it is 0.
+	MaxSize int64
+
+	// MaxFiles is the number of rotated files kept. DefaultEventLogMaxFiles
+	// is used if it is 0.
+	MaxFiles int
+
+	// OnError, if non-nil, is called with the errors of writes made by Run.
+	OnError func(error)
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenEventLog opens the EventLog at path, creating the file if needed and
+// appending to it otherwise.
+func OpenEventLog(path string) (*EventLog, error) {
+	l := &EventLog{Path: path}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *EventLog) open() error {
+	f, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file = f
+	l.size = fi.Size()
+	return nil
+}
+
+// Run logs the state transitions of the breakers in p until ctx is done.
+// Failures and call completions are not logged.
+func (l *EventLog) Run(ctx context.Context, p *Panel) {
+	events, unsubscribe := p.subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case pe := <-events:
+			if pe.Event == BreakerFail || pe.Event == BreakerCallComplete {
+				continue
+			}
+			e := EventLogEntry{
+				Time:        realClock.Now(),
+				Breaker:     pe.Name,
+				Event:       eventName(pe.Event),
+				NextAttempt: pe.NextAttempt,
+			}
+			if cb, ok := p.Get(pe.Name); ok && pe.Event != BreakerRemoved {
+				stats := cb.Stats()
+				e.Time = cb.Clock.Now()
+				e.State = cb.State().String()
+				e.Cause = stats.TripCause
+				e.Stats = &stats
+			}
+			if err := l.Write(e); err != nil && l.OnError != nil {
+				l.OnError(err)
+			}
+		}
+	}
+}
+
+// Write appends e to the log, rotating the file first if e would take it past
+// MaxSize.
+func (l *EventLog) Write(e EventLogEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return fmt.Errorf("event log %s is closed", l.Path)
+	}
+	maxSize := l.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultEventLogMaxSize
+	}
+	if l.size > 0 && l.size+int64(len(line)) > maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// rotate shifts the rotated files along, moves the file to the first of them
+// and opens a new one.
+func (l *EventLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	maxFiles := l.MaxFiles
+	if maxFiles == 0 {
+		maxFiles = DefaultEventLogMaxFiles
+	}
+	os.Remove(rotatedName(l.Path, maxFiles))
+	var err error
+	for i := maxFiles - 1; i > 0 && err == nil; i-- {
+		err = os.Rename(rotatedName(l.Path, i), rotatedName(l.Path, i+1))
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if err == nil {
+		err = os.Rename(l.Path, rotatedName(l.Path, 1))
+	}
+	// The file is reopened even if it could not be rotated, so that a log
+	// that cannot rotate keeps growing rather than dropping entries.
+	if openErr := l.open(); err == nil {
+		err = openErr
+	}
+	return err
+}
+
+func rotatedName(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// Close closes the log's file.
+func (l *EventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
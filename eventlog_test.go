@@ -0,0 +1,110 @@
+package circuit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readEventLog(t *testing.T, path string) []EventLogEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []EventLogEntry
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var e EventLogEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestEventLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breakers.jsonl")
+	l, err := OpenEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.OnError = func(err error) { t.Errorf("unexpected error: %v", err) }
+
+	p := NewPanel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		l.Run(ctx, p)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cb := NewThresholdBreaker(1)
+	p.Add("db", cb)
+	cb.Fail(errors.New("boom"))
+	cb.Reset()
+	time.Sleep(50 * time.Millisecond)
+
+	entries := readEventLog(t, path)
+	var events []string
+	for _, e := range entries {
+		events = append(events, e.Event)
+	}
+	if len(entries) != 3 || events[0] != "added" || events[1] != "tripped" || events[2] != "reset" {
+		t.Fatalf("expected added, tripped and reset entries, got %v", events)
+	}
+	if e := entries[1]; e.Breaker != "db" || e.Cause == nil || e.Stats == nil || e.Stats.Trips != 1 {
+		t.Fatalf("expected the trip to be logged with its cause and stats, got %+v", e)
+	}
+
+	cancel()
+	<-done
+	p.panelLock.Lock()
+	defer p.panelLock.Unlock()
+	if len(p.eventReceivers) != 0 {
+		t.Fatal("expected Run to unsubscribe when ctx is done")
+	}
+}
+
+func TestEventLogRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breakers.jsonl")
+	l, err := OpenEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.MaxSize = 100
+	l.MaxFiles = 2
+
+	for i := 0; i < 5; i++ {
+		if err := l.Write(EventLogEntry{Breaker: "db", Event: "tripped"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		if n := len(readEventLog(t, name)); n != 1 {
+			t.Fatalf("expected 1 entry in %s, got %d", name, n)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected at most 2 rotated files, got %v", err)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Write(EventLogEntry{}); err == nil {
+		t.Fatal("expected an error writing to a closed log")
+	}
+}
@@ -0,0 +1,53 @@
+//go:build go1.23
+
+package circuit
+
+import (
+	"context"
+	"iter"
+)
+
+// Events returns an iterator over the breaker's events, for consuming them
+// with a range loop rather than by managing a subscription:
+//
+//	for event := range cb.Events(ctx) {
+//		log.Printf("breaker %s", event)
+//	}
+//
+// Each iteration subscribes to the breaker, and the subscription ends when the
+// loop does or ctx is done. As with Subscribe, events may be dropped if the
+// loop falls behind.
+func (cb *Breaker) Events(ctx context.Context) iter.Seq[BreakerEvent] {
+	return func(yield func(BreakerEvent) bool) {
+		events, unsubscribe := cb.subscribe()
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-events:
+				if !yield(event) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Events returns an iterator over the panel's events. See Breaker.Events.
+func (p *Panel) Events(ctx context.Context) iter.Seq[PanelEvent] {
+	return func(yield func(PanelEvent) bool) {
+		events, unsubscribe := p.subscribe()
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-events:
+				if !yield(event) {
+					return
+				}
+			}
+		}
+	}
+}
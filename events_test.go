@@ -0,0 +1,67 @@
+//go:build go1.23
+
+package circuit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBreakerEventsIterator(t *testing.T) {
+	cb := NewBreaker()
+	go func() {
+		// Wait for the loop to subscribe.
+		for {
+			cb.eventLock.Lock()
+			n := len(cb.eventReceivers)
+			cb.eventLock.Unlock()
+			if n > 0 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		cb.Trip()
+		cb.Reset()
+	}()
+
+	var events []BreakerEvent
+	for event := range cb.Events(context.Background()) {
+		events = append(events, event)
+		if len(events) == 2 {
+			break
+		}
+	}
+	if events[0] != BreakerTripped || events[1] != BreakerReset {
+		t.Fatalf("expected a trip and a reset, got %v", events)
+	}
+
+	cb.eventLock.Lock()
+	n := len(cb.eventReceivers)
+	cb.eventLock.Unlock()
+	if n != 0 {
+		t.Fatalf("expected breaking out of the loop to unsubscribe, got %d subscriptions", n)
+	}
+}
+
+func TestPanelEventsIterator(t *testing.T) {
+	p := NewPanel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for len(p.receivers()) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		p.Add("a", NewBreaker())
+	}()
+
+	for event := range p.Events(ctx) {
+		if event.Name != "a" || event.Event != BreakerAdded {
+			t.Fatalf("expected a to be added, got %+v", event)
+		}
+		cancel()
+	}
+	if n := len(p.receivers()); n != 0 {
+		t.Fatalf("expected the loop to unsubscribe once ctx was done, got %d subscriptions", n)
+	}
+}
@@ -0,0 +1,96 @@
+package circuit
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Exec runs subprocesses under breakers, for services that shell out to flaky
+// external tools. Each binary has its own breaker in Panel, named after the
+// binary's base name, so that one failing tool does not stop the others being
+// run:
+//
+//	e := circuit.NewExec(panel, 10*time.Second)
+//	out, err := e.Output(ctx, "convert", "in.png", "out.jpg")
+//
+// Commands that exit with a non-zero status or time out are recorded as
+// failures. Unlike calls timed out by Call, timed out commands are killed.
+type Exec struct {
+	// Panel holds the breakers. Breakers missing from it are added with the
+	// panel's Defaults.
+	Panel *Panel
+
+	// Timeout, if non-zero, is how long a command may run before it is
+	// killed and fails with an error wrapping ErrBreakerTimeout.
+	Timeout time.Duration
+
+	// Configure, if non-nil, is called with each command before it is
+	// started, to set its Dir, Env, Stdin and so on.
+	Configure func(*exec.Cmd)
+}
+
+// NewExec creates an Exec whose breakers are kept in p.
+func NewExec(p *Panel, timeout time.Duration) *Exec {
+	return &Exec{Panel: p, Timeout: timeout}
+}
+
+// Breaker returns the breaker for the binary name, adding it if needed.
+func (e *Exec) Breaker(name string) *Breaker {
+	return e.Panel.getOrAdd(filepath.Base(name), nil)
+}
+
+// Run runs the command name with args and waits for it to exit, as
+// exec.Cmd.Run. If the binary's breaker is open, the command is not run and
+// ErrBreakerOpen is returned.
+func (e *Exec) Run(ctx context.Context, name string, args ...string) error {
+	return e.run(ctx, name, args, (*exec.Cmd).Run)
+}
+
+// Output is Run, but returns the command's standard output as
+// exec.Cmd.Output.
+func (e *Exec) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var out []byte
+	err := e.run(ctx, name, args, func(cmd *exec.Cmd) error {
+		var err error
+		out, err = cmd.Output()
+		return err
+	})
+	return out, err
+}
+
+// CombinedOutput is Run, but returns the command's combined standard output
+// and standard error as exec.Cmd.CombinedOutput.
+func (e *Exec) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var out []byte
+	err := e.run(ctx, name, args, func(cmd *exec.Cmd) error {
+		var err error
+		out, err = cmd.CombinedOutput()
+		return err
+	})
+	return out, err
+}
+
+// run runs the command name with args through fn under its breaker. As with
+// CallContext, commands killed because ctx was canceled are not recorded.
+func (e *Exec) run(ctx context.Context, name string, args []string, fn func(*exec.Cmd) error) error {
+	return e.Breaker(name).CallContext(ctx, func() error {
+		cmdCtx := ctx
+		if e.Timeout > 0 {
+			var cancel context.CancelFunc
+			cmdCtx, cancel = context.WithTimeout(ctx, e.Timeout)
+			defer cancel()
+		}
+		cmd := exec.CommandContext(cmdCtx, name, args...)
+		if e.Configure != nil {
+			e.Configure(cmd)
+		}
+		err := fn(cmd)
+		if err != nil && ctx.Err() == nil && cmdCtx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("circuit: %s timed out after %v: %w", name, e.Timeout, ErrBreakerTimeout)
+		}
+		return err
+	}, 0)
+}
@@ -0,0 +1,44 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExec(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	p := NewPanel()
+	p.Defaults = &Options{ShouldTrip: ConsecutiveTripFunc(2)}
+	e := NewExec(p, 50*time.Millisecond)
+	ctx := context.Background()
+
+	out, err := e.Output(ctx, "/bin/sh", "-c", "echo hello")
+	if err != nil || strings.TrimSpace(string(out)) != "hello" {
+		t.Fatalf("expected hello, got %q (%v)", out, err)
+	}
+	cb, ok := p.Get("sh")
+	if !ok || cb.Successes() != 1 {
+		t.Fatal("expected a breaker named after the binary to record the success")
+	}
+
+	var exitErr *exec.ExitError
+	if err := e.Run(ctx, "sh", "-c", "exit 3"); !errors.As(err, &exitErr) {
+		t.Fatalf("expected an exit error, got %v", err)
+	}
+	if err := e.Run(ctx, "sh", "-c", "sleep 5"); !errors.Is(err, ErrBreakerTimeout) {
+		t.Fatalf("expected the command to time out, got %v", err)
+	}
+	if !cb.Tripped() {
+		t.Fatal("expected a non-zero exit and a timeout to trip the breaker")
+	}
+	if _, err := e.CombinedOutput(ctx, "sh", "-c", "echo hello"); err != ErrBreakerOpen {
+		t.Fatalf("expected ErrBreakerOpen, got %v", err)
+	}
+}
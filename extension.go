@@ -0,0 +1,130 @@
+package circuit
+
+import "math"
+
+// BucketExtension keeps a value of its own in each bucket of a breaker's
+// window, such as the bytes transferred or the retries made by the calls in
+// the bucket. Values are recorded with Breaker.RecordExtra, aggregated over
+// the window by Breaker.Extra and reported in Stats.Extra, so a TripFunc can
+// trip on domain-specific signals:
+//
+//	retries := circuit.SumExtension("retries")
+//	cb := circuit.NewBreakerWithOptions(&circuit.Options{
+//		BucketExtensions: []circuit.BucketExtension{retries},
+//		ShouldTrip: func(cb *circuit.Breaker) bool {
+//			return cb.Extra("retries") > 100
+//		},
+//	})
+//	cb.RecordExtra("retries", 3)
+//
+// A bucket's value starts at 0 and is cleared with the bucket's counts.
+type BucketExtension interface {
+	// Key is the name the extension's values are recorded under.
+	Key() string
+
+	// Record returns the value of a bucket holding current once value is
+	// recorded in it.
+	Record(current, value float64) float64
+
+	// Aggregate returns the value over the window given the values of its
+	// buckets.
+	Aggregate(values []float64) float64
+}
+
+type sumExtension string
+
+// SumExtension returns a BucketExtension that sums the values recorded under
+// key.
+func SumExtension(key string) BucketExtension {
+	return sumExtension(key)
+}
+
+func (e sumExtension) Key() string                           { return string(e) }
+func (e sumExtension) Record(current, value float64) float64 { return current + value }
+
+func (e sumExtension) Aggregate(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
+
+type maxExtension string
+
+// MaxExtension returns a BucketExtension that keeps the largest value
+// recorded under key.
+func MaxExtension(key string) BucketExtension {
+	return maxExtension(key)
+}
+
+func (e maxExtension) Key() string                           { return string(e) }
+func (e maxExtension) Record(current, value float64) float64 { return math.Max(current, value) }
+
+func (e maxExtension) Aggregate(values []float64) float64 {
+	var max float64
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	return max
+}
+
+// RecordExtra records value under key in the current bucket of the breaker's
+// window. It reports false, recording nothing, if the breaker has no
+// BucketExtension for key.
+func (cb *Breaker) RecordExtra(key string, value float64) bool {
+	return cb.counts.RecordExtra(key, value)
+}
+
+// Extra returns the value recorded under key aggregated over the breaker's
+// window, or 0 if the breaker has no BucketExtension for key.
+func (cb *Breaker) Extra(key string) float64 {
+	v, _ := cb.counts.Extra(key)
+	return v
+}
+
+// extras returns the aggregated values of each of the breaker's extensions,
+// or nil if it has none.
+func (cb *Breaker) extras() map[string]float64 {
+	if len(cb.counts.extensions) == 0 {
+		return nil
+	}
+	extras := make(map[string]float64, len(cb.counts.extensions))
+	for key := range cb.counts.extensions {
+		extras[key], _ = cb.counts.Extra(key)
+	}
+	return extras
+}
+
+// RecordExtra records value under key in the current bucket. It reports false
+// if the window has no extension for key.
+func (w *window) RecordExtra(key string, value float64) bool {
+	ext, ok := w.extensions[key]
+	if !ok {
+		return false
+	}
+	w.bucketLock.Lock()
+	b, rolled := w.getLatestBucket()
+	if b.extra == nil {
+		b.extra = make(map[string]float64, len(w.extensions))
+	}
+	b.extra[key] = ext.Record(b.extra[key], value)
+	w.bucketLock.Unlock()
+	w.rolledOver(rolled)
+	return true
+}
+
+// Extra returns the values recorded under key aggregated over all buckets.
+func (w *window) Extra(key string) (float64, bool) {
+	ext, ok := w.extensions[key]
+	if !ok {
+		return 0, false
+	}
+	w.bucketLock.RLock()
+	values := make([]float64, 0, w.buckets.Len())
+	w.buckets.Do(func(x interface{}) {
+		values = append(values, x.(*bucket).extra[key])
+	})
+	w.bucketLock.RUnlock()
+	return ext.Aggregate(values), true
+}
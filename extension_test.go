@@ -0,0 +1,56 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestBucketExtensions(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := NewBreakerWithOptions(&Options{
+		Clock:            c,
+		WindowTime:       10 * time.Second,
+		WindowBuckets:    10,
+		BucketExtensions: []BucketExtension{SumExtension("bytes"), MaxExtension("retries")},
+		ShouldTrip: func(cb *Breaker) bool {
+			return cb.Extra("retries") >= 5
+		},
+	})
+
+	cb.RecordExtra("bytes", 100)
+	cb.RecordExtra("retries", 2)
+	c.Add(time.Second)
+	cb.RecordExtra("bytes", 50)
+	cb.RecordExtra("retries", 1)
+	if cb.RecordExtra("unknown", 1) {
+		t.Fatal("expected a value without an extension not to be recorded")
+	}
+
+	if got := cb.Extra("bytes"); got != 150 {
+		t.Fatalf("expected 150 bytes over the window, got %v", got)
+	}
+	if got := cb.Extra("retries"); got != 2 {
+		t.Fatalf("expected at most 2 retries, got %v", got)
+	}
+	if s := cb.Stats(); s.Extra["bytes"] != 150 || s.Extra["retries"] != 2 || len(s.Extra) != 2 {
+		t.Fatalf("expected Stats to report the extensions, got %v", s.Extra)
+	}
+
+	cb.RecordExtra("retries", 5)
+	cb.Fail(nil)
+	if !cb.Tripped() {
+		t.Fatal("expected the breaker to trip on its extension")
+	}
+
+	c.Add(10 * time.Second)
+	cb.RecordExtra("bytes", 10)
+	if got := cb.Extra("bytes"); got != 10 {
+		t.Fatalf("expected values to leave the window with their buckets, got %v", got)
+	}
+	if NewBreaker().Stats().Extra != nil {
+		t.Fatal("expected no Extra without extensions")
+	}
+}
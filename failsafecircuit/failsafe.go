@@ -0,0 +1,148 @@
+// Package failsafecircuit provides a circuit breaker with the standalone API
+// of failsafe-go's circuitbreaker.CircuitBreaker, backed by a circuit.Breaker,
+// for migrating code that acquires permits and records outcomes by hand:
+//
+//	cb := failsafecircuit.New(circuit.NewRateBreaker(0.5, 20))
+//	if cb.TryAcquirePermit() {
+//		cb.RecordError(call())
+//	}
+//
+// failsafe-go itself is not imported, so the CircuitBreaker cannot be passed
+// to failsafe.Run and friends as a policy; Run covers the common case of
+// running a single function.
+package failsafecircuit
+
+import (
+	"errors"
+	"sync"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+// ErrOpen is returned by Run when the breaker is open, as by failsafe-go.
+var ErrOpen = errors.New("circuit breaker open")
+
+// errFailure is recorded for the trial calls RecordFailure records.
+var errFailure = errors.New("failsafecircuit: call failed")
+
+// CircuitBreaker has the API of failsafe-go's circuitbreaker.CircuitBreaker.
+type CircuitBreaker struct {
+	breaker *circuit.Breaker
+
+	// probes are the tokens of the trial calls permitted by
+	// TryAcquirePermit whose outcome has not been recorded yet, oldest
+	// first. As in failsafe-go, permits are not tied to the outcomes
+	// recorded, so each outcome recorded while trial calls are in flight is
+	// taken to be that of the oldest.
+	lock   sync.Mutex
+	probes []circuit.ProbeToken
+}
+
+// New returns a CircuitBreaker backed by cb.
+func New(cb *circuit.Breaker) *CircuitBreaker {
+	return &CircuitBreaker{breaker: cb}
+}
+
+// Breaker returns the circuit.Breaker backing the CircuitBreaker.
+func (c *CircuitBreaker) Breaker() *circuit.Breaker {
+	return c.breaker
+}
+
+// TryAcquirePermit reports whether a call may be made. If it returns true,
+// the outcome of the call must be recorded. Trial calls of a half-open
+// breaker count against its MaxProbes until their outcome is recorded.
+func (c *CircuitBreaker) TryAcquirePermit() bool {
+	token, err := c.breaker.Acquire()
+	if err != nil {
+		return false
+	}
+	if token.Probe() {
+		c.lock.Lock()
+		c.probes = append(c.probes, token)
+		c.lock.Unlock()
+	}
+	return true
+}
+
+// RecordSuccess records a successful call.
+func (c *CircuitBreaker) RecordSuccess() {
+	if token, ok := c.probe(); ok {
+		token.Done(nil)
+		return
+	}
+	c.breaker.Success()
+}
+
+// RecordFailure records a failed call.
+func (c *CircuitBreaker) RecordFailure() {
+	if token, ok := c.probe(); ok {
+		token.Done(errFailure)
+		return
+	}
+	c.breaker.Fail(nil)
+}
+
+// RecordError records a failed call if err is non-nil, and a successful call
+// otherwise.
+func (c *CircuitBreaker) RecordError(err error) {
+	if err == nil {
+		c.RecordSuccess()
+		return
+	}
+	if token, ok := c.probe(); ok {
+		token.Done(err)
+		return
+	}
+	c.breaker.Fail(err)
+}
+
+// probe takes the token of the oldest trial call in flight, if there is one.
+func (c *CircuitBreaker) probe() (circuit.ProbeToken, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.probes) == 0 {
+		return circuit.ProbeToken{}, false
+	}
+	token := c.probes[0]
+	c.probes = c.probes[1:]
+	return token, true
+}
+
+// Open opens the breaker.
+func (c *CircuitBreaker) Open() {
+	c.breaker.Trip()
+}
+
+// Close closes the breaker.
+func (c *CircuitBreaker) Close() {
+	c.breaker.Reset()
+}
+
+// IsOpen reports whether the breaker is open. A breaker marked unavailable
+// is open.
+func (c *CircuitBreaker) IsOpen() bool {
+	s := c.breaker.State()
+	return s == circuit.StateOpen || s == circuit.StateUnavailable
+}
+
+// IsHalfOpen reports whether the breaker is half-open.
+func (c *CircuitBreaker) IsHalfOpen() bool {
+	return c.breaker.State() == circuit.StateHalfOpen
+}
+
+// IsClosed reports whether the breaker is closed.
+func (c *CircuitBreaker) IsClosed() bool {
+	return c.breaker.State() == circuit.StateClosed
+}
+
+// Run runs fn if the breaker allows it and records its outcome, as
+// failsafe.Run does with a circuit breaker policy. It returns ErrOpen without
+// calling fn when the breaker is open.
+func (c *CircuitBreaker) Run(fn func() error) error {
+	if !c.TryAcquirePermit() {
+		return ErrOpen
+	}
+	err := fn()
+	c.RecordError(err)
+	return err
+}
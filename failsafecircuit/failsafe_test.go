@@ -0,0 +1,67 @@
+package failsafecircuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	circuit "github.com/cockroachdb/circuitbreaker"
+	"github.com/facebookgo/clock"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	cb := New(circuit.NewConsecutiveBreaker(2))
+	if !cb.IsClosed() || !cb.TryAcquirePermit() {
+		t.Fatal("expected a closed breaker to grant permits")
+	}
+	cb.RecordSuccess()
+	cb.RecordFailure()
+	cb.RecordError(errors.New("failed"))
+	if !cb.IsOpen() || cb.TryAcquirePermit() {
+		t.Fatal("expected two consecutive failures to open the breaker")
+	}
+	if err := cb.Run(func() error { return nil }); err != ErrOpen {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+
+	cb.Close()
+	errFailed := errors.New("failed")
+	if err := cb.Run(func() error { return errFailed }); err != errFailed {
+		t.Fatalf("expected the call's error, got %v", err)
+	}
+	if f := cb.Breaker().Failures(); f != 1 {
+		t.Fatalf("expected Run to record the failure, got %d failures", f)
+	}
+
+	cb.Open()
+	if cb.IsClosed() {
+		t.Fatal("expected Open to open the breaker")
+	}
+}
+
+func TestCircuitBreakerProbes(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	breaker := circuit.NewBreakerWithOptions(&circuit.Options{
+		Clock:   c,
+		BackOff: &backoff.ConstantBackOff{Interval: time.Second},
+	})
+	cb := New(breaker)
+	breaker.Trip()
+	c.Add(5 * time.Second)
+
+	if !cb.TryAcquirePermit() {
+		t.Fatal("expected a permit for a trial call")
+	}
+	if cb.TryAcquirePermit() {
+		t.Fatal("expected no second permit while the trial call is in flight")
+	}
+	if _, ok := breaker.TryProbe(); ok {
+		t.Fatal("expected the permit to hold the breaker's trial call slot")
+	}
+	cb.RecordSuccess()
+	if !cb.IsClosed() {
+		t.Fatal("expected the successful trial call to close the breaker")
+	}
+}
@@ -0,0 +1,79 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInjectedFault is the failure recorded for calls failed by a FaultInjector.
+var ErrInjectedFault = errors.New("injected fault")
+
+// Faults describes the faults a FaultInjector injects into calls.
+type Faults struct {
+	// FailureRate is the probability, from 0 to 1, that a call fails with
+	// ErrInjectedFault without the wrapped function being run.
+	FailureRate float64 `json:"failure_rate"`
+
+	// Latency is added before the wrapped function is run, with probability
+	// LatencyRate. The added latency counts towards the call's timeout.
+	Latency     time.Duration `json:"latency"`
+	LatencyRate float64       `json:"latency_rate"`
+
+	// Open rejects every call as if the breaker were open, without changing
+	// the breaker's state.
+	Open bool `json:"open"`
+}
+
+// FaultInjector injects faults into the calls made through a Breaker, so that
+// how callers handle failures, slowness and open breakers can be tested
+// without touching the real dependency. The faults may be changed at any time,
+// for example through the AdminHandler. A FaultInjector with no faults set
+// has no effect.
+type FaultInjector struct {
+	faults Faults
+	lock   sync.RWMutex
+}
+
+// NewFaultInjector creates a FaultInjector with no faults set.
+func NewFaultInjector() *FaultInjector {
+	return &FaultInjector{}
+}
+
+// Faults returns the faults currently injected.
+func (f *FaultInjector) Faults() Faults {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.faults
+}
+
+// Set replaces the faults injected. Set(Faults{}) stops injecting faults.
+func (f *FaultInjector) Set(faults Faults) {
+	f.lock.Lock()
+	f.faults = faults
+	f.lock.Unlock()
+}
+
+// open reports whether calls should be rejected.
+func (f *FaultInjector) open() bool {
+	return f.Faults().Open
+}
+
+// wrap returns circuit with the configured latency and failures injected.
+func (f *FaultInjector) wrap(ctx context.Context, cb *Breaker, circuit func() error) func() error {
+	faults := f.Faults()
+	return func() error {
+		if faults.Latency > 0 && cb.rng.Float64() < faults.LatencyRate {
+			select {
+			case <-cb.Clock.After(faults.Latency):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if cb.rng.Float64() < faults.FailureRate {
+			return ErrInjectedFault
+		}
+		return circuit()
+	}
+}
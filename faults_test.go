@@ -0,0 +1,47 @@
+package circuit
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFaultInjector(t *testing.T) {
+	f := NewFaultInjector()
+	cb := NewBreakerWithOptions(&Options{FaultInjector: f})
+	var called int64
+	circuit := func() error {
+		atomic.AddInt64(&called, 1)
+		return nil
+	}
+
+	if err := cb.Call(circuit, 0); err != nil || atomic.LoadInt64(&called) != 1 {
+		t.Fatalf("expected call to run without faults, got %v", err)
+	}
+
+	f.Set(Faults{FailureRate: 1})
+	if err := cb.Call(circuit, 0); err != ErrInjectedFault || atomic.LoadInt64(&called) != 1 {
+		t.Fatalf("expected injected fault without running the call, got %v", err)
+	}
+	if failures := cb.Failures(); failures != 1 {
+		t.Fatalf("expected injected fault to be recorded, got %d failures", failures)
+	}
+
+	f.Set(Faults{Latency: 50 * time.Millisecond, LatencyRate: 1})
+	if err := cb.Call(circuit, 5*time.Millisecond); err != ErrBreakerTimeout {
+		t.Fatalf("expected injected latency to time out the call, got %v", err)
+	}
+
+	f.Set(Faults{Open: true})
+	if err := cb.Call(circuit, 0); err != ErrBreakerOpen {
+		t.Fatalf("expected forced open breaker to reject calls, got %v", err)
+	}
+	if cb.Tripped() {
+		t.Fatal("expected forcing the breaker open not to trip it")
+	}
+
+	f.Set(Faults{})
+	if err := cb.Call(circuit, 0); err != nil {
+		t.Fatalf("expected call to succeed once faults are cleared, got %v", err)
+	}
+}
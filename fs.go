@@ -0,0 +1,199 @@
+package circuit
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// FS is an fs.FS whose operations are protected by a breaker, so that a hung
+// NFS mount or a slow network volume is detected and short-circuited rather
+// than tying up every goroutine that touches it:
+//
+//	cb := circuit.NewBreakerWithOptions(&circuit.Options{
+//		ShouldTrip: circuit.LatencyTripFunc(0.9, 500*time.Millisecond, 20),
+//	})
+//	fsys := circuit.NewFS(os.DirFS("/mnt/shared"), cb, time.Second)
+//
+// Operations on fsys and on the files it opens fail with ErrBreakerOpen while
+// the breaker is open, and with ErrBreakerTimeout when they take longer than
+// the timeout. Errors that show the file system is responding, such as
+// fs.ErrNotExist, fs.ErrPermission and io.EOF, are returned but recorded as
+// successes.
+//
+// An operation that times out is abandoned rather than interrupted, and its
+// goroutine lives on until the file system responds.
+type FS struct {
+	fsys    fs.FS
+	cb      *Breaker
+	timeout time.Duration
+}
+
+var (
+	_ fs.FS         = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+)
+
+// NewFS returns fsys protected by cb. Operations taking longer than timeout
+// fail; 0 means no timeout.
+func NewFS(fsys fs.FS, cb *Breaker, timeout time.Duration) *FS {
+	return &FS{fsys: fsys, cb: cb, timeout: timeout}
+}
+
+// Breaker returns the breaker protecting the file system.
+func (f *FS) Breaker() *Breaker {
+	return f.cb
+}
+
+// Open implements fs.FS. Reads from, and other operations on, the file
+// returned are protected by the breaker too.
+func (f *FS) Open(name string) (fs.File, error) {
+	var file fs.File
+	err := f.do(func() error {
+		var err error
+		file, err = f.fsys.Open(name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	bf := &breakerFile{file: file, fs: f}
+	if _, ok := file.(fs.ReadDirFile); ok {
+		return &breakerDirFile{bf}, nil
+	}
+	return bf, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	var data []byte
+	err := f.do(func() error {
+		var err error
+		data, err = fs.ReadFile(f.fsys, name)
+		return err
+	})
+	if err == ErrBreakerTimeout {
+		return nil, err
+	}
+	return data, err
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	err := f.do(func() error {
+		var err error
+		entries, err = fs.ReadDir(f.fsys, name)
+		return err
+	})
+	if err == ErrBreakerTimeout {
+		return nil, err
+	}
+	return entries, err
+}
+
+// Stat implements fs.StatFS.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	var info fs.FileInfo
+	err := f.do(func() error {
+		var err error
+		info, err = fs.Stat(f.fsys, name)
+		return err
+	})
+	if err == ErrBreakerTimeout {
+		return nil, err
+	}
+	return info, err
+}
+
+// do runs op through the breaker. If do returns ErrBreakerTimeout, op has
+// been abandoned and may still be running, so the results it sets must not be
+// read.
+func (f *FS) do(op func() error) error {
+	var benign error
+	err := f.cb.Call(func() error {
+		err := op()
+		if isBenignFSError(err) {
+			benign = err
+			return nil
+		}
+		return err
+	}, f.timeout)
+	if err == nil {
+		return benign
+	}
+	return err
+}
+
+// isBenignFSError reports whether err is an error a healthy file system
+// returns, which should not count against the breaker.
+func isBenignFSError(err error) bool {
+	return err == io.EOF ||
+		errors.Is(err, fs.ErrNotExist) ||
+		errors.Is(err, fs.ErrExist) ||
+		errors.Is(err, fs.ErrPermission) ||
+		errors.Is(err, fs.ErrInvalid)
+}
+
+// breakerFile is a file opened by an FS.
+type breakerFile struct {
+	file fs.File
+	fs   *FS
+}
+
+func (f *breakerFile) Stat() (fs.FileInfo, error) {
+	var info fs.FileInfo
+	err := f.fs.do(func() error {
+		var err error
+		info, err = f.file.Stat()
+		return err
+	})
+	if err == ErrBreakerTimeout {
+		return nil, err
+	}
+	return info, err
+}
+
+func (f *breakerFile) Read(p []byte) (int, error) {
+	// An abandoned read must not write to p after Read returns.
+	buf := p
+	if f.fs.timeout > 0 {
+		buf = make([]byte, len(p))
+	}
+	var n int
+	err := f.fs.do(func() error {
+		var err error
+		n, err = f.file.Read(buf)
+		return err
+	})
+	if err == ErrBreakerTimeout {
+		return 0, err
+	}
+	copy(p, buf[:n])
+	return n, err
+}
+
+func (f *breakerFile) Close() error {
+	return f.fs.do(f.file.Close)
+}
+
+// breakerDirFile is a directory opened by an FS.
+type breakerDirFile struct {
+	*breakerFile
+}
+
+func (f *breakerDirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	err := f.fs.do(func() error {
+		var err error
+		entries, err = f.file.(fs.ReadDirFile).ReadDir(n)
+		return err
+	})
+	if err == ErrBreakerTimeout {
+		return nil, err
+	}
+	return entries, err
+}
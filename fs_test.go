@@ -0,0 +1,80 @@
+package circuit
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// hangFS is a file system whose files named "hung" cannot be opened until
+// release is closed.
+type hangFS struct {
+	fstest.MapFS
+	release chan struct{}
+}
+
+func (h hangFS) Open(name string) (fs.File, error) {
+	if name == "hung" {
+		<-h.release
+	}
+	return h.MapFS.Open(name)
+}
+
+func TestFS(t *testing.T) {
+	hung := hangFS{
+		MapFS: fstest.MapFS{
+			"a.txt":     {Data: []byte("hello")},
+			"dir/b.txt": {Data: []byte("world")},
+			"hung":      {Data: []byte("slow")},
+		},
+		release: make(chan struct{}),
+	}
+	defer close(hung.release)
+
+	cb := NewBreakerWithOptions(&Options{ShouldTrip: LatencyTripFunc(0.9, 5*time.Millisecond, 1)})
+	fsys := NewFS(hung, cb, 20*time.Millisecond)
+
+	if data, err := fsys.ReadFile("a.txt"); err != nil || string(data) != "hello" {
+		t.Fatalf("expected hello, got %q (%v)", data, err)
+	}
+	f, err := fsys.Open("dir/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := io.ReadAll(f); err != nil || string(data) != "world" {
+		t.Fatalf("expected world, got %q (%v)", data, err)
+	}
+	f.Close()
+	if entries, err := fsys.ReadDir("dir"); err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %v (%v)", entries, err)
+	}
+	if _, err := fsys.Stat("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist, got %v", err)
+	}
+	if cb.Failures() != 0 {
+		t.Fatalf("expected no failures from a healthy file system, got %d", cb.Failures())
+	}
+
+	if _, err := fsys.Open("hung"); err != ErrBreakerTimeout {
+		t.Fatalf("expected a hung open to time out, got %v", err)
+	}
+	if !cb.Tripped() {
+		t.Fatal("expected the slow file system to trip the breaker")
+	}
+	if _, err := fsys.ReadFile("a.txt"); err != ErrBreakerOpen {
+		t.Fatalf("expected ErrBreakerOpen, got %v", err)
+	}
+}
+
+func TestFSConformance(t *testing.T) {
+	fsys := NewFS(fstest.MapFS{
+		"a.txt":     {Data: []byte("hello")},
+		"dir/b.txt": {Data: []byte("world")},
+	}, NewBreaker(), time.Second)
+	if err := fstest.TestFS(fsys, "a.txt", "dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
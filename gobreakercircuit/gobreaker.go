@@ -0,0 +1,180 @@
+// Package gobreakercircuit provides a circuit breaker with the API of
+// sony/gobreaker's CircuitBreaker and TwoStepCircuitBreaker, backed by a
+// circuit.Breaker. Code written against gobreaker can be moved onto this
+// package's breakers, and so into a circuit.Panel, by changing its imports
+// and constructors, and then migrated to the circuit API call by call:
+//
+//	cb := gobreakercircuit.New("db", circuit.NewConsecutiveBreaker(5))
+//	rows, err := cb.Execute(func() (interface{}, error) {
+//		return db.Query(q)
+//	})
+//
+// gobreaker itself is not imported: the types it defines, such as State and
+// Counts, are mirrored here.
+package gobreakercircuit
+
+import (
+	"errors"
+	"sync/atomic"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+var (
+	// ErrOpenState is returned when the breaker is open, as by gobreaker.
+	ErrOpenState = errors.New("circuit breaker is open")
+
+	// ErrTooManyRequests is returned by gobreaker when too many calls are
+	// made while half-open. A circuit.Breaker lets Options.MaxProbes trial
+	// calls through at once and rejects the others with ErrOpenState, so it
+	// is never returned; it is defined for code that checks for it.
+	ErrTooManyRequests = errors.New("too many requests")
+
+	// errUnsuccessful is recorded for calls that IsSuccessful, or the
+	// outcome passed to a TwoStepCircuitBreaker's done, says failed.
+	errUnsuccessful = errors.New("gobreakercircuit: call unsuccessful")
+)
+
+// State mirrors gobreaker's State.
+type State int
+
+// The states of a breaker, with gobreaker's values.
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	}
+	return "unknown state"
+}
+
+// Counts mirrors gobreaker's Counts. Requests, TotalSuccesses and
+// TotalFailures count the calls in the breaker's window.
+type Counts struct {
+	Requests             uint32
+	TotalSuccesses       uint32
+	TotalFailures        uint32
+	ConsecutiveSuccesses uint32
+	ConsecutiveFailures  uint32
+}
+
+// CircuitBreaker has the API of gobreaker's CircuitBreaker.
+type CircuitBreaker struct {
+	name    string
+	breaker *circuit.Breaker
+
+	// IsSuccessful, as in gobreaker's Settings, reports whether an error
+	// returned by a call counts as a success. If it is nil, only calls
+	// returning a nil error succeed.
+	IsSuccessful func(err error) bool
+
+	consecSuccesses uint32
+}
+
+// New returns a CircuitBreaker named name backed by cb.
+func New(name string, cb *circuit.Breaker) *CircuitBreaker {
+	return &CircuitBreaker{name: name, breaker: cb}
+}
+
+// Breaker returns the circuit.Breaker backing the CircuitBreaker.
+func (c *CircuitBreaker) Breaker() *circuit.Breaker {
+	return c.breaker
+}
+
+// Name returns the name of the CircuitBreaker.
+func (c *CircuitBreaker) Name() string {
+	return c.name
+}
+
+// State returns the state of the CircuitBreaker.
+func (c *CircuitBreaker) State() State {
+	switch c.breaker.State() {
+	case circuit.StateOpen, circuit.StateUnavailable:
+		return StateOpen
+	case circuit.StateHalfOpen:
+		return StateHalfOpen
+	}
+	return StateClosed
+}
+
+// Counts returns the counts of the calls in the breaker's window.
+func (c *CircuitBreaker) Counts() Counts {
+	failures := uint32(c.breaker.Failures())
+	successes := uint32(c.breaker.Successes())
+	return Counts{
+		Requests:             failures + successes,
+		TotalSuccesses:       successes,
+		TotalFailures:        failures,
+		ConsecutiveSuccesses: atomic.LoadUint32(&c.consecSuccesses),
+		ConsecutiveFailures:  uint32(c.breaker.ConsecFailures()),
+	}
+}
+
+// Execute runs req if the breaker allows it and records its outcome. It
+// returns ErrOpenState without calling req when the breaker is open. As with
+// gobreaker, a panic in req is recorded as a failure and then re-raised.
+func (c *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
+	done, err := c.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			done(false)
+			panic(e)
+		}
+	}()
+
+	result, err := req()
+	done(c.isSuccessful(err))
+	return result, err
+}
+
+func (c *CircuitBreaker) isSuccessful(err error) bool {
+	if c.IsSuccessful != nil {
+		return c.IsSuccessful(err)
+	}
+	return err == nil
+}
+
+func (c *CircuitBreaker) allow() (func(success bool), error) {
+	token, err := c.breaker.Acquire()
+	if err != nil {
+		return nil, ErrOpenState
+	}
+	return func(success bool) {
+		if success {
+			atomic.AddUint32(&c.consecSuccesses, 1)
+			token.Done(nil)
+		} else {
+			atomic.StoreUint32(&c.consecSuccesses, 0)
+			token.Done(errUnsuccessful)
+		}
+	}, nil
+}
+
+// TwoStepCircuitBreaker has the API of gobreaker's TwoStepCircuitBreaker.
+type TwoStepCircuitBreaker struct {
+	*CircuitBreaker
+}
+
+// NewTwoStep returns a TwoStepCircuitBreaker named name backed by cb.
+func NewTwoStep(name string, cb *circuit.Breaker) *TwoStepCircuitBreaker {
+	return &TwoStepCircuitBreaker{New(name, cb)}
+}
+
+// Allow returns ErrOpenState if the breaker is open. Otherwise it returns a
+// function that must be called with the outcome of the call.
+func (c *TwoStepCircuitBreaker) Allow() (done func(success bool), err error) {
+	return c.allow()
+}
@@ -0,0 +1,73 @@
+package gobreakercircuit
+
+import (
+	"errors"
+	"testing"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+func TestExecute(t *testing.T) {
+	cb := New("db", circuit.NewConsecutiveBreaker(2))
+	if cb.Name() != "db" || cb.State() != StateClosed {
+		t.Fatalf("expected closed breaker named db, got %q %v", cb.Name(), cb.State())
+	}
+
+	result, err := cb.Execute(func() (interface{}, error) { return 42, nil })
+	if result != 42 || err != nil {
+		t.Fatalf("expected the call's result, got %v, %v", result, err)
+	}
+
+	errFailed := errors.New("failed")
+	for i := 0; i < 2; i++ {
+		if _, err := cb.Execute(func() (interface{}, error) { return nil, errFailed }); err != errFailed {
+			t.Fatalf("expected the call's error, got %v", err)
+		}
+	}
+	if want := (Counts{Requests: 3, TotalSuccesses: 1, TotalFailures: 2, ConsecutiveFailures: 2}); cb.Counts() != want {
+		t.Fatalf("expected counts %+v, got %+v", want, cb.Counts())
+	}
+	if cb.State() != StateOpen {
+		t.Fatalf("expected breaker to be open, got %v", cb.State())
+	}
+
+	called := false
+	_, err = cb.Execute(func() (interface{}, error) { called = true; return nil, nil })
+	if err != ErrOpenState || called {
+		t.Fatalf("expected ErrOpenState without calling, got %v", err)
+	}
+}
+
+func TestExecutePanic(t *testing.T) {
+	cb := New("db", circuit.NewThresholdBreaker(1))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected the panic to be re-raised")
+			}
+		}()
+		cb.Execute(func() (interface{}, error) { panic("boom") })
+	}()
+	if !cb.Breaker().Tripped() {
+		t.Fatal("expected the panic to be recorded as a failure")
+	}
+}
+
+func TestTwoStep(t *testing.T) {
+	cb := NewTwoStep("db", circuit.NewThresholdBreaker(1))
+
+	done, err := cb.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done(true)
+	if c := cb.Counts(); c.ConsecutiveSuccesses != 1 {
+		t.Fatalf("expected one consecutive success, got %+v", c)
+	}
+
+	done, _ = cb.Allow()
+	done(false)
+	if _, err := cb.Allow(); err != ErrOpenState {
+		t.Fatalf("expected ErrOpenState, got %v", err)
+	}
+}
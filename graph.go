@@ -0,0 +1,117 @@
+package circuit
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Graph is the dependency graph of the breakers in a Panel with their live
+// states, as returned by Panel.Graph.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a breaker in a Graph.
+type GraphNode struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// GraphEdge records that the breaker From depends on the breaker To.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DependsOn declares that the breaker named name protects something that
+// depends on what the breaker named dependency protects, such as an API whose
+// handlers query a database. Dependencies are only used to draw the panel's
+// Graph, where a failure cascading up the dependency tree can be followed.
+// They can be declared before the breakers are added.
+func (p *Panel) DependsOn(name, dependency string) {
+	p.panelLock.Lock()
+	defer p.panelLock.Unlock()
+	if p.dependencies == nil {
+		p.dependencies = make(map[string][]string)
+	}
+	for _, d := range p.dependencies[name] {
+		if d == dependency {
+			return
+		}
+	}
+	p.dependencies[name] = append(p.dependencies[name], dependency)
+}
+
+// Dependencies returns the names of the breakers the breaker named name was
+// declared to depend on, sorted.
+func (p *Panel) Dependencies(name string) []string {
+	p.panelLock.RLock()
+	deps := append([]string(nil), p.dependencies[name]...)
+	p.panelLock.RUnlock()
+	sort.Strings(deps)
+	return deps
+}
+
+// Graph returns the panel's breakers, sorted by name, and the dependencies
+// declared between them. Dependencies on breakers not in the panel are left
+// out.
+func (p *Panel) Graph() Graph {
+	var g Graph
+	p.Range(func(name string, cb *Breaker) bool {
+		g.Nodes = append(g.Nodes, GraphNode{Name: name, State: cb.State().String()})
+		return true
+	})
+
+	p.panelLock.RLock()
+	for from, deps := range p.dependencies {
+		if _, ok := p.Circuits[from]; !ok {
+			continue
+		}
+		for _, to := range deps {
+			if _, ok := p.Circuits[to]; ok {
+				g.Edges = append(g.Edges, GraphEdge{From: from, To: to})
+			}
+		}
+	}
+	p.panelLock.RUnlock()
+
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	return g
+}
+
+// dotColors are the colors of the breakers in each state in DOT output.
+var dotColors = map[string]string{
+	StateClosed.String():      "green",
+	StateHalfOpen.String():    "orange",
+	StateOpen.String():        "red",
+	StateUnavailable.String(): "gray",
+}
+
+// WriteDOT writes g in the DOT language of Graphviz, with each breaker
+// colored by its state.
+func (g Graph) WriteDOT(w io.Writer) error {
+	if _, err := io.WriteString(w, "digraph circuit {\n"); err != nil {
+		return err
+	}
+	for _, n := range g.Nodes {
+		if _, err := fmt.Fprintf(w, "\t%s [label=%s color=%s];\n",
+			strconv.Quote(n.Name), strconv.Quote(n.Name+"\n"+n.State), dotColors[n.State]); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		if _, err := fmt.Fprintf(w, "\t%s -> %s;\n", strconv.Quote(e.From), strconv.Quote(e.To)); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}\n")
+	return err
+}
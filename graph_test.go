@@ -0,0 +1,49 @@
+package circuit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPanelGraph(t *testing.T) {
+	p := NewPanel()
+	p.DependsOn("api", "db")
+	p.DependsOn("api", "cache")
+	p.DependsOn("api", "db")
+	p.DependsOn("cache", "db")
+	p.DependsOn("api", "missing")
+	p.Add("api", NewBreaker())
+	p.Add("cache", NewBreaker())
+	db := NewBreaker()
+	p.Add("db", db)
+	db.Break()
+
+	if deps := p.Dependencies("api"); !reflect.DeepEqual(deps, []string{"cache", "db", "missing"}) {
+		t.Fatalf("expected api to depend on cache, db and missing, got %v", deps)
+	}
+
+	want := Graph{
+		Nodes: []GraphNode{{"api", "closed"}, {"cache", "closed"}, {"db", "open"}},
+		Edges: []GraphEdge{{"api", "cache"}, {"api", "db"}, {"cache", "db"}},
+	}
+	g := p.Graph()
+	if !reflect.DeepEqual(g, want) {
+		t.Fatalf("expected graph %+v, got %+v", want, g)
+	}
+
+	var b strings.Builder
+	if err := g.WriteDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	dot := b.String()
+	for _, s := range []string{
+		"digraph circuit {",
+		`"db" [label="db\nopen" color=red];`,
+		`"api" -> "cache";`,
+	} {
+		if !strings.Contains(dot, s) {
+			t.Errorf("expected DOT output to contain %s, got:\n%s", s, dot)
+		}
+	}
+}
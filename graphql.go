@@ -0,0 +1,183 @@
+package circuit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+)
+
+// GraphQLError is an entry of the errors array of a GraphQL response.
+type GraphQLError struct {
+	Message string `json:"message"`
+
+	// Path is the path of the response field that failed, if the error is a
+	// per-field failure, such as ["user", "posts", 2, "title"].
+	Path []interface{} `json:"path,omitempty"`
+
+	// Extensions holds additional information on the error. Many servers
+	// give an error code under "code", such as "INTERNAL_SERVER_ERROR".
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Code returns the error code under "code" in the error's extensions, or ""
+// if there is none.
+func (e GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// GraphQLPolicy decides whether a GraphQL response with errors counts as a
+// failure of the backend. errs is the errors array of the response, and
+// partial reports whether the response holds data nonetheless, as when only
+// some fields failed.
+type GraphQLPolicy func(errs []GraphQLError, partial bool) bool
+
+// GraphQLFailOnAnyError is a GraphQLPolicy counting every response with errors
+// as a failure, including partial ones.
+func GraphQLFailOnAnyError(errs []GraphQLError, partial bool) bool {
+	return true
+}
+
+// GraphQLFailOnTotalError is a GraphQLPolicy counting responses with errors as
+// failures only when they hold no data, so that failures of a few fields do
+// not trip the breaker.
+func GraphQLFailOnTotalError(errs []GraphQLError, partial bool) bool {
+	return !partial
+}
+
+// GraphQLFailOnCodes returns a GraphQLPolicy counting responses as failures
+// when one of their errors has one of codes, such as "INTERNAL_SERVER_ERROR",
+// so that errors caused by the request, such as validation errors, are not
+// held against the backend.
+func GraphQLFailOnCodes(codes ...string) GraphQLPolicy {
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return func(errs []GraphQLError, partial bool) bool {
+		for _, e := range errs {
+			if set[e.Code()] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// GraphQLResponseError is the failure recorded for a GraphQL response that
+// its GraphQLTransport's Policy counts as failed.
+type GraphQLResponseError struct {
+	Errors  []GraphQLError
+	Partial bool
+}
+
+func (e *GraphQLResponseError) Error() string {
+	kind := "errors"
+	if e.Partial {
+		kind = "partial errors"
+	}
+	if len(e.Errors) == 0 {
+		return "graphql response has " + kind
+	}
+	return fmt.Sprintf("graphql response has %s: %s", kind, e.Errors[0].Message)
+}
+
+// GraphQLTransport is an http.RoundTripper that protects requests to a
+// GraphQL server with a circuit breaker. GraphQL servers often report
+// failures with a 200 response and an errors array in the body, which a
+// Transport would record as successes; GraphQLTransport reads the body and
+// records the response as a failure when its Policy says so. The response is
+// given to the caller either way, with its body intact, so any GraphQL client
+// library built on http.Client can be used:
+//
+//	client := &http.Client{Transport: circuit.NewGraphQLTransport(cb, circuit.GraphQLFailOnTotalError, nil)}
+type GraphQLTransport struct {
+	// Transport makes the requests. http.DefaultTransport is used if nil.
+	Transport http.RoundTripper
+
+	// Breaker protects the requests.
+	Breaker *Breaker
+
+	// Policy decides which responses with errors are failures.
+	// GraphQLFailOnAnyError is used if it is nil.
+	Policy GraphQLPolicy
+}
+
+// NewGraphQLTransport provides a circuit breaker wrapper around an
+// http.RoundTripper making GraphQL requests. Passing in a nil rt will wrap
+// http.DefaultTransport.
+func NewGraphQLTransport(breaker *Breaker, policy GraphQLPolicy, rt http.RoundTripper) *GraphQLTransport {
+	return &GraphQLTransport{Transport: rt, Breaker: breaker, Policy: policy}
+}
+
+// graphQLResponse is the part of a GraphQL response the transport inspects.
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []GraphQLError  `json:"errors"`
+}
+
+// RoundTrip implements http.RoundTripper. If the breaker is open, the request
+// is not sent and RoundTrip returns ErrBreakerOpen, and requests the breaker
+// rejects for other reasons are not sent either. Responses counted as
+// failures are returned without an error.
+func (t *GraphQLTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	var sent int32
+	err := t.Breaker.CallContext(req.Context(), func() error {
+		atomic.StoreInt32(&sent, 1)
+		aresp, err := t.transport().RoundTrip(req)
+		if err != nil {
+			return err
+		}
+		t.Breaker.noteUpstreamTrip(aresp)
+
+		body, err := ioutil.ReadAll(aresp.Body)
+		aresp.Body.Close()
+		if err != nil {
+			return err
+		}
+		aresp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		resp = aresp
+		return t.classify(body)
+	}, 0)
+
+	if atomic.LoadInt32(&sent) == 0 && req.Body != nil {
+		// A RoundTripper must always close the request body.
+		req.Body.Close()
+	}
+	var gerr *GraphQLResponseError
+	if errors.As(err, &gerr) && resp != nil {
+		return resp, nil
+	}
+	return resp, err
+}
+
+// classify returns a GraphQLResponseError if body is a GraphQL response with
+// errors the policy counts as a failure. Bodies that are not GraphQL
+// responses, such as error pages, are left to the caller.
+func (t *GraphQLTransport) classify(body []byte) error {
+	var gr graphQLResponse
+	if json.Unmarshal(body, &gr) != nil || len(gr.Errors) == 0 {
+		return nil
+	}
+	partial := len(gr.Data) > 0 && string(gr.Data) != "null"
+	policy := t.Policy
+	if policy == nil {
+		policy = GraphQLFailOnAnyError
+	}
+	if !policy(gr.Errors, partial) {
+		return nil
+	}
+	return &GraphQLResponseError{Errors: gr.Errors, Partial: partial}
+}
+
+func (t *GraphQLTransport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
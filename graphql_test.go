@@ -0,0 +1,84 @@
+package circuit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func graphQLServer(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+}
+
+func TestGraphQLTransport(t *testing.T) {
+	tests := []struct {
+		body   string
+		policy GraphQLPolicy
+		fail   bool
+	}{
+		{`{"data":{"user":{"name":"a"}}}`, nil, false},
+		{`{"data":null,"errors":[{"message":"boom"}]}`, nil, true},
+		{`{"data":{"user":null},"errors":[{"message":"boom","path":["user"]}]}`, nil, true},
+		{`{"data":{"user":null},"errors":[{"message":"boom","path":["user"]}]}`, GraphQLFailOnTotalError, false},
+		{`{"errors":[{"message":"boom"}]}`, GraphQLFailOnTotalError, true},
+		{`{"errors":[{"message":"bad","extensions":{"code":"BAD_USER_INPUT"}}]}`, GraphQLFailOnCodes("INTERNAL_SERVER_ERROR"), false},
+		{`{"errors":[{"message":"boom","extensions":{"code":"INTERNAL_SERVER_ERROR"}}]}`, GraphQLFailOnCodes("INTERNAL_SERVER_ERROR"), true},
+		{`<html>not graphql</html>`, nil, false},
+	}
+
+	for i, test := range tests {
+		ts := graphQLServer(test.body)
+		cb := NewBreaker()
+		client := &http.Client{Transport: NewGraphQLTransport(cb, test.policy, nil)}
+
+		resp, err := client.Post(ts.URL, "application/json", strings.NewReader(`{"query":"{ user { name } }"}`))
+		if err != nil {
+			t.Fatalf("%d: expected the response to be returned, got %v", i, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		ts.Close()
+
+		if string(body) != test.body {
+			t.Fatalf("%d: expected the body to be intact, got %s", i, body)
+		}
+		if failed := cb.Failures() == 1; failed != test.fail {
+			t.Fatalf("%d: expected failure %v, got %d failures and %d successes", i, test.fail, cb.Failures(), cb.Successes())
+		}
+		if !test.fail && cb.Successes() != 1 {
+			t.Fatalf("%d: expected 1 success, got %d", i, cb.Successes())
+		}
+	}
+}
+
+func TestGraphQLTransportOpen(t *testing.T) {
+	cb := NewBreaker()
+	cb.Break()
+	client := &http.Client{Transport: NewGraphQLTransport(cb, nil, nil)}
+	if _, err := client.Post("http://example.invalid/", "application/json", strings.NewReader("{}")); err == nil {
+		t.Fatal("expected request to fail while the breaker is open")
+	}
+
+	cb.Reset()
+	cb.SetUnavailable("maintenance")
+	body := &closeTracker{Reader: strings.NewReader("{}")}
+	req, _ := http.NewRequest("POST", "http://example.invalid/", body)
+	if _, err := NewGraphQLTransport(cb, nil, nil).RoundTrip(req); err == nil {
+		t.Fatal("expected request to fail while the breaker is unavailable")
+	}
+	if !body.closed {
+		t.Fatal("expected the request body to be closed")
+	}
+}
+
+func TestGraphQLResponseError(t *testing.T) {
+	err := &GraphQLResponseError{Errors: []GraphQLError{{Message: "boom"}}, Partial: true}
+	if s := err.Error(); s != "graphql response has partial errors: boom" {
+		t.Fatalf("unexpected error string %q", s)
+	}
+}
@@ -0,0 +1,127 @@
+package grpccircuit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultHealthCheckInterval is the interval between health checks used when
+// HealthCheck.Interval is 0.
+const DefaultHealthCheckInterval = time.Second
+
+// HealthCheck configures WatchHealth.
+type HealthCheck struct {
+	// Conn is the connection to the backend protected by the breaker.
+	Conn grpc.ClientConnInterface
+
+	// Service is the service name sent in health check requests. The empty
+	// string asks for the health of the server as a whole.
+	Service string
+
+	// Interval is the time between health checks while the breaker is open.
+	// DefaultHealthCheckInterval is used if it is 0.
+	Interval time.Duration
+
+	// Timeout bounds each health check. Interval is used if it is 0.
+	Timeout time.Duration
+}
+
+// WatchHealth uses the backend's grpc.health.v1 service as the recovery signal
+// for cb, instead of letting trial requests through while the breaker is open.
+// Whenever cb trips, WatchHealth breaks it so that no requests are let through,
+// then polls the backend's health every interval, as measured by cb.Clock, and
+// resets the breaker once the backend reports SERVING. Breakers broken by hand
+// are left alone.
+//
+// WatchHealth blocks until ctx is done, so it is normally run in its own
+// goroutine:
+//
+//	go grpccircuit.WatchHealth(ctx, cb, grpccircuit.HealthCheck{Conn: conn})
+//
+// If ctx is done while the backend is not yet serving, cb is left broken, as
+// nothing has shown the backend to be healthy; the caller may Reset it. If the
+// backend does not implement the health service, WatchHealth returns an error
+// and leaves cb to recover on its own as usual.
+func WatchHealth(ctx context.Context, cb *circuit.Breaker, hc HealthCheck) error {
+	if hc.Interval == 0 {
+		hc.Interval = DefaultHealthCheckInterval
+	}
+	if hc.Timeout == 0 {
+		hc.Timeout = hc.Interval
+	}
+	client := healthpb.NewHealthClient(hc.Conn)
+
+	events := make(chan circuit.ListenerEvent, 10)
+	cb.AddListener(events)
+	defer cb.RemoveListener(events)
+
+	// The breaker may have tripped before we started listening.
+	tripped := cb.Tripped()
+	for {
+		if tripped {
+			if err := probeHealth(ctx, cb, client, hc); err != nil {
+				return err
+			}
+			tripped = false
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e := <-events:
+			// Events are delivered asynchronously, so ignore trips the
+			// breaker has since recovered from.
+			tripped = e.Event == circuit.BreakerTripped && cb.Tripped()
+		}
+	}
+}
+
+// probeHealth holds cb open until the backend reports SERVING.
+func probeHealth(
+	ctx context.Context, cb *circuit.Breaker, client healthpb.HealthClient, hc HealthCheck,
+) error {
+	if cb.Stats().Broken {
+		return nil
+	}
+	if _, err := checkHealth(ctx, client, hc); status.Code(err) == codes.Unimplemented {
+		return fmt.Errorf("grpccircuit: health checking unavailable: %w", err)
+	}
+	cb.Break()
+
+	ticker := cb.Clock.Ticker(hc.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if !cb.Tripped() {
+			// Reset by someone else.
+			return nil
+		}
+		if serving, _ := checkHealth(ctx, client, hc); serving {
+			cb.Reset()
+			return nil
+		}
+	}
+}
+
+// checkHealth reports whether the backend is serving.
+func checkHealth(ctx context.Context, client healthpb.HealthClient, hc HealthCheck) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, hc.Timeout)
+	defer cancel()
+
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: hc.Service})
+	if err != nil {
+		return false, err
+	}
+	return resp.GetStatus() == healthpb.HealthCheckResponse_SERVING, nil
+}
@@ -0,0 +1,131 @@
+package grpccircuit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+	"github.com/facebookgo/clock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func dialHealthServer(t *testing.T, hs *health.Server) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	if hs != nil {
+		healthpb.RegisterHealthServer(s, hs)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWatchHealth(t *testing.T) {
+	hs := health.NewServer()
+	hs.SetServingStatus("db", healthpb.HealthCheckResponse_NOT_SERVING)
+	conn := dialHealthServer(t, hs)
+
+	cb := circuit.NewBreaker()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchHealth(ctx, cb, HealthCheck{Conn: conn, Service: "db", Interval: 5 * time.Millisecond})
+	}()
+
+	cb.Trip()
+	waitFor(t, "breaker to be held open", func() bool { return cb.Stats().Broken })
+	time.Sleep(20 * time.Millisecond)
+	if !cb.Tripped() || cb.Ready() {
+		t.Fatal("expected breaker to stay open while the backend is not serving")
+	}
+
+	hs.SetServingStatus("db", healthpb.HealthCheckResponse_SERVING)
+	waitFor(t, "breaker to reset", func() bool { return !cb.Tripped() })
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestWatchHealthClock(t *testing.T) {
+	hs := health.NewServer()
+	hs.SetServingStatus("db", healthpb.HealthCheckResponse_NOT_SERVING)
+	conn := dialHealthServer(t, hs)
+
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := circuit.NewBreakerWithOptions(&circuit.Options{Clock: c})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchHealth(ctx, cb, HealthCheck{Conn: conn, Service: "db", Interval: time.Minute})
+	}()
+
+	cb.Trip()
+	waitFor(t, "breaker to be held open", func() bool { return cb.Stats().Broken })
+	hs.SetServingStatus("db", healthpb.HealthCheckResponse_SERVING)
+	time.Sleep(20 * time.Millisecond)
+	if !cb.Tripped() {
+		t.Fatal("expected no health check before the breaker's clock reaches the interval")
+	}
+	c.Add(time.Minute)
+	waitFor(t, "breaker to reset", func() bool { return !cb.Tripped() })
+
+	hs.SetServingStatus("db", healthpb.HealthCheckResponse_NOT_SERVING)
+	cb.Trip()
+	waitFor(t, "breaker to be held open", func() bool { return cb.Stats().Broken })
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !cb.Tripped() {
+		t.Fatal("expected cancelling the watch not to reset a breaker whose backend is not serving")
+	}
+}
+
+func TestWatchHealthUnimplemented(t *testing.T) {
+	conn := dialHealthServer(t, nil)
+
+	cb := circuit.NewBreaker()
+	cb.Trip()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := WatchHealth(ctx, cb, HealthCheck{Conn: conn, Timeout: 5 * time.Second})
+	if status.Code(errors.Unwrap(err)) != codes.Unimplemented {
+		t.Fatalf("expected an error when the health service is not implemented, got %v", err)
+	}
+	if cb.Stats().Broken {
+		t.Fatal("expected breaker to be left to recover on its own")
+	}
+}
@@ -0,0 +1,117 @@
+// Package grpccircuit integrates circuit breakers with gRPC.
+//
+// The server interceptors shed incoming RPCs early when a breaker protecting
+// one of the downstream dependencies needed to serve them is open, rather than
+// letting each request discover the outage on its own.
+//
+// On the client side, WatchHealth uses a backend's standard health service to
+// decide when a breaker protecting it may close again.
+package grpccircuit
+
+import (
+	"context"
+	"strconv"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// RetryPushbackKey is the trailer used to tell clients how long to wait
+	// before retrying, in milliseconds. It is the key gRPC retry policies read
+	// server pushback from; a negative value asks clients not to retry.
+	RetryPushbackKey = "grpc-retry-pushback-ms"
+
+	// DependencyKey is the trailer naming the dependency whose breaker is open.
+	DependencyKey = "circuit-open-dependency"
+
+	// TripTokenKey is the trailer carrying a circuit.TripToken, which names
+	// the root cause of the outage when the dependency whose breaker is open
+	// reported one further away. See circuit.TripHeader.
+	TripTokenKey = "circuit-trip"
+)
+
+// DependencyFunc returns the names of the breakers, as added to a Panel, that
+// protect the downstream dependencies needed to serve method. method is the
+// full RPC method string, i.e., /package.service/method.
+type DependencyFunc func(ctx context.Context, method string) []string
+
+// UnaryServerInterceptor returns a server interceptor that fails unary RPCs
+// with codes.ResourceExhausted when the breaker of any dependency returned by
+// deps is open. The delay until the breaker will retry is sent in the
+// RetryPushbackKey trailer.
+func UnaryServerInterceptor(p *circuit.Panel, deps DependencyFunc) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if name, cb, ok := openDependency(ctx, p, deps, info.FullMethod); ok {
+			grpc.SetTrailer(ctx, trailer(name, cb))
+			return nil, openError(name)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of
+// UnaryServerInterceptor.
+func StreamServerInterceptor(p *circuit.Panel, deps DependencyFunc) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+	) error {
+		if name, cb, ok := openDependency(ss.Context(), p, deps, info.FullMethod); ok {
+			ss.SetTrailer(trailer(name, cb))
+			return openError(name)
+		}
+		return handler(srv, ss)
+	}
+}
+
+// openDependency returns the first dependency of method whose breaker is open
+// or unavailable. A half-open breaker is not considered open so that the RPC
+// can serve as the trial call, and a disabled one is closed.
+func openDependency(
+	ctx context.Context, p *circuit.Panel, deps DependencyFunc, method string,
+) (string, *circuit.Breaker, bool) {
+	for _, name := range deps(ctx, method) {
+		cb, ok := p.Get(name)
+		if !ok {
+			continue
+		}
+		if s := cb.State(); s == circuit.StateOpen || s == circuit.StateUnavailable {
+			return name, cb, true
+		}
+	}
+	return "", nil, false
+}
+
+func trailer(name string, cb *circuit.Breaker) metadata.MD {
+	pushback := int64(-1)
+	if d := cb.RetryAfter(); d >= 0 {
+		pushback = d.Milliseconds()
+	}
+	return metadata.Pairs(
+		RetryPushbackKey, strconv.FormatInt(pushback, 10),
+		DependencyKey, name,
+		TripTokenKey, circuit.NewTripToken(name, cb).String(),
+	)
+}
+
+// TripTokenFromTrailer returns the token in the TripTokenKey trailer of a
+// failed RPC, if it has a valid one. Clients can record it on the breaker
+// protecting the server with Breaker.SetUpstreamTrip, so that they in turn
+// report the root cause to their own callers.
+func TripTokenFromTrailer(md metadata.MD) (circuit.TripToken, bool) {
+	v := md.Get(TripTokenKey)
+	if len(v) == 0 {
+		return circuit.TripToken{}, false
+	}
+	t, err := circuit.ParseTripToken(v[0])
+	return t, err == nil
+}
+
+func openError(name string) error {
+	return status.Errorf(codes.ResourceExhausted, "circuit breaker for dependency %q is open", name)
+}
@@ -0,0 +1,115 @@
+package grpccircuit
+
+import (
+	"context"
+	"testing"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type testTransportStream struct {
+	trailer metadata.MD
+}
+
+func (s *testTransportStream) Method() string                  { return "/test.Service/Method" }
+func (s *testTransportStream) SetHeader(md metadata.MD) error  { return nil }
+func (s *testTransportStream) SendHeader(md metadata.MD) error { return nil }
+func (s *testTransportStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	p := circuit.NewPanel()
+	db := circuit.NewBreaker()
+	p.Add("db", db)
+	deps := func(ctx context.Context, method string) []string {
+		return []string{"cache", "db"}
+	}
+	interceptor := UnaryServerInterceptor(p, deps)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	called := false
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return "ok", nil
+	}
+
+	stream := &testTransportStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	if _, err := interceptor(ctx, nil, info, handler); err != nil || !called {
+		t.Fatalf("expected RPC to be handled while breaker is closed, got %v", err)
+	}
+
+	db.Break()
+	called = false
+	_, err := interceptor(ctx, nil, info, handler)
+	if called {
+		t.Fatal("expected RPC to be shed while breaker is open")
+	}
+	if code := status.Code(err); code != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", code)
+	}
+	if v := stream.trailer.Get(RetryPushbackKey); len(v) != 1 || v[0] != "-1" {
+		t.Fatalf("expected pushback trailer of -1 for broken breaker, got %v", v)
+	}
+	if v := stream.trailer.Get(DependencyKey); len(v) != 1 || v[0] != "db" {
+		t.Fatalf("expected dependency trailer to name db, got %v", v)
+	}
+
+	db.SetUpstreamTrip(circuit.TripToken{Dependency: "disk", RetryAfter: -1})
+	stream.trailer = nil
+	interceptor(ctx, nil, info, handler)
+	token, ok := TripTokenFromTrailer(stream.trailer)
+	if !ok || token.Dependency != "disk" || len(token.Via) != 1 || token.Via[0] != "db" {
+		t.Fatalf("expected the trip token to name disk via db, got %+v", token)
+	}
+
+	db.Disable()
+	called = false
+	if _, err := interceptor(ctx, nil, info, handler); err != nil || !called {
+		t.Fatalf("expected RPC to be handled while breaker is disabled, got %v", err)
+	}
+	db.Enable()
+	db.Reset()
+
+	db.SetUnavailable("maintenance")
+	called = false
+	if _, err := interceptor(ctx, nil, info, handler); called || status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected RPC to be shed while breaker is unavailable, got %v", err)
+	}
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	trailer metadata.MD
+}
+
+func (s *testServerStream) Context() context.Context  { return context.Background() }
+func (s *testServerStream) SetTrailer(md metadata.MD) { s.trailer = md }
+
+func TestStreamServerInterceptor(t *testing.T) {
+	p := circuit.NewPanel()
+	db := circuit.NewBreaker()
+	p.Add("db", db)
+	deps := func(ctx context.Context, method string) []string {
+		return []string{"db"}
+	}
+	interceptor := StreamServerInterceptor(p, deps)
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}
+	handler := func(srv interface{}, ss grpc.ServerStream) error { return nil }
+
+	db.Break()
+	ss := &testServerStream{}
+	err := interceptor(nil, ss, info, handler)
+	if code := status.Code(err); code != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", code)
+	}
+	if v := ss.trailer.Get(DependencyKey); len(v) != 1 || v[0] != "db" {
+		t.Fatalf("expected dependency trailer to name db, got %v", v)
+	}
+}
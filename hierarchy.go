@@ -0,0 +1,74 @@
+package circuit
+
+// Breakers can be composed into a hierarchy by creating them with
+// Options.Parent, to tell a whole service being down apart from one of its
+// endpoints failing:
+//
+//	service := circuit.NewBreakerWithOptions(&circuit.Options{
+//		ShouldTrip: circuit.TrippedChildrenTripFunc(3),
+//	})
+//	endpoint := circuit.NewBreakerWithOptions(&circuit.Options{
+//		ShouldTrip: circuit.ConsecutiveTripFunc(5),
+//		Parent:     service,
+//	})
+//
+// Tripping one endpoint's breaker only stops calls to that endpoint. Once
+// three have tripped, the service's breaker trips and calls to every endpoint
+// are rejected until it lets calls through again.
+
+func (cb *Breaker) addChild(child *Breaker) {
+	cb.eventLock.Lock()
+	cb.children = append(cb.children, child)
+	cb.eventLock.Unlock()
+}
+
+// detach removes the breaker from its parent's children, once it is no longer
+// used, so that the parent's TripFunc stops counting it.
+func (cb *Breaker) detach() {
+	p := cb.parent
+	if p == nil {
+		return
+	}
+	p.eventLock.Lock()
+	defer p.eventLock.Unlock()
+	for i, child := range p.children {
+		if child == cb {
+			p.children = append(p.children[:i:i], p.children[i+1:]...)
+			return
+		}
+	}
+}
+
+// Parent returns the breaker's parent, or nil if it has none.
+func (cb *Breaker) Parent() *Breaker {
+	return cb.parent
+}
+
+// Children returns the breakers created with cb as their parent.
+func (cb *Breaker) Children() []*Breaker {
+	cb.eventLock.Lock()
+	defer cb.eventLock.Unlock()
+	return append([]*Breaker(nil), cb.children...)
+}
+
+// parentOpen reports whether an ancestor of the breaker is open. A half-open
+// parent lets its children's calls through as its trial calls, up to its
+// MaxProbes at once, and the first of them to succeed resets it.
+func (cb *Breaker) parentOpen() bool {
+	return cb.parent != nil && cb.parent.State() == StateOpen
+}
+
+// TrippedChildrenTripFunc returns a TripFunc for a parent breaker that trips
+// when at least threshold of its children are tripped. It is checked whenever
+// a child records a failure.
+func TrippedChildrenTripFunc(threshold int) TripFunc {
+	return func(cb *Breaker) bool {
+		tripped := 0
+		for _, child := range cb.Children() {
+			if child.Tripped() {
+				tripped++
+			}
+		}
+		return tripped >= threshold
+	}
+}
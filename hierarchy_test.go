@@ -0,0 +1,121 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/facebookgo/clock"
+)
+
+func TestNestedBreakers(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	service := NewBreakerWithOptions(&Options{
+		Clock:      c,
+		ShouldTrip: TrippedChildrenTripFunc(2),
+	})
+	newEndpoint := func() *Breaker {
+		return NewBreakerWithOptions(&Options{
+			Clock:      c,
+			ShouldTrip: ConsecutiveTripFunc(2),
+			Parent:     service,
+		})
+	}
+	a, b, other := newEndpoint(), newEndpoint(), newEndpoint()
+
+	if a.Parent() != service || len(service.Children()) != 3 {
+		t.Fatal("expected endpoints to be children of the service")
+	}
+
+	fail := func() error { return errors.New("failed") }
+	ok := func() error { return nil }
+
+	a.Call(fail, 0)
+	a.Call(fail, 0)
+	if !a.Tripped() || service.Tripped() {
+		t.Fatal("expected one endpoint to trip without tripping the service")
+	}
+	if err := other.Call(ok, 0); err != nil {
+		t.Fatalf("expected other endpoints to let calls through, got %v", err)
+	}
+	if service.Successes() != 1 || service.Failures() != 2 {
+		t.Fatalf("expected outcomes to be recorded on the service, got %d successes and %d failures",
+			service.Successes(), service.Failures())
+	}
+
+	b.Call(fail, 0)
+	b.Call(fail, 0)
+	if !service.Tripped() {
+		t.Fatal("expected the service to trip once two endpoints tripped")
+	}
+	if other.State() != StateOpen {
+		t.Fatalf("expected an open service to open its endpoints, got %v", other.State())
+	}
+	if err := other.Call(ok, 0); err != ErrBreakerOpen {
+		t.Fatalf("expected an open service to reject calls, got %v", err)
+	}
+
+	c.Add(service.RetryAfter() + 1)
+	if err := other.Call(ok, 0); err != nil {
+		t.Fatalf("expected a half-open service to let calls through, got %v", err)
+	}
+	if service.Tripped() {
+		t.Fatal("expected a successful call to reset the service")
+	}
+	if !a.Tripped() {
+		t.Fatal("expected tripped endpoints to stay tripped")
+	}
+}
+
+func TestHalfOpenParentProbes(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	service := NewBreakerWithOptions(&Options{
+		Clock:   c,
+		BackOff: &backoff.ConstantBackOff{Interval: time.Second},
+	})
+	a := NewBreakerWithOptions(&Options{Clock: c, Parent: service})
+	b := NewBreakerWithOptions(&Options{Clock: c, Parent: service})
+
+	service.Trip()
+	c.Add(5 * time.Second)
+	token, err := a.Acquire()
+	if err != nil || !token.Probe() {
+		t.Fatalf("expected a call through a half-open parent to be a trial call, got %v", err)
+	}
+	if err := b.Call(func() error { return nil }, 0); err != ErrBreakerOpen {
+		t.Fatalf("expected another child's call to be rejected while the parent's trial call is in flight, got %v", err)
+	}
+
+	token.Done(nil)
+	if service.Tripped() {
+		t.Fatal("expected the successful trial call to reset the parent")
+	}
+	if err := b.Call(func() error { return nil }, 0); err != nil {
+		t.Fatalf("expected a closed parent to let calls through, got %v", err)
+	}
+}
+
+func TestDetachChildren(t *testing.T) {
+	service := NewBreaker()
+	p := NewPanel()
+	p.Defaults = &Options{Parent: service}
+	p.MaxTenants = 1
+
+	p.AddWithOptions("db", nil)
+	p.ForTenant("a").Get("db")
+	if n := len(service.Children()); n != 2 {
+		t.Fatalf("expected 2 children, got %d", n)
+	}
+
+	p.Remove("db")
+	if n := len(service.Children()); n != 1 {
+		t.Fatalf("expected the removed breaker to be detached, got %d children", n)
+	}
+	p.ForTenant("b")
+	if n := len(service.Children()); n != 0 {
+		t.Fatalf("expected the evicted tenant's breaker to be detached, got %d children", n)
+	}
+}
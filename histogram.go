@@ -0,0 +1,94 @@
+package circuit
+
+import (
+	"sync"
+	"time"
+)
+
+// stateDurationBounds are the upper bounds of the buckets of the histograms of
+// the time breakers spend in each state.
+var stateDurationBounds = []time.Duration{
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+}
+
+// Histogram is a snapshot of a distribution of durations.
+type Histogram struct {
+	// Bounds are the upper bounds of the buckets.
+	Bounds []time.Duration `json:"bounds"`
+	// Counts holds the number of durations in each bucket, that is, greater
+	// than the previous bound and at most the bucket's own. It has one more
+	// entry than Bounds, counting the durations above the last bound.
+	Counts []int64 `json:"counts"`
+	// Count is the total number of durations and Sum their total.
+	Count int64         `json:"count"`
+	Sum   time.Duration `json:"sum"`
+}
+
+// Mean returns the mean of the durations, or 0 if there are none.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// histogram accumulates durations into the buckets given by
+// stateDurationBounds. The zero value is ready to use.
+type histogram struct {
+	counts []int64
+	count  int64
+	sum    time.Duration
+	lock   sync.Mutex
+}
+
+// Observe records a duration.
+func (h *histogram) Observe(d time.Duration) {
+	i := 0
+	for i < len(stateDurationBounds) && d > stateDurationBounds[i] {
+		i++
+	}
+
+	h.lock.Lock()
+	if h.counts == nil {
+		h.counts = make([]int64, len(stateDurationBounds)+1)
+	}
+	h.counts[i]++
+	h.count++
+	h.sum += d
+	h.lock.Unlock()
+}
+
+// Snapshot returns the recorded distribution.
+func (h *histogram) Snapshot() Histogram {
+	s := Histogram{
+		Bounds: append([]time.Duration(nil), stateDurationBounds...),
+		Counts: make([]int64, len(stateDurationBounds)+1),
+	}
+	h.lock.Lock()
+	copy(s.Counts, h.counts)
+	s.Count = h.count
+	s.Sum = h.sum
+	h.lock.Unlock()
+	return s
+}
+
+// Restore replaces the recorded distribution with s. Buckets are matched by
+// position, so s should have been taken with the same bounds.
+func (h *histogram) Restore(s Histogram) {
+	h.lock.Lock()
+	h.counts = make([]int64, len(stateDurationBounds)+1)
+	copy(h.counts, s.Counts)
+	h.count = s.Count
+	h.sum = s.Sum
+	h.lock.Unlock()
+}
@@ -0,0 +1,51 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestStateDurationHistograms(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	cb := NewBreakerWithOptions(&Options{Clock: c})
+
+	c.Add(2 * time.Minute)
+	cb.Trip()
+	c.Add(3 * time.Second)
+	cb.Reset()
+	c.Add(20 * time.Second)
+	cb.Trip()
+	c.Add(2 * time.Hour)
+	cb.Reset()
+
+	s := cb.Stats()
+	open := s.OpenDurations
+	if open.Count != 2 || open.Sum != 2*time.Hour+3*time.Second {
+		t.Fatalf("expected 2 open durations totalling 2h0m3s, got %+v", open)
+	}
+	if len(open.Counts) != len(open.Bounds)+1 {
+		t.Fatalf("expected an overflow bucket, got %d counts for %d bounds", len(open.Counts), len(open.Bounds))
+	}
+	// 3s falls in the (1s, 5s] bucket and 2h above the last bound.
+	if open.Counts[3] != 1 || open.Counts[len(open.Counts)-1] != 1 {
+		t.Fatalf("expected open durations in the 5s and overflow buckets, got %v", open.Counts)
+	}
+	if mean := open.Mean(); mean != time.Hour+1500*time.Millisecond {
+		t.Fatalf("expected mean open duration of 1h0m1.5s, got %v", mean)
+	}
+
+	closed := s.ClosedDurations
+	if closed.Count != 2 || closed.Sum != 2*time.Minute+20*time.Second {
+		t.Fatalf("expected 2 closed durations totalling 2m20s, got %+v", closed)
+	}
+
+	// Tripping while tripped is not a new closed period.
+	cb.Trip()
+	cb.Trip()
+	if n := cb.Stats().ClosedDurations.Count; n != 3 {
+		t.Fatalf("expected 3 closed durations, got %d", n)
+	}
+}
@@ -0,0 +1,118 @@
+package circuit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff"
+)
+
+const (
+	// IMDSTimeout is the timeout of calls made by an IMDS created with
+	// NewIMDS. Metadata services answer from the local host, so anything
+	// slower means they are in trouble.
+	IMDSTimeout = time.Second
+
+	// IMDSOpenInterval is how long the breaker of IMDSOptions stays open
+	// before its first trial call.
+	IMDSOpenInterval = 30 * time.Second
+
+	// IMDSMaxOpenInterval is the longest the breaker of IMDSOptions stays
+	// open between trial calls.
+	IMDSMaxOpenInterval = 10 * time.Minute
+)
+
+// IMDSOptions returns the options of a breaker for calls to a cloud metadata
+// service, such as AWS's IMDS or GCP's metadata server: a hidden dependency of
+// credential and region lookups that tends to hang during platform incidents.
+// The breaker trips after 2 consecutive failures and stays open for
+// IMDSOpenInterval, backing off up to IMDSMaxOpenInterval, since metadata
+// rarely changes and stale values are usually fine meanwhile. A new set of
+// options is returned on each call, to be adjusted as needed.
+func IMDSOptions() *Options {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = IMDSOpenInterval
+	b.MaxInterval = IMDSMaxOpenInterval
+	b.MaxElapsedTime = 0
+	b.Reset()
+	return &Options{
+		BackOff:    b,
+		ShouldTrip: ConsecutiveTripFunc(2),
+		Name:       "imds",
+	}
+}
+
+// IMDS makes calls to a cloud metadata service through a breaker, and falls
+// back to the last value fetched for a key when a call fails or is rejected:
+//
+//	imds := circuit.NewIMDS()
+//	region, err := imds.Get(ctx, "region", func(ctx context.Context) ([]byte, error) {
+//		return fetchRegion(ctx)
+//	})
+type IMDS struct {
+	// Breaker protects the calls.
+	Breaker *Breaker
+
+	// Timeout, if non-zero, is how long a call may take before it fails.
+	Timeout time.Duration
+
+	// Fallback, if non-nil, is called with the key, the last value fetched
+	// for it (nil if none) and the error of a call that failed or was
+	// rejected, and its results are returned by Get. If it is nil, Get
+	// returns the last value fetched for the key, or the error if there is
+	// none.
+	Fallback func(key string, cached []byte, err error) ([]byte, error)
+
+	lock  sync.Mutex
+	cache map[string][]byte
+}
+
+// NewIMDS creates an IMDS with a breaker configured by IMDSOptions and a
+// timeout of IMDSTimeout.
+func NewIMDS() *IMDS {
+	return &IMDS{Breaker: NewBreakerWithOptions(IMDSOptions()), Timeout: IMDSTimeout}
+}
+
+// Get returns the value for key returned by fetch, or a fallback if the call
+// fails or the breaker is open. The context passed to fetch expires after the
+// Timeout. As with CallContext, calls failing because ctx was canceled are not
+// recorded.
+func (m *IMDS) Get(ctx context.Context, key string, fetch func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	fetchCtx := ctx
+	if m.Timeout > 0 {
+		var cancel context.CancelFunc
+		fetchCtx, cancel = context.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+	}
+
+	fetched := make(chan []byte, 1)
+	err := m.Breaker.CallContext(ctx, func() error {
+		value, err := fetch(fetchCtx)
+		if err != nil {
+			return err
+		}
+		fetched <- value
+		return nil
+	}, m.Timeout)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if err == nil {
+		value := <-fetched
+		if m.cache == nil {
+			m.cache = make(map[string][]byte)
+		}
+		m.cache[key] = value
+		return value, nil
+	}
+
+	cached, ok := m.cache[key]
+	if m.Fallback != nil {
+		return m.Fallback(key, cached, err)
+	}
+	if ok {
+		return cached, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,56 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIMDS(t *testing.T) {
+	m := NewIMDS()
+	m.Timeout = 10 * time.Millisecond
+	ctx := context.Background()
+
+	ok := func(ctx context.Context) ([]byte, error) { return []byte("us-east-1"), nil }
+	hang := func(ctx context.Context) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	fail := func(ctx context.Context) ([]byte, error) { return nil, errors.New("unavailable") }
+
+	if v, err := m.Get(ctx, "region", ok); err != nil || string(v) != "us-east-1" {
+		t.Fatalf("expected us-east-1, got %q (%v)", v, err)
+	}
+
+	if v, err := m.Get(ctx, "region", hang); err != nil || string(v) != "us-east-1" {
+		t.Fatalf("expected the cached value when the call hangs, got %q (%v)", v, err)
+	}
+	if _, err := m.Get(ctx, "zone", fail); err == nil {
+		t.Fatal("expected an error without a cached value")
+	}
+	if !m.Breaker.Tripped() {
+		t.Fatal("expected 2 consecutive failures to trip the breaker")
+	}
+	if d := m.Breaker.RetryAfter(); d < IMDSOpenInterval/4 {
+		t.Fatalf("expected the breaker to stay open for about %v, got %v", IMDSOpenInterval, d)
+	}
+
+	called := false
+	if v, _ := m.Get(ctx, "region", func(ctx context.Context) ([]byte, error) {
+		called = true
+		return nil, nil
+	}); called || string(v) != "us-east-1" {
+		t.Fatalf("expected the cached value without a call while open, got %q", v)
+	}
+
+	m.Fallback = func(key string, cached []byte, err error) ([]byte, error) {
+		if err != ErrBreakerOpen {
+			t.Errorf("expected ErrBreakerOpen, got %v", err)
+		}
+		return []byte("default-" + key), nil
+	}
+	if v, err := m.Get(ctx, "zone", ok); err != nil || string(v) != "default-zone" {
+		t.Fatalf("expected the fallback's value, got %q (%v)", v, err)
+	}
+}
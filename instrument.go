@@ -0,0 +1,52 @@
+package circuit
+
+import "net/http"
+
+// DefaultInstrumentOptions are the options of the breakers added by
+// NewEndpointTransport and InstrumentDefaultTransport to a panel without
+// Defaults: they trip once half of the requests in the window fail, when
+// there have been at least 20.
+var DefaultInstrumentOptions = Options{ShouldTrip: RateTripFunc(0.5, 20)}
+
+// NewEndpointTransport returns a Transport that protects requests made with rt
+// with a breaker per endpoint, as named by key. The breakers are added to p on
+// first use, with p's Defaults, or DefaultInstrumentOptions if it has none.
+// Requests that cannot be named are made without a breaker.
+func NewEndpointTransport(p *Panel, key EndpointKeyFunc, rt http.RoundTripper) *Transport {
+	t := &Transport{Transport: rt}
+	t.BreakerLookup = func(req *http.Request) *Breaker {
+		name := key(req.URL)
+		if name == "" {
+			return nil
+		}
+		var opts *Options
+		if p.Defaults == nil {
+			defaults := DefaultInstrumentOptions
+			opts = &defaults
+		}
+		return p.getOrAdd(name, opts)
+	}
+	return t
+}
+
+// InstrumentDefaultTransport replaces http.DefaultTransport with a Transport
+// protecting every request made with it, and so with http.DefaultClient and
+// the http package's Get, Post and the like, with a breaker per host, added
+// to p as in NewEndpointTransport. It gives every outbound HTTP call breaker
+// coverage with one line of initialization:
+//
+//	func init() {
+//		circuit.InstrumentDefaultTransport(panel)
+//	}
+//
+// Clients with a Transport of their own are not affected. It should be called
+// before any requests are made, as http.DefaultTransport is not safe to
+// replace concurrently with them. The returned function puts back the
+// transport it replaced.
+func InstrumentDefaultTransport(p *Panel) (restore func()) {
+	orig := http.DefaultTransport
+	http.DefaultTransport = NewEndpointTransport(p, HostKey, orig)
+	return func() {
+		http.DefaultTransport = orig
+	}
+}
@@ -0,0 +1,84 @@
+package circuit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestInstrumentDefaultTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	p := NewPanel()
+	restore := InstrumentDefaultTransport(p)
+	defer restore()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("expected request to succeed, got %v", err)
+	}
+	resp.Body.Close()
+
+	u, _ := url.Parse(ts.URL)
+	cb, ok := p.Get(u.Host)
+	if !ok {
+		t.Fatalf("expected a breaker for %s", u.Host)
+	}
+	if s := cb.Successes(); s != 1 {
+		t.Fatalf("expected 1 success, got %d", s)
+	}
+
+	cb.Break()
+	if _, err := http.Get(ts.URL); err == nil {
+		t.Fatal("expected request to fail while the host's breaker is open")
+	}
+
+	restore()
+	if _, ok := http.DefaultTransport.(*Transport); ok {
+		t.Fatal("expected restore to put back the original transport")
+	}
+	resp, err = http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("expected request to succeed after restore, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestEndpointTransportDefaults(t *testing.T) {
+	p := NewPanel()
+	transport := NewEndpointTransport(p, HostKey, nil)
+	req, _ := http.NewRequest("GET", "http://example.invalid/", nil)
+
+	cb := transport.BreakerLookup(req)
+	if cb == nil {
+		t.Fatal("expected a breaker for the host")
+	}
+	for i := 0; i < 20; i++ {
+		cb.Fail(nil)
+	}
+	if !cb.Tripped() {
+		t.Fatal("expected the default options to trip on a high error rate")
+	}
+	if again := transport.BreakerLookup(req); again != cb {
+		t.Fatal("expected the same breaker for the same host")
+	}
+}
+
+func TestEndpointTransportUnnamed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	p := NewPanel()
+	unnamed := func(*url.URL) string { return "" }
+	client := &http.Client{Transport: NewEndpointTransport(p, unnamed, nil)}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("expected a request that cannot be named to be made without a breaker, got %v", err)
+	}
+	resp.Body.Close()
+	if n := len(p.Circuits); n != 0 {
+		t.Fatalf("expected no breakers to be added, got %d", n)
+	}
+}
@@ -0,0 +1,147 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// CircuitBreaker is the interface implemented by Breaker. Code that depends on
+// it rather than on *Breaker can be given mocks in tests, or alternative
+// implementations such as distributed or adaptive breakers.
+type CircuitBreaker interface {
+	// Call runs circuit if the breaker allows it and records the outcome.
+	Call(circuit func() error, timeout time.Duration) error
+
+	// CallContext is Call, but does not record a failure if ctx was canceled.
+	CallContext(ctx context.Context, circuit func() error, timeout time.Duration) error
+
+	// Allow returns ErrBreakerOpen if a call should not be made. Otherwise
+	// the call may be made and its outcome must be passed to Record.
+	Allow() error
+
+	// Record records the outcome of a call allowed by Allow.
+	Record(err error)
+
+	// Stats returns a snapshot of the breaker's state and statistics.
+	Stats() Stats
+
+	// State returns the breaker's state.
+	State() State
+
+	// Subscribe returns a channel of the breaker's events.
+	Subscribe() <-chan BreakerEvent
+}
+
+var _ CircuitBreaker = (*Breaker)(nil)
+
+// State is the state of a breaker, as returned by Breaker.State.
+type State int
+
+const (
+	// StateClosed is the state of a breaker that lets calls through.
+	StateClosed State = iota
+
+	// StateOpen is the state of a tripped breaker that rejects calls.
+	StateOpen
+
+	// StateHalfOpen is the state of a tripped breaker that is ready to let a
+	// trial call through.
+	StateHalfOpen
+
+	// StateUnavailable is the state of a breaker whose dependency has been
+	// turned off on purpose. See Breaker.SetUnavailable.
+	StateUnavailable
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	case StateUnavailable:
+		return "unavailable"
+	}
+	return "unknown"
+}
+
+// State returns the breaker's state. Unlike Ready, it does not let a trial
+// call through when the breaker is half-open. A disabled breaker is closed,
+// unless it is unavailable.
+func (cb *Breaker) State() State {
+	if atomic.LoadInt32(&cb.unavailable) == 1 {
+		return StateUnavailable
+	}
+	if cb.Disabled() {
+		return StateClosed
+	}
+	if cb.faults != nil && cb.faults.open() {
+		return StateOpen
+	}
+	if cb.parentOpen() {
+		return StateOpen
+	}
+	if !cb.Tripped() {
+		return StateClosed
+	}
+	if cb.RetryAfter() == 0 && atomic.LoadInt64(&cb.halfOpens) == 0 {
+		return StateHalfOpen
+	}
+	return StateOpen
+}
+
+// rejecting reports whether the breaker is open or unavailable, so that calls
+// made through it now would be rejected. A half-open breaker is not, as it
+// would let a trial call through.
+func (cb *Breaker) rejecting() bool {
+	s := cb.State()
+	return s == StateOpen || s == StateUnavailable
+}
+
+// Allow is the first half of a call made in two steps, for when the call
+// cannot be wrapped in a function for Call, such as when it spans a callback.
+// It returns ErrBreakerOpen if the call should not be made. Otherwise the
+// outcome of the call must be passed to Record.
+//
+// Allow takes no trial call slot, so calls it lets through do not count
+// against Options.MaxProbes. Use Acquire for calls that should.
+func (cb *Breaker) Allow() error {
+	_, _, err := cb.allow(false)
+	return err
+}
+
+// allow is Allow, but also reports whether the call is a half-open trial. If
+// slot is set, a trial call takes trial call slots, as with readySlot.
+func (cb *Breaker) allow(slot bool) (probe bool, held trialSlots, err error) {
+	if err := cb.unavailableErr(); err != nil {
+		return false, nil, err
+	}
+	ready, probe, held := cb.readySlot(slot)
+	if !ready {
+		cb.reject()
+		return false, nil, ErrBreakerOpen
+	}
+	return probe, held, nil
+}
+
+// reject records a call the breaker rejected.
+func (cb *Breaker) reject() {
+	cb.updateStreak(cb.consecPolicy.Rejections, 1)
+	cb.counts.Reject()
+}
+
+// Record records the outcome of a call allowed by Allow: a success if err is
+// nil, and a failure otherwise. As with CallContext, canceled calls are not
+// recorded.
+func (cb *Breaker) Record(err error) {
+	switch {
+	case err == nil:
+		cb.Success()
+	case !errors.Is(err, context.Canceled):
+		cb.Fail(err)
+	}
+}
@@ -0,0 +1,62 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestBreakerState(t *testing.T) {
+	c := clock.NewMock()
+	cb := NewBreakerWithOptions(&Options{Clock: c})
+	if s := cb.State(); s != StateClosed {
+		t.Fatalf("expected closed, got %s", s)
+	}
+
+	cb.Trip()
+	if s := cb.State(); s != StateOpen {
+		t.Fatalf("expected open, got %s", s)
+	}
+
+	c.Add(cb.nextBackOff + time.Millisecond)
+	if s := cb.State(); s != StateHalfOpen {
+		t.Fatalf("expected half-open, got %s", s)
+	}
+	if s := cb.State(); s != StateHalfOpen {
+		t.Fatalf("expected State not to use up the trial call, got %s", s)
+	}
+	if !cb.Ready() {
+		t.Fatal("expected a trial call to be let through")
+	}
+	cb.Fail(nil)
+	if s := cb.State(); s != StateOpen {
+		t.Fatalf("expected open after the trial call failed, got %s", s)
+	}
+}
+
+func TestAllowRecord(t *testing.T) {
+	var cb CircuitBreaker = NewConsecutiveBreaker(2)
+
+	for i := 0; i < 2; i++ {
+		if err := cb.Allow(); err != nil {
+			t.Fatalf("expected call %d to be allowed, got %v", i, err)
+		}
+		cb.Record(errors.New("fail"))
+	}
+	if err := cb.Allow(); err != ErrBreakerOpen {
+		t.Fatalf("expected ErrBreakerOpen, got %v", err)
+	}
+	if s := cb.Stats(); s.Failures != 2 || !s.Tripped {
+		t.Fatalf("expected 2 failures and a tripped breaker, got %+v", s)
+	}
+
+	cb = NewBreaker()
+	cb.Record(context.Canceled)
+	cb.Record(nil)
+	if s := cb.Stats(); s.Failures != 0 || s.Successes != 1 {
+		t.Fatalf("expected canceled call to be ignored, got %+v", s)
+	}
+}
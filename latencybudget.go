@@ -0,0 +1,146 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExhausted is the error calls rejected because their request's
+// latency budget is spent match with errors.Is. They fail with a
+// *BudgetExhaustedError.
+var ErrBudgetExhausted = errors.New("latency budget exhausted")
+
+// LatencyBudget is the time a request may spend in calls made through
+// breakers, shared by the breakers it passes through by way of its context.
+// The timeout of each call made with CallContext or Do is shortened to the
+// budget left, and once the budget is spent, later calls are rejected at
+// once rather than made too late to be of use. Calls made without a timeout
+// still run without one:
+//
+//	ctx = circuit.WithLatencyBudget(ctx, 200*time.Millisecond)
+//	err := auth.Do(ctx, checkToken, time.Second)
+//	...
+//	err = db.Do(ctx, query, time.Second) // rejected if checkToken took 200ms
+//
+// Calls made inside a call made with Do are nested: they are part of the
+// outer call's time and do not count toward the budget again.
+type LatencyBudget struct {
+	budget time.Duration
+	lock   sync.Mutex
+	spent  time.Duration
+	hops   []BudgetHop
+}
+
+// BudgetHop is a call made through a breaker on a LatencyBudget.
+type BudgetHop struct {
+	// Breaker is the name of the breaker.
+	Breaker string
+
+	// Duration is how long the call took, and Err what it failed with.
+	Duration time.Duration
+	Err      error
+
+	// Nested is true for calls made inside another call on the budget.
+	Nested bool
+}
+
+func (h BudgetHop) String() string {
+	s := fmt.Sprintf("%s (%s", h.Breaker, h.Duration)
+	if h.Nested {
+		s += ", nested"
+	}
+	if h.Err != nil {
+		s += ": " + h.Err.Error()
+	}
+	return s + ")"
+}
+
+type latencyBudgetKey struct{}
+
+// WithLatencyBudget returns a copy of ctx carrying a LatencyBudget of d.
+func WithLatencyBudget(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, latencyBudgetKey{}, &LatencyBudget{budget: d})
+}
+
+// LatencyBudgetFromContext returns the LatencyBudget carried by ctx.
+func LatencyBudgetFromContext(ctx context.Context) (*LatencyBudget, bool) {
+	b, ok := ctx.Value(latencyBudgetKey{}).(*LatencyBudget)
+	return b, ok
+}
+
+// Budget returns the time the budget started with.
+func (b *LatencyBudget) Budget() time.Duration {
+	return b.budget
+}
+
+// Spent returns the time spent in calls that were not nested.
+func (b *LatencyBudget) Spent() time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.spent
+}
+
+// Remaining returns the time left in the budget, which is negative if it has
+// been overspent.
+func (b *LatencyBudget) Remaining() time.Duration {
+	return b.budget - b.Spent()
+}
+
+// Hops returns the calls made on the budget so far, in the order they
+// completed.
+func (b *LatencyBudget) Hops() []BudgetHop {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return append([]BudgetHop(nil), b.hops...)
+}
+
+// record records a call made through the breaker named name.
+func (b *LatencyBudget) record(name string, d time.Duration, err error, nested bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.hops = append(b.hops, BudgetHop{Breaker: name, Duration: d, Err: err, Nested: nested})
+	if !nested {
+		b.spent += d
+	}
+}
+
+// BudgetExhaustedError is the error of a call rejected because its latency
+// budget was spent. It lists the calls that spent it.
+type BudgetExhaustedError struct {
+	// Breaker is the name of the breaker that rejected the call.
+	Breaker string
+
+	Budget time.Duration
+	Spent  time.Duration
+	Hops   []BudgetHop
+}
+
+func (e *BudgetExhaustedError) Error() string {
+	hops := make([]string, len(e.Hops))
+	for i, h := range e.Hops {
+		hops[i] = h.String()
+	}
+	return fmt.Sprintf("latency budget of %s exhausted before breaker %s: %s spent in %s",
+		e.Budget, e.Breaker, e.Spent, strings.Join(hops, ", "))
+}
+
+// Unwrap returns ErrBudgetExhausted.
+func (e *BudgetExhaustedError) Unwrap() error {
+	return ErrBudgetExhausted
+}
+
+// exhausted returns the error of a call rejected by the breaker named name.
+func (b *LatencyBudget) exhausted(name string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return &BudgetExhaustedError{
+		Breaker: name,
+		Budget:  b.budget,
+		Spent:   b.spent,
+		Hops:    append([]BudgetHop(nil), b.hops...),
+	}
+}
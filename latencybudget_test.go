@@ -0,0 +1,72 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatencyBudget(t *testing.T) {
+	auth := New(WithName("auth"))
+	db := New(WithName("db"))
+	cache := New(WithName("cache"))
+	ctx := WithLatencyBudget(context.Background(), 50*time.Millisecond)
+
+	err := auth.CallContext(ctx, func() error {
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.CallContext(ctx, func() error {
+		time.Sleep(time.Second)
+		return nil
+	}, time.Minute)
+	if err != ErrBreakerTimeout {
+		t.Fatalf("expected the call to time out with the rest of the budget, got %v", err)
+	}
+
+	called := false
+	err = cache.CallContext(ctx, func() error { called = true; return nil }, 0)
+	var be *BudgetExhaustedError
+	if called || !errors.As(err, &be) || !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("expected the call to be rejected with the budget spent, got %v", err)
+	}
+	if be.Breaker != "cache" || len(be.Hops) != 2 || be.Hops[0].Breaker != "auth" || be.Hops[1].Err != ErrBreakerTimeout {
+		t.Fatalf("expected the error to list the calls that spent the budget, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "auth (") || !strings.Contains(err.Error(), "db (") {
+		t.Fatalf("expected the error message to name the hops, got %q", err)
+	}
+	if cache.Rejects() != 1 {
+		t.Fatal("expected the rejected call to be counted")
+	}
+}
+
+func TestLatencyBudgetNested(t *testing.T) {
+	outer := New(WithName("outer"))
+	inner := New(WithName("inner"))
+	ctx := WithLatencyBudget(context.Background(), time.Minute)
+
+	err := outer.Do(ctx, func(ctx context.Context) error {
+		return inner.Do(ctx, func(context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}, 0)
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	budget, _ := LatencyBudgetFromContext(ctx)
+	hops := budget.Hops()
+	if len(hops) != 2 || hops[0].Breaker != "inner" || !hops[0].Nested || hops[1].Nested {
+		t.Fatalf("expected the inner call to be nested in the outer one, got %v", hops)
+	}
+	if budget.Spent() != hops[1].Duration {
+		t.Fatalf("expected only the outer call to count, got %s spent", budget.Spent())
+	}
+}
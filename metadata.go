@@ -0,0 +1,60 @@
+package circuit
+
+import (
+	"context"
+	"time"
+)
+
+// Metadata describes a call, such as the request ID, endpoint or tenant it was
+// made for. Metadata attached to a call's context with WithMetadata is
+// included in the events its failure causes and in the breaker's TripCause,
+// so that trips can be traced back to the requests behind them.
+type Metadata map[string]string
+
+type metadataKey struct{}
+
+// WithMetadata returns a copy of ctx carrying md, merged over any metadata ctx
+// already carries.
+func WithMetadata(ctx context.Context, md Metadata) context.Context {
+	parent := MetadataFromContext(ctx)
+	merged := make(Metadata, len(parent)+len(md))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range md {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, metadataKey{}, merged)
+}
+
+// MetadataFromContext returns the metadata carried by ctx, or nil. The result
+// must not be modified.
+func MetadataFromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+	return md
+}
+
+// TripCause describes the failure that last tripped a breaker.
+type TripCause struct {
+	// Error is the error of the failed call.
+	Error string `json:"error"`
+	// Metadata is the metadata of the failed call.
+	Metadata Metadata `json:"metadata,omitempty"`
+	// Time is when the breaker tripped.
+	Time time.Time `json:"time"`
+
+	err error
+}
+
+// TripCause returns the cause of the breaker's last trip, or nil if it has not
+// been tripped by a failure. Trips made by calling Trip or Break have no
+// cause.
+func (cb *Breaker) TripCause() *TripCause {
+	cb.eventLock.Lock()
+	defer cb.eventLock.Unlock()
+	if cb.tripCause == nil {
+		return nil
+	}
+	cause := *cb.tripCause
+	return &cause
+}
@@ -0,0 +1,51 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCallMetadata(t *testing.T) {
+	cb := NewConsecutiveBreaker(2)
+	listener := make(chan ListenerEvent, 10)
+	cb.AddListener(listener)
+
+	ctx := WithMetadata(context.Background(), Metadata{"tenant": "acme"})
+	ctx = WithMetadata(ctx, Metadata{"request_id": "r1"})
+	errFailed := errors.New("failed")
+	fail := func() error { return errFailed }
+
+	cb.CallContext(WithMetadata(context.Background(), Metadata{"request_id": "r0"}), fail, 0)
+	cb.CallContext(ctx, fail, 0)
+
+	want := Metadata{"tenant": "acme", "request_id": "r1"}
+	var sawTrip bool
+	for len(listener) > 0 {
+		e := <-listener
+		if e.Event == BreakerTripped {
+			sawTrip = true
+			if e.Err != errFailed || !reflect.DeepEqual(e.Metadata, want) {
+				t.Fatalf("expected trip event to carry the failure, got %+v", e)
+			}
+		}
+	}
+	if !sawTrip {
+		t.Fatal("expected a trip event")
+	}
+
+	cause := cb.TripCause()
+	if cause == nil || cause.Error != "failed" || !reflect.DeepEqual(cause.Metadata, want) {
+		t.Fatalf("expected trip cause to be captured, got %+v", cause)
+	}
+	if s := cb.Stats(); s.TripCause == nil || s.TripCause.Metadata["request_id"] != "r1" {
+		t.Fatalf("expected trip cause in stats, got %+v", s.TripCause)
+	}
+
+	cb.Reset()
+	cb.Trip()
+	if cause := cb.TripCause(); cause != nil {
+		t.Fatalf("expected a manual trip to have no cause, got %+v", cause)
+	}
+}
@@ -0,0 +1,166 @@
+package circuit
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/facebookgo/clock"
+)
+
+// Option configures a breaker created with New.
+type Option func(*Options)
+
+// New creates a breaker configured by opts, applied in order:
+//
+//	cb := circuit.New(
+//		circuit.WithRate(0.5, 20),
+//		circuit.WithWindow(time.Minute, 60),
+//		circuit.WithName("db"),
+//	)
+//
+// New() is the same as NewBreaker(). Settings without an Option of their own
+// can be given with WithOptions.
+func New(opts ...Option) *Breaker {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return NewBreakerWithOptions(&options)
+}
+
+// WithOptions applies the set fields of o, as Panel.AddWithOptions does over
+// the panel's Defaults.
+func WithOptions(o *Options) Option {
+	return func(options *Options) {
+		*options = *mergeOptions(options, o)
+	}
+}
+
+// OptionFields is a set of the boolean and policy fields of Options, for
+// Options.Unset.
+type OptionFields uint32
+
+const (
+	FieldCoarseClock OptionFields = 1 << iota
+	FieldAlignBuckets
+	FieldRateDecay
+	FieldEmptyRate
+	FieldRejectShortDeadlines
+	FieldReconcileLateResults
+	FieldAbandonedPolicy
+	FieldPanicPolicy
+	FieldReentrancyPolicy
+	FieldCallEvents
+	FieldProfileLabels
+	FieldStaleWhileRevalidate
+)
+
+// unset sets the fields of o in fields to their zero value.
+func (fields OptionFields) unset(o *Options) {
+	if fields&FieldCoarseClock != 0 {
+		o.CoarseClock = false
+	}
+	if fields&FieldAlignBuckets != 0 {
+		o.AlignBuckets = false
+	}
+	if fields&FieldRateDecay != 0 {
+		o.RateDecay = DecayNone
+	}
+	if fields&FieldEmptyRate != 0 {
+		o.EmptyRate = EmptyRateZero
+	}
+	if fields&FieldRejectShortDeadlines != 0 {
+		o.RejectShortDeadlines = false
+	}
+	if fields&FieldReconcileLateResults != 0 {
+		o.ReconcileLateResults = false
+	}
+	if fields&FieldAbandonedPolicy != 0 {
+		o.AbandonedPolicy = AbandonedReject
+	}
+	if fields&FieldPanicPolicy != 0 {
+		o.PanicPolicy = PanicPropagate
+	}
+	if fields&FieldReentrancyPolicy != 0 {
+		o.ReentrancyPolicy = ReentrantAllow
+	}
+	if fields&FieldCallEvents != 0 {
+		o.CallEvents = false
+	}
+	if fields&FieldProfileLabels != 0 {
+		o.ProfileLabels = false
+	}
+	if fields&FieldStaleWhileRevalidate != 0 {
+		o.StaleWhileRevalidate = false
+	}
+}
+
+// WithTripFunc sets the breaker's ShouldTrip.
+func WithTripFunc(tf TripFunc) Option {
+	return func(o *Options) { o.ShouldTrip = tf }
+}
+
+// WithThreshold trips the breaker once there are threshold failures in its
+// window. See ThresholdTripFunc.
+func WithThreshold(threshold int64) Option {
+	return WithTripFunc(ThresholdTripFunc(threshold))
+}
+
+// WithConsecutive trips the breaker after threshold consecutive failures. See
+// ConsecutiveTripFunc.
+func WithConsecutive(threshold int64) Option {
+	return WithTripFunc(ConsecutiveTripFunc(threshold))
+}
+
+// WithRate trips the breaker when the error rate over its window reaches rate,
+// once there have been minSamples calls. See RateTripFunc.
+func WithRate(rate float64, minSamples int64) Option {
+	return WithTripFunc(RateTripFunc(rate, minSamples))
+}
+
+// WithCloseRate sets the error rate the window must fall below before the
+// tripped breaker closes. See Options.CloseRate.
+func WithCloseRate(rate float64) Option {
+	return func(o *Options) { o.CloseRate = rate }
+}
+
+// WithWindow sets the length of the breaker's window and the number of
+// buckets it is divided into.
+func WithWindow(d time.Duration, buckets int) Option {
+	return func(o *Options) {
+		o.WindowTime = d
+		o.WindowBuckets = buckets
+	}
+}
+
+// WithBackOff sets the backoff policy that spaces the breaker's trial calls.
+func WithBackOff(b backoff.BackOff) Option {
+	return func(o *Options) { o.BackOff = b }
+}
+
+// WithClock sets the breaker's source of time. See Options.Clock.
+func WithClock(c clock.Clock) Option {
+	return func(o *Options) { o.Clock = c }
+}
+
+// WithName sets the breaker's name, which is used in logs.
+func WithName(name string) Option {
+	return func(o *Options) { o.Name = name }
+}
+
+// WithLogger sets the Logger the breaker logs its events to.
+func WithLogger(l Logger) Option {
+	return func(o *Options) { o.Logger = l }
+}
+
+// WithParent makes the breaker a child of parent. See Options.Parent.
+func WithParent(parent *Breaker) Option {
+	return func(o *Options) { o.Parent = parent }
+}
+
+// WithSeed seeds the breaker's random choices with seed, making them
+// reproducible. See Options.Rand.
+func WithSeed(seed int64) Option {
+	return func(o *Options) { o.Rand = rand.NewSource(seed) }
+}
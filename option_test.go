@@ -0,0 +1,41 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestNew(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	parent := New()
+	cb := New(
+		WithThreshold(2),
+		WithWindow(time.Minute, 6),
+		WithClock(c),
+		WithName("db"),
+		WithParent(parent),
+		WithOptions(&Options{CallEvents: true}),
+	)
+	if cb.name != "db" || cb.Clock != c || cb.Parent() != parent || !cb.callEvents {
+		t.Fatal("expected the options to be applied")
+	}
+	if cb.counts.bucketTime != 10*time.Second || cb.counts.buckets.Len() != 6 {
+		t.Fatal("expected a 1m window of 6 buckets")
+	}
+
+	cb.Fail(errors.New("failed"))
+	cb.Fail(errors.New("failed"))
+	if !cb.Tripped() {
+		t.Fatal("expected the breaker to trip at its threshold")
+	}
+
+	cb = New(WithThreshold(1), WithOptions(&Options{ShouldTrip: ConsecutiveTripFunc(2)}))
+	cb.Fail(errors.New("failed"))
+	if cb.Tripped() {
+		t.Fatal("expected later options to override earlier ones")
+	}
+}
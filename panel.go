@@ -1,9 +1,16 @@
 package circuit
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/cenkalti/backoff"
 )
 
 var defaultStatsPrefixf = "circuit.%s"
@@ -15,10 +22,16 @@ type Statter interface {
 	Gauge(sampleRate float32, bucket string, value ...string)
 }
 
-// PanelEvent wraps a BreakerEvent and provides the string name of the breaker
+// PanelEvent wraps a BreakerEvent and provides the string name of the breaker.
+// Besides the events of its breakers, a Panel sends BreakerAdded and
+// BreakerRemoved events as breakers are added to and removed from it.
 type PanelEvent struct {
 	Name  string
 	Event BreakerEvent
+
+	// NextAttempt is when the breaker will allow a trial call. See
+	// Breaker.NextAttempt.
+	NextAttempt time.Time
 }
 
 // Panel tracks a group of circuit breakers by name.
@@ -26,12 +39,30 @@ type Panel struct {
 	Statter      Statter
 	StatsPrefixf string
 
+	// OutlierDetection configures Outliers. DefaultOutlierDetection is used if
+	// it is nil.
+	OutlierDetection *OutlierDetection
+
+	// Defaults are the options of breakers created by AddWithOptions, for
+	// the settings their own options leave unset.
+	Defaults *Options
+
+	// MaxTenants is the number of tenants ForTenant tracks breakers for.
+	// DefaultMaxTenants is used if it is 0.
+	MaxTenants int
+
 	Circuits map[string]*Breaker
 
 	lastTripTimes  map[string]time.Time
 	tripTimesLock  sync.RWMutex
 	panelLock      sync.RWMutex
-	eventReceivers []chan PanelEvent
+	addLock        sync.Mutex
+	eventReceivers []panelSubscription
+	unsubscribe    map[string]func()
+	names          map[*Breaker]string
+	dependencies   map[string][]string
+	tenants        tenantSet
+	rollup         *Breaker
 }
 
 // NewPanel creates a new Panel
@@ -43,19 +74,48 @@ func NewPanel() *Panel {
 		lastTripTimes: make(map[string]time.Time)}
 }
 
-// Add sets the name as a reference to the given circuit breaker.
+// Add sets the name as a reference to the given circuit breaker, replacing
+// any breaker already added under name.
 func (p *Panel) Add(name string, cb *Breaker) {
+	events, unsubscribe := cb.subscribe()
+
 	p.panelLock.Lock()
+	replaced, ok := p.Circuits[name]
 	p.Circuits[name] = cb
+	if p.unsubscribe == nil {
+		p.unsubscribe = make(map[string]func())
+	}
+	if ok {
+		p.unsubscribe[name]()
+	}
+	p.unsubscribe[name] = unsubscribe
+	if p.names == nil {
+		p.names = make(map[*Breaker]string)
+	}
+	if ok && p.names[replaced] == name {
+		delete(p.names, replaced)
+	}
+	p.names[cb] = name
+	if ok && replaced != cb && p.rollup != nil {
+		replaced.removeRollup(p.rollup)
+	}
+	if p.rollup != nil {
+		cb.addRollup(p.rollup)
+	}
 	p.panelLock.Unlock()
 
-	events := cb.Subscribe()
+	if ok && replaced != cb {
+		p.sendEvent(PanelEvent{Name: name, Event: BreakerRemoved})
+	}
+	p.sendEvent(PanelEvent{Name: name, Event: BreakerAdded})
 
 	go func() {
 		for event := range events {
-			for _, receiver := range p.eventReceivers {
-				receiver <- PanelEvent{name, event}
+			pe := PanelEvent{Name: name, Event: event}
+			if len(p.receivers()) > 0 {
+				pe.NextAttempt = cb.NextAttempt()
 			}
+			p.sendEvent(pe)
 			switch event {
 			case BreakerTripped:
 				p.breakerTripped(name)
@@ -70,6 +130,256 @@ func (p *Panel) Add(name string, cb *Breaker) {
 	}()
 }
 
+// getOrAdd returns the breaker added under name, adding one created with opts
+// if there is none. Concurrent callers asking for the same name get the same
+// breaker.
+func (p *Panel) getOrAdd(name string, opts *Options) *Breaker {
+	p.addLock.Lock()
+	defer p.addLock.Unlock()
+	cb, ok := p.Get(name)
+	if !ok {
+		cb = p.AddWithOptions(name, opts)
+	}
+	return cb
+}
+
+// Remove removes the breaker added under name, and reports whether there was
+// one. The panel stops reporting the breaker's events, and the breaker is
+// removed from its parent's children.
+func (p *Panel) Remove(name string) bool {
+	p.panelLock.Lock()
+	cb, ok := p.Circuits[name]
+	if ok {
+		delete(p.Circuits, name)
+		p.unsubscribe[name]()
+		delete(p.unsubscribe, name)
+		if p.names[cb] == name {
+			delete(p.names, cb)
+		}
+		if p.rollup != nil {
+			cb.removeRollup(p.rollup)
+		}
+	}
+	p.panelLock.Unlock()
+	if !ok {
+		return false
+	}
+	cb.detach()
+
+	p.tripTimesLock.Lock()
+	delete(p.lastTripTimes, name)
+	p.tripTimesLock.Unlock()
+
+	p.sendEvent(PanelEvent{Name: name, Event: BreakerRemoved})
+	return true
+}
+
+func (p *Panel) sendEvent(pe PanelEvent) {
+	for _, receiver := range p.receivers() {
+		select {
+		case receiver.events <- pe:
+		case <-receiver.done:
+		}
+	}
+}
+
+// receivers returns the panel's subscriptions.
+func (p *Panel) receivers() []panelSubscription {
+	p.panelLock.RLock()
+	defer p.panelLock.RUnlock()
+	return p.eventReceivers
+}
+
+// AddWithOptions creates a breaker, adds it under name and returns it. The
+// breaker is configured with opts, with unset fields taken from the panel's
+// Defaults, so that a fleet of breakers stays consistent while individual
+// breakers can still override settings. Options.Unset turns off boolean and
+// policy defaults. The breaker's Name defaults to name. opts may be nil.
+//
+// The panel's breakers share the default ShouldTrip, WeightFunc, Logger and
+// FaultInjector. A default exponential or constant BackOff is copied for each
+// breaker; other BackOff implementations are shared and must be safe for that.
+func (p *Panel) AddWithOptions(name string, opts *Options) *Breaker {
+	merged := mergeOptions(p.Defaults, opts)
+	if merged.Name == "" {
+		merged.Name = name
+	}
+	cb := NewBreakerWithOptions(merged)
+	p.Add(name, cb)
+	return cb
+}
+
+// mergeOptions returns a copy of defaults with the fields in overrides.Unset
+// cleared and the set fields of overrides applied. Either may be nil.
+func mergeOptions(defaults, overrides *Options) *Options {
+	var merged Options
+	if defaults != nil {
+		merged = *defaults
+		merged.BackOff = copyBackOff(defaults.BackOff)
+	}
+	merged.Unset = 0
+	if overrides == nil {
+		return &merged
+	}
+
+	overrides.Unset.unset(&merged)
+
+	if overrides.BackOff != nil {
+		merged.BackOff = overrides.BackOff
+	}
+	if overrides.Clock != nil {
+		merged.Clock = overrides.Clock
+	}
+	if overrides.ShouldTrip != nil {
+		merged.ShouldTrip = overrides.ShouldTrip
+	}
+	if overrides.WindowTime != 0 {
+		merged.WindowTime = overrides.WindowTime
+	}
+	if overrides.WindowBuckets != 0 {
+		merged.WindowBuckets = overrides.WindowBuckets
+	}
+	if overrides.CoarseClock {
+		merged.CoarseClock = true
+	}
+	if overrides.AlignBuckets {
+		merged.AlignBuckets = true
+	}
+	if overrides.BucketRollover != nil {
+		merged.BucketRollover = overrides.BucketRollover
+	}
+	if overrides.RateDecay != DecayNone {
+		merged.RateDecay = overrides.RateDecay
+	}
+	if overrides.DecayFactor != 0 {
+		merged.DecayFactor = overrides.DecayFactor
+	}
+	if overrides.EmptyRate != EmptyRateZero {
+		merged.EmptyRate = overrides.EmptyRate
+	}
+	if overrides.TripCheckInterval != 0 {
+		merged.TripCheckInterval = overrides.TripCheckInterval
+	}
+	if overrides.TLSTripThreshold != 0 {
+		merged.TLSTripThreshold = overrides.TLSTripThreshold
+	}
+	if overrides.ConsecutivePolicy != nil {
+		merged.ConsecutivePolicy = overrides.ConsecutivePolicy
+	}
+	if overrides.WeightFunc != nil {
+		merged.WeightFunc = overrides.WeightFunc
+	}
+	if overrides.BackOffResetAfter != 0 {
+		merged.BackOffResetAfter = overrides.BackOffResetAfter
+	}
+	if overrides.RejectShortDeadlines {
+		merged.RejectShortDeadlines = true
+	}
+	if overrides.ProbeTimeout != 0 {
+		merged.ProbeTimeout = overrides.ProbeTimeout
+	}
+	if overrides.MaxProbes != 0 {
+		merged.MaxProbes = overrides.MaxProbes
+	}
+	if overrides.ReconcileLateResults {
+		merged.ReconcileLateResults = true
+	}
+	if overrides.Rand != nil {
+		merged.Rand = overrides.Rand
+	}
+	if overrides.MinOpenDuration != 0 {
+		merged.MinOpenDuration = overrides.MinOpenDuration
+	}
+	if overrides.MinClosedDuration != 0 {
+		merged.MinClosedDuration = overrides.MinClosedDuration
+	}
+	if overrides.PressureConcurrency != 0 {
+		merged.PressureConcurrency = overrides.PressureConcurrency
+	}
+	if overrides.QueueSize != 0 {
+		merged.QueueSize = overrides.QueueSize
+	}
+	if overrides.QueueTimeout != 0 {
+		merged.QueueTimeout = overrides.QueueTimeout
+	}
+	if overrides.Categorize != nil {
+		merged.Categorize = overrides.Categorize
+	}
+	if overrides.BucketExtensions != nil {
+		merged.BucketExtensions = overrides.BucketExtensions
+	}
+	if overrides.AdaptiveTimeout != nil {
+		merged.AdaptiveTimeout = overrides.AdaptiveTimeout
+	}
+	if overrides.MaxAbandoned != 0 {
+		merged.MaxAbandoned = overrides.MaxAbandoned
+	}
+	if overrides.AbandonedPolicy != AbandonedReject {
+		merged.AbandonedPolicy = overrides.AbandonedPolicy
+	}
+	if overrides.PanicPolicy != PanicPropagate {
+		merged.PanicPolicy = overrides.PanicPolicy
+	}
+	if overrides.ReentrancyPolicy != ReentrantAllow {
+		merged.ReentrancyPolicy = overrides.ReentrancyPolicy
+	}
+	if overrides.CloseRate != 0 {
+		merged.CloseRate = overrides.CloseRate
+	}
+	if overrides.Canary != nil {
+		merged.Canary = overrides.Canary
+	}
+	if overrides.SLA != nil {
+		merged.SLA = overrides.SLA
+	}
+	if overrides.FaultInjector != nil {
+		merged.FaultInjector = overrides.FaultInjector
+	}
+	if overrides.CallEvents {
+		merged.CallEvents = true
+	}
+	if overrides.ProfileLabels {
+		merged.ProfileLabels = true
+	}
+	if overrides.Cache != nil {
+		merged.Cache = overrides.Cache
+	}
+	if overrides.StaleWhileRevalidate {
+		merged.StaleWhileRevalidate = true
+	}
+	if overrides.EventQueue != 0 {
+		merged.EventQueue = overrides.EventQueue
+	}
+	if overrides.EventReplay != 0 {
+		merged.EventReplay = overrides.EventReplay
+	}
+	if overrides.Parent != nil {
+		merged.Parent = overrides.Parent
+	}
+	if overrides.Logger != nil {
+		merged.Logger = overrides.Logger
+	}
+	if overrides.Name != "" {
+		merged.Name = overrides.Name
+	}
+	return &merged
+}
+
+// copyBackOff returns a copy of b in its initial state if b is one of the
+// backoff package's stateful policies, and b itself otherwise.
+func copyBackOff(b backoff.BackOff) backoff.BackOff {
+	switch b := b.(type) {
+	case *backoff.ExponentialBackOff:
+		c := *b
+		c.Reset()
+		return &c
+	case *backoff.ConstantBackOff:
+		c := *b
+		return &c
+	}
+	return b
+}
+
 // Get retrieves a circuit breaker by name.  If no circuit breaker exists, it
 // returns the NoOp one and sets ok to false.
 func (p *Panel) Get(name string) (*Breaker, bool) {
@@ -84,14 +394,81 @@ func (p *Panel) Get(name string) (*Breaker, bool) {
 	return NewBreaker(), ok
 }
 
+// Range calls fn with each breaker in the panel in order of name, until fn
+// returns false. It ranges over a snapshot taken when it is called, so fn may
+// add and remove breakers, and changes made meanwhile are not seen. Unlike
+// ranging over Circuits, it is safe while breakers are added concurrently.
+func (p *Panel) Range(fn func(name string, cb *Breaker) bool) {
+	p.panelLock.RLock()
+	names := make([]string, 0, len(p.Circuits))
+	circuits := make(map[string]*Breaker, len(p.Circuits))
+	for name, cb := range p.Circuits {
+		names = append(names, name)
+		circuits[name] = cb
+	}
+	p.panelLock.RUnlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		if !fn(name, circuits[name]) {
+			return
+		}
+	}
+}
+
+// Stats returns a snapshot of the Stats of every breaker in the panel, keyed by
+// name.
+func (p *Panel) Stats() map[string]Stats {
+	p.panelLock.RLock()
+	defer p.panelLock.RUnlock()
+
+	stats := make(map[string]Stats, len(p.Circuits))
+	for name, cb := range p.Circuits {
+		stats[name] = cb.Stats()
+	}
+	return stats
+}
+
 // Subscribe returns a channel of PanelEvents. Whenever a breaker changes state,
 // the PanelEvent will be sent over the channel. See BreakerEvent for the types of events.
 func (p *Panel) Subscribe() <-chan PanelEvent {
-	eventReader := make(chan PanelEvent)
+	output, _ := p.subscribe()
+	return output
+}
+
+// SubscribeWithCancel is Subscribe, but also returns a function that ends the
+// subscription and closes the channel. Callers that stop reading events, such
+// as a loop that returns when its context is done, should call it so that the
+// panel stops sending to the subscription.
+func (p *Panel) SubscribeWithCancel() (<-chan PanelEvent, func()) {
+	return p.subscribe()
+}
+
+// panelSubscription is a subscription to a panel's events. Events are sent on
+// events until done is closed.
+type panelSubscription struct {
+	events chan PanelEvent
+	done   chan struct{}
+}
+
+// subscribe is Subscribe, but also returns a function that ends the
+// subscription and closes the channel.
+func (p *Panel) subscribe() (<-chan PanelEvent, func()) {
+	sub := panelSubscription{
+		events: make(chan PanelEvent),
+		done:   make(chan struct{}),
+	}
 	output := make(chan PanelEvent, 100)
 
 	go func() {
-		for v := range eventReader {
+		defer close(output)
+		for {
+			var v PanelEvent
+			select {
+			case v = <-sub.events:
+			case <-sub.done:
+				return
+			}
 			select {
 			case output <- v:
 			default:
@@ -100,12 +477,302 @@ func (p *Panel) Subscribe() <-chan PanelEvent {
 			}
 		}
 	}()
-	p.eventReceivers = append(p.eventReceivers, eventReader)
-	return output
+	p.panelLock.Lock()
+	p.eventReceivers = append(p.eventReceivers, sub)
+	p.panelLock.Unlock()
+
+	unsubscribe := func() {
+		p.panelLock.Lock()
+		defer p.panelLock.Unlock()
+		for i, receiver := range p.eventReceivers {
+			if receiver == sub {
+				// Copy rather than shift in place, as sendEvent may be
+				// ranging over the old slice.
+				receivers := make([]panelSubscription, 0, len(p.eventReceivers)-1)
+				receivers = append(receivers, p.eventReceivers[:i]...)
+				p.eventReceivers = append(receivers, p.eventReceivers[i+1:]...)
+				close(sub.done)
+				return
+			}
+		}
+	}
+	return output, unsubscribe
+}
+
+// OutlierDetection configures how Panel.Outliers finds breakers whose error
+// rate is anomalous relative to their peers.
+type OutlierDetection struct {
+	// MinSamples is the number of calls a breaker must have in its window to
+	// take part in detection.
+	MinSamples int64
+
+	// MinPeers is the number of breakers that must take part for detection to
+	// run at all; with fewer, the statistics are not meaningful.
+	MinPeers int
+
+	// StdDevFactor controls sensitivity. A breaker is an outlier if its error
+	// rate exceeds the mean error rate of its peers by more than StdDevFactor
+	// standard deviations.
+	StdDevFactor float64
+
+	// Eject trips outliers when they are found, preemptively moving traffic
+	// off them. Tripped breakers recover through their normal backoff.
+	Eject bool
+}
+
+// DefaultOutlierDetection mirrors Envoy's success rate outlier detection
+// defaults.
+var DefaultOutlierDetection = OutlierDetection{
+	MinSamples:   100,
+	MinPeers:     5,
+	StdDevFactor: 1.9,
+}
+
+// Outliers returns the names of the breakers whose error rate is statistically
+// anomalous relative to the other breakers in the panel, such as the backend
+// in one bad availability zone. Only breakers that are not tripped and have
+// enough samples take part. If OutlierDetection.Eject is set, the outliers are
+// tripped.
+func (p *Panel) Outliers() []string {
+	od := p.OutlierDetection
+	if od == nil {
+		od = &DefaultOutlierDetection
+	}
+
+	type peer struct {
+		name string
+		cb   *Breaker
+		rate float64
+	}
+	var peers []peer
+	var sum float64
+
+	p.panelLock.RLock()
+	for name, cb := range p.Circuits {
+		if cb.Tripped() || cb.Failures()+cb.Successes() < od.MinSamples {
+			continue
+		}
+		rate := cb.ErrorRate()
+		if math.IsNaN(rate) {
+			continue
+		}
+		peers = append(peers, peer{name, cb, rate})
+		sum += rate
+	}
+	p.panelLock.RUnlock()
+
+	if len(peers) == 0 || len(peers) < od.MinPeers {
+		return nil
+	}
+
+	mean := sum / float64(len(peers))
+	var variance float64
+	for _, pr := range peers {
+		variance += (pr.rate - mean) * (pr.rate - mean)
+	}
+	threshold := mean + od.StdDevFactor*math.Sqrt(variance/float64(len(peers)))
+
+	var outliers []string
+	for _, pr := range peers {
+		if pr.rate > threshold {
+			outliers = append(outliers, pr.name)
+			if od.Eject {
+				pr.cb.Trip()
+			}
+		}
+	}
+	sort.Strings(outliers)
+	return outliers
+}
+
+// breakerJSON is the JSON representation of a breaker in a Panel.
+type breakerJSON struct {
+	breakerConfig
+	Stats  Stats       `json:"stats"`
+	Window *windowJSON `json:"window,omitempty"`
+}
+
+// breakerConfig is the JSON representation of a breaker's effective Options.
+// Only options that are plain values are encoded. Functions, interfaces and
+// policies held by pointer, such as ShouldTrip, BackOff, Clock, Cache, Canary,
+// SLA and Parent, as well as RateDecay and CoarseClock, are not.
+type breakerConfig struct {
+	Name                 string             `json:"name,omitempty"`
+	WindowTime           time.Duration      `json:"window_time"`
+	WindowBuckets        int                `json:"window_buckets"`
+	AlignBuckets         bool               `json:"align_buckets,omitempty"`
+	EmptyRate            EmptyRate          `json:"empty_rate,omitempty"`
+	CloseRate            float64            `json:"close_rate,omitempty"`
+	TripCheckInterval    time.Duration      `json:"trip_check_interval,omitempty"`
+	TLSTripThreshold     int64              `json:"tls_trip_threshold,omitempty"`
+	ConsecutivePolicy    *ConsecutivePolicy `json:"consecutive_policy,omitempty"`
+	BackOffResetAfter    time.Duration      `json:"backoff_reset_after,omitempty"`
+	RejectShortDeadlines bool               `json:"reject_short_deadlines,omitempty"`
+	ProbeTimeout         time.Duration      `json:"probe_timeout,omitempty"`
+	MaxProbes            int                `json:"max_probes,omitempty"`
+	MinOpenDuration      time.Duration      `json:"min_open_duration,omitempty"`
+	MinClosedDuration    time.Duration      `json:"min_closed_duration,omitempty"`
+	PressureConcurrency  int                `json:"pressure_concurrency,omitempty"`
+	QueueSize            int                `json:"queue_size,omitempty"`
+	QueueTimeout         time.Duration      `json:"queue_timeout,omitempty"`
+	ReconcileLateResults bool               `json:"reconcile_late_results,omitempty"`
+	MaxAbandoned         int64              `json:"max_abandoned,omitempty"`
+	AbandonedPolicy      AbandonedPolicy    `json:"abandoned_policy,omitempty"`
+	PanicPolicy          PanicPolicy        `json:"panic_policy,omitempty"`
+	ReentrancyPolicy     ReentrancyPolicy   `json:"reentrancy_policy,omitempty"`
+	CallEvents           bool               `json:"call_events,omitempty"`
+	ProfileLabels        bool               `json:"profile_labels,omitempty"`
+	StaleWhileRevalidate bool               `json:"stale_while_revalidate,omitempty"`
+	EventQueue           int                `json:"event_queue,omitempty"`
+	EventReplay          int                `json:"event_replay,omitempty"`
+}
+
+// config returns the encodable part of the breaker's effective Options.
+func (cb *Breaker) config() breakerConfig {
+	policy := cb.consecPolicy
+	return breakerConfig{
+		Name:                 cb.name,
+		WindowTime:           cb.counts.bucketTime * time.Duration(cb.counts.buckets.Len()),
+		WindowBuckets:        cb.counts.buckets.Len(),
+		AlignBuckets:         cb.counts.aligned,
+		EmptyRate:            cb.emptyRate,
+		CloseRate:            cb.closeRate,
+		TripCheckInterval:    cb.tripCheck,
+		TLSTripThreshold:     cb.tlsThreshold,
+		ConsecutivePolicy:    &policy,
+		BackOffResetAfter:    cb.backOffReset,
+		RejectShortDeadlines: cb.rejectShort,
+		ProbeTimeout:         cb.probeTimeout,
+		MaxProbes:            int(cb.maxProbes),
+		MinOpenDuration:      cb.minOpen,
+		MinClosedDuration:    cb.minClosed,
+		PressureConcurrency:  int(cb.pressureConc),
+		QueueSize:            int(cb.queueSize),
+		QueueTimeout:         cb.queueTimeout,
+		ReconcileLateResults: cb.reconcile,
+		MaxAbandoned:         cb.maxAbandoned,
+		AbandonedPolicy:      cb.onAbandon,
+		PanicPolicy:          cb.onPanic,
+		ReentrancyPolicy:     cb.onReentry,
+		CallEvents:           cb.callEvents,
+		ProfileLabels:        cb.profLabels,
+		StaleWhileRevalidate: cb.revalidate,
+		EventQueue:           cap(cb.eventQueue),
+		EventReplay:          cb.replaySize,
+	}
+}
+
+// options returns Options configuring a breaker as c describes.
+func (c breakerConfig) options() *Options {
+	return &Options{
+		Name:                 c.Name,
+		WindowTime:           c.WindowTime,
+		WindowBuckets:        c.WindowBuckets,
+		AlignBuckets:         c.AlignBuckets,
+		EmptyRate:            c.EmptyRate,
+		CloseRate:            c.CloseRate,
+		TripCheckInterval:    c.TripCheckInterval,
+		TLSTripThreshold:     c.TLSTripThreshold,
+		ConsecutivePolicy:    c.ConsecutivePolicy,
+		BackOffResetAfter:    c.BackOffResetAfter,
+		RejectShortDeadlines: c.RejectShortDeadlines,
+		ProbeTimeout:         c.ProbeTimeout,
+		MaxProbes:            c.MaxProbes,
+		MinOpenDuration:      c.MinOpenDuration,
+		MinClosedDuration:    c.MinClosedDuration,
+		PressureConcurrency:  c.PressureConcurrency,
+		QueueSize:            c.QueueSize,
+		QueueTimeout:         c.QueueTimeout,
+		ReconcileLateResults: c.ReconcileLateResults,
+		MaxAbandoned:         c.MaxAbandoned,
+		AbandonedPolicy:      c.AbandonedPolicy,
+		PanicPolicy:          c.PanicPolicy,
+		ReentrancyPolicy:     c.ReentrancyPolicy,
+		CallEvents:           c.CallEvents,
+		ProfileLabels:        c.ProfileLabels,
+		StaleWhileRevalidate: c.StaleWhileRevalidate,
+		EventQueue:           c.EventQueue,
+		EventReplay:          c.EventReplay,
+	}
+}
+
+type panelJSON struct {
+	Breakers map[string]breakerJSON `json:"breakers"`
+}
+
+// MarshalJSON implements json.Marshaler. It encodes the configuration, the
+// contents of the window and the current Stats of every breaker in the panel,
+// so that state can be dumped for debugging or diffed between instances, or
+// handed off to a new process on restart. The configuration is the effective
+// value of each Options field that is a plain value, such as ProbeTimeout,
+// MaxProbes and PanicPolicy; functions, interfaces and pointers to policies,
+// such as ShouldTrip, BackOff and Canary, are not encoded.
+func (p *Panel) MarshalJSON() ([]byte, error) {
+	pj := panelJSON{Breakers: make(map[string]breakerJSON)}
+
+	p.panelLock.RLock()
+	for name, cb := range p.Circuits {
+		pj.Breakers[name] = breakerJSON{
+			breakerConfig: cb.config(),
+			Stats:         cb.Stats(),
+			Window:        cb.counts.Snapshot(),
+		}
+	}
+	p.panelLock.RUnlock()
+
+	return json.Marshal(pj)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It restores the state and
+// statistics of each encoded breaker. Breakers missing from the panel are
+// created with the encoded configuration and added. Restoring a breaker
+// does not send events. The contents of the window are restored bucket by
+// bucket if the breaker has as many buckets as were encoded; otherwise the
+// failures and successes are restored into the current bucket.
+func (p *Panel) UnmarshalJSON(data []byte) error {
+	var pj panelJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+
+	// Allow decoding into a zero Panel.
+	p.panelLock.Lock()
+	if p.Circuits == nil {
+		p.Circuits = make(map[string]*Breaker)
+	}
+	if p.Statter == nil {
+		p.Statter = &noopStatter{}
+	}
+	if p.StatsPrefixf == "" {
+		p.StatsPrefixf = defaultStatsPrefixf
+	}
+	p.panelLock.Unlock()
+	p.tripTimesLock.Lock()
+	if p.lastTripTimes == nil {
+		p.lastTripTimes = make(map[string]time.Time)
+	}
+	p.tripTimesLock.Unlock()
+
+	for name, bj := range pj.Breakers {
+		cb, ok := p.Get(name)
+		if !ok {
+			cb = NewBreakerWithOptions(bj.options())
+			p.Add(name, cb)
+		}
+		cb.restoreStats(bj.Stats)
+		if bj.Window != nil {
+			cb.counts.Restore(bj.Window)
+		}
+	}
+	return nil
 }
 
 func (p *Panel) breakerTripped(name string) {
 	p.Statter.Counter(1.0, fmt.Sprintf(p.StatsPrefixf, name)+".tripped", 1)
+	if cb, ok := p.Get(name); ok {
+		trips := atomic.LoadInt64(&cb.trips)
+		p.Statter.Gauge(1.0, fmt.Sprintf(p.StatsPrefixf, name)+".trips", strconv.FormatInt(trips, 10))
+	}
 	p.tripTimesLock.Lock()
 	p.lastTripTimes[name] = time.Now()
 	p.tripTimesLock.Unlock()
@@ -120,6 +787,11 @@ func (p *Panel) breakerReset(name string) {
 	lastTrip := p.lastTripTimes[name]
 	p.tripTimesLock.RUnlock()
 
+	if cb, ok := p.Get(name); ok {
+		mean := mttr(time.Duration(atomic.LoadInt64(&cb.timeOpen)), atomic.LoadInt64(&cb.recoveries))
+		p.Statter.Gauge(1.0, bucket+".mttr", strconv.FormatInt(int64(mean/time.Millisecond), 10))
+	}
+
 	if !lastTrip.IsZero() {
 		p.Statter.Timing(1.0, bucket+".trip-time", time.Since(lastTrip))
 		p.tripTimesLock.Lock()
@@ -136,6 +808,20 @@ func (p *Panel) breakerReady(name string) {
 	p.Statter.Counter(1.0, fmt.Sprintf(p.StatsPrefixf, name)+".ready", 1)
 }
 
+// nameOf returns the name cb was added under. Rejections are not breaker
+// events, so the breakers rejecting calls are looked up by identity.
+func (p *Panel) nameOf(cb *Breaker) (string, bool) {
+	p.panelLock.RLock()
+	defer p.panelLock.RUnlock()
+	name, ok := p.names[cb]
+	return name, ok
+}
+
+// breakerRejected records a call rejected by the breaker added under name.
+func (p *Panel) breakerRejected(name string) {
+	p.Statter.Counter(1.0, fmt.Sprintf(p.StatsPrefixf, name)+".rejected", 1)
+}
+
 type noopStatter struct {
 }
 
@@ -1,10 +1,14 @@
 package circuit
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/cenkalti/backoff"
 )
 
 func TestPanelGet(t *testing.T) {
@@ -36,6 +40,91 @@ func TestPanelAdd(t *testing.T) {
 	}
 }
 
+func TestPanelAddRemoveEvents(t *testing.T) {
+	p := NewPanel()
+	events := p.Subscribe()
+	next := func() PanelEvent {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a panel event")
+		}
+		return PanelEvent{}
+	}
+
+	rb := NewBreaker()
+	p.Add("a", rb)
+	if e := next(); e.Name != "a" || e.Event != BreakerAdded {
+		t.Fatalf("expected BreakerAdded, got %v", e)
+	}
+	rb.Trip()
+	if e := next(); e.Event != BreakerTripped {
+		t.Fatalf("expected BreakerTripped, got %v", e)
+	}
+
+	if p.Remove("missing") {
+		t.Fatal("expected removing a missing breaker to fail")
+	}
+	if !p.Remove("a") {
+		t.Fatal("expected the breaker to be removed")
+	}
+	if e := next(); e.Name != "a" || e.Event != BreakerRemoved {
+		t.Fatalf("expected BreakerRemoved, got %v", e)
+	}
+	if _, ok := p.Get("a"); ok {
+		t.Fatal("expected the breaker to be gone")
+	}
+
+	rb.Reset()
+	select {
+	case e := <-events:
+		t.Fatalf("expected no events from a removed breaker, got %v", e)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestPanelSubscribeWithCancel(t *testing.T) {
+	p := NewPanel()
+	events, unsubscribe := p.SubscribeWithCancel()
+	p.Add("a", NewBreaker())
+	if e := <-events; e.Name != "a" || e.Event != BreakerAdded {
+		t.Fatalf("expected BreakerAdded, got %v", e)
+	}
+
+	unsubscribe()
+	p.Add("b", NewBreaker())
+	select {
+	case e, ok := <-events:
+		if ok {
+			t.Fatalf("expected no events after unsubscribing, got %v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the channel to be closed")
+	}
+}
+
+func TestPanelRange(t *testing.T) {
+	p := NewPanel()
+	for _, name := range []string{"c", "a", "b"} {
+		p.Add(name, NewBreaker())
+	}
+
+	var names []string
+	p.Range(func(name string, cb *Breaker) bool {
+		names = append(names, name)
+		p.Remove("c")
+		p.Add(name+"2", NewBreaker())
+		return name != "b"
+	})
+	if want := []string{"a", "b"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("expected to range over %v, got %v", want, names)
+	}
+	if _, ok := p.Get("a2"); !ok {
+		t.Fatal("expected fn to be able to add breakers")
+	}
+}
+
 func TestPanelStats(t *testing.T) {
 	statter := newTestStatter()
 	p := NewPanel()
@@ -111,3 +200,173 @@ func (s *testStatter) Timing(sampleRate float32, bucket string, d ...time.Durati
 }
 
 func (*testStatter) Gauge(sampleRate float32, bucket string, value ...string) {}
+
+func TestPanelOutliers(t *testing.T) {
+	p := NewPanel()
+	p.OutlierDetection = &OutlierDetection{MinSamples: 10, MinPeers: 3, StdDevFactor: 1.5}
+
+	for i, failures := range []int{1, 0, 2, 1, 9} {
+		cb := NewBreaker()
+		for j := 0; j < 10; j++ {
+			if j < failures {
+				cb.Fail(nil)
+			} else {
+				cb.Success()
+			}
+		}
+		p.Add(fmt.Sprintf("az%d", i), cb)
+	}
+	// Not enough samples to take part.
+	sparse := NewBreaker()
+	sparse.Fail(nil)
+	p.Add("sparse", sparse)
+
+	if outliers := p.Outliers(); !reflect.DeepEqual(outliers, []string{"az4"}) {
+		t.Fatalf("expected az4 to be the only outlier, got %v", outliers)
+	}
+	if cb, _ := p.Get("az4"); cb.Tripped() {
+		t.Fatal("expected outlier not to be ejected by default")
+	}
+
+	p.OutlierDetection.Eject = true
+	p.Outliers()
+	if cb, _ := p.Get("az4"); !cb.Tripped() {
+		t.Fatal("expected outlier to be ejected")
+	}
+	if outliers := p.Outliers(); len(outliers) != 0 {
+		t.Fatalf("expected ejected breaker not to take part, got %v", outliers)
+	}
+}
+
+func TestPanelJSON(t *testing.T) {
+	p := NewPanel()
+	a := NewBreakerWithOptions(&Options{
+		WindowTime:    time.Minute,
+		WindowBuckets: 6,
+		ProbeTimeout:  time.Second,
+		MaxProbes:     3,
+		PanicPolicy:   PanicRecover,
+		CallEvents:    true,
+		EventReplay:   10,
+	})
+	a.Fail(nil)
+	a.Fail(nil)
+	a.Success()
+	b := NewBreaker()
+	b.Break()
+	p.Add("a", a)
+	p.Add("b", b)
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var restored Panel
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+
+	ra, ok := restored.Get("a")
+	if !ok {
+		t.Fatal("expected breaker a to be restored")
+	}
+	if got, want := ra.Stats(), a.Stats(); got.Failures != want.Failures ||
+		got.Successes != want.Successes || got.ConsecFailures != want.ConsecFailures ||
+		!got.LastFailure.Equal(want.LastFailure) {
+		t.Fatalf("expected restored stats %+v, got %+v", want, got)
+	}
+	if wt := ra.counts.bucketTime * time.Duration(ra.counts.buckets.Len()); wt != time.Minute {
+		t.Fatalf("expected restored window time of 1m, got %v", wt)
+	}
+	if got, want := ra.config(), a.config(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected restored options %+v, got %+v", want, got)
+	}
+	if ra.probeTimeout != time.Second || ra.maxProbes != 3 || ra.onPanic != PanicRecover || !ra.callEvents {
+		t.Fatal("expected the breaker to be recreated with the encoded options")
+	}
+	var encoded struct {
+		Breakers map[string]map[string]interface{} `json:"breakers"`
+	}
+	json.Unmarshal(data, &encoded)
+	if v := encoded.Breakers["a"]["max_probes"]; v != 3.0 {
+		t.Fatalf("expected the effective options to be encoded, got max_probes %v", v)
+	}
+	if v := encoded.Breakers["b"]["max_probes"]; v != 1.0 {
+		t.Fatalf("expected defaulted options to be encoded, got max_probes %v", v)
+	}
+
+	rb, _ := restored.Get("b")
+	if !rb.Tripped() || rb.Ready() {
+		t.Fatal("expected broken breaker to be restored broken")
+	}
+	if got, want := rb.Stats(), b.Stats(); got.Trips != want.Trips || !got.TrippedAt.Equal(want.TrippedAt) {
+		t.Fatalf("expected restored trip stats %+v, got %+v", want, got)
+	}
+}
+
+func TestPanelAddWithOptions(t *testing.T) {
+	p := NewPanel()
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = time.Minute
+	p.Defaults = &Options{
+		BackOff:       b,
+		ShouldTrip:    ConsecutiveTripFunc(2),
+		WindowTime:    time.Minute,
+		WindowBuckets: 6,
+	}
+
+	a := p.AddWithOptions("a", nil)
+	c := p.AddWithOptions("c", &Options{ShouldTrip: ConsecutiveTripFunc(1)})
+	if got, _ := p.Get("a"); got != a {
+		t.Fatal("expected breaker to be added to the panel")
+	}
+	if a.BackOff == c.BackOff || a.BackOff == b {
+		t.Fatal("expected each breaker to get its own copy of the default backoff")
+	}
+	if a.nextBackOff < 30*time.Second {
+		t.Fatalf("expected the default backoff to be used, got %v", a.nextBackOff)
+	}
+	if wt := a.counts.bucketTime * time.Duration(a.counts.buckets.Len()); wt != time.Minute {
+		t.Fatalf("expected the default window time, got %v", wt)
+	}
+	if a.name != "a" {
+		t.Fatalf("expected the breaker to be named after its key, got %q", a.name)
+	}
+
+	c.Fail(nil)
+	a.Fail(nil)
+	if !c.Tripped() || a.Tripped() {
+		t.Fatal("expected the override to apply only to its breaker")
+	}
+}
+
+func TestMergeOptionsUnset(t *testing.T) {
+	defaults := &Options{
+		CallEvents:           true,
+		RejectShortDeadlines: true,
+		PanicPolicy:          PanicRecover,
+		ReentrancyPolicy:     ReentrantReject,
+	}
+	merged := mergeOptions(defaults, &Options{
+		Unset:            FieldCallEvents | FieldPanicPolicy | FieldReentrancyPolicy,
+		ReentrancyPolicy: ReentrantReject,
+	})
+	if merged.CallEvents || merged.PanicPolicy != PanicPropagate {
+		t.Fatalf("expected the unset fields to be turned off, got %+v", merged)
+	}
+	if !merged.RejectShortDeadlines {
+		t.Fatal("expected the fields not unset to keep their defaults")
+	}
+	if merged.ReentrancyPolicy != ReentrantReject {
+		t.Fatal("expected a set field to take precedence over Unset")
+	}
+	if merged.Unset != 0 || !defaults.CallEvents {
+		t.Fatal("expected the defaults to be left alone")
+	}
+
+	cb := New(WithOptions(defaults), WithOptions(&Options{Unset: FieldPanicPolicy}))
+	if cb.onPanic != PanicPropagate {
+		t.Fatalf("expected WithOptions to unset fields, got %v", cb.onPanic)
+	}
+}
@@ -0,0 +1,58 @@
+package circuit
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicPolicy is what Call does when the function it runs panics. See
+// Options.PanicPolicy.
+type PanicPolicy int
+
+const (
+	// PanicPropagate lets panics propagate untouched, without recording
+	// anything. A panic in a call with a timeout, which runs in its own
+	// goroutine, crashes the program.
+	PanicPropagate PanicPolicy = iota
+
+	// PanicRecover recovers panics, records them as failures and returns
+	// them as a *PanicError.
+	PanicRecover
+
+	// PanicRepanic records panics as failures and then panics again with the
+	// same value in the goroutine that made the call, even for calls with a
+	// timeout. Panics in calls that have already timed out are dropped.
+	PanicRepanic
+)
+
+// PanicError is the error of a call that panicked, under PanicRecover or
+// PanicRepanic. It is the error of the call's BreakerFail event, and the
+// trip cause if the panic trips the breaker.
+type PanicError struct {
+	// Value is the value the call panicked with.
+	Value interface{}
+	// Stack is the stack trace of the panic.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("circuit: call panicked: %v", e.Value)
+}
+
+// Unwrap returns Value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverPanics returns circuit, but returning a *PanicError when it panics.
+func recoverPanics(circuit func() error) func() error {
+	return func() (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = &PanicError{Value: v, Stack: debug.Stack()}
+			}
+		}()
+		return circuit()
+	}
+}
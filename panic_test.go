@@ -0,0 +1,71 @@
+package circuit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPanicRecover(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{PanicPolicy: PanicRecover, ShouldTrip: ThresholdTripFunc(1)})
+	listener := make(chan ListenerEvent, 10)
+	cb.AddListener(listener)
+
+	for _, timeout := range []time.Duration{0, time.Second} {
+		err := cb.Call(func() error { panic("boom") }, timeout)
+		var pe *PanicError
+		if !errors.As(err, &pe) || pe.Value != "boom" {
+			t.Fatalf("expected a PanicError for boom, got %v", err)
+		}
+		if !strings.Contains(string(pe.Stack), "panic_test.go") {
+			t.Fatalf("expected the stack of the panic, got %s", pe.Stack)
+		}
+		cb.Reset()
+	}
+
+	for e := range listener {
+		if e.Event == BreakerFail {
+			if pe, ok := e.Err.(*PanicError); !ok || pe.Value != "boom" {
+				t.Fatalf("expected the failure event to carry the panic, got %v", e.Err)
+			}
+			break
+		}
+	}
+
+	inner := errors.New("inner")
+	err := cb.Call(func() error { panic(inner) }, 0)
+	if !errors.Is(err, inner) {
+		t.Fatalf("expected the PanicError to wrap the error panicked with, got %v", err)
+	}
+}
+
+func TestPanicRepanic(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{PanicPolicy: PanicRepanic})
+	for _, timeout := range []time.Duration{0, time.Second} {
+		func() {
+			defer func() {
+				if v := recover(); v != "boom" {
+					t.Fatalf("expected the call to panic again with boom, got %v", v)
+				}
+			}()
+			cb.Call(func() error { panic("boom") }, timeout)
+		}()
+	}
+	if n := cb.Failures(); n != 2 {
+		t.Fatalf("expected 2 failures, got %d", n)
+	}
+}
+
+func TestPanicPropagate(t *testing.T) {
+	cb := NewBreaker()
+	defer func() {
+		if v := recover(); v != "boom" {
+			t.Fatalf("expected the panic to propagate, got %v", v)
+		}
+		if cb.Failures() != 0 {
+			t.Fatal("expected the panic not to be recorded")
+		}
+	}()
+	cb.Call(func() error { panic("boom") }, 0)
+}
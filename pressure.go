@@ -0,0 +1,61 @@
+package circuit
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Pressure returns how hard the dependency the breaker protects is pressed,
+// from 0 for an idle, healthy one to 1 for one that cannot take more calls:
+// 1 while the breaker is tripped or unavailable, and otherwise the greater
+// of the error rate over the window and, with Options.PressureConcurrency,
+// the fraction of it the calls in flight take up. It is kept up to date as
+// calls are made and read with a single atomic load, so servers can consult
+// it on every request to shed inbound load in proportion, or to set
+// Retry-After and RateLimit headers:
+//
+//	if rand.Float64() < cb.Pressure() {
+//		w.Header().Set("Retry-After", "1")
+//		w.WriteHeader(http.StatusServiceUnavailable)
+//		return
+//	}
+func (cb *Breaker) Pressure() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&cb.pressure))
+}
+
+// InFlight returns the number of calls made by Call and CallContext that
+// have not yet returned or timed out.
+func (cb *Breaker) InFlight() int64 {
+	return atomic.LoadInt64(&cb.inflight)
+}
+
+// enter counts a call as in flight and exit as no longer.
+func (cb *Breaker) enter() {
+	atomic.AddInt64(&cb.inflight, 1)
+	if cb.pressureConc > 0 {
+		cb.updatePressure()
+	}
+}
+
+func (cb *Breaker) exit() {
+	atomic.AddInt64(&cb.inflight, -1)
+	if cb.pressureConc > 0 {
+		cb.updatePressure()
+	}
+}
+
+// updatePressure recomputes the breaker's Pressure.
+func (cb *Breaker) updatePressure() {
+	p := 1.0
+	if !cb.Tripped() && atomic.LoadInt32(&cb.unavailable) == 0 {
+		p = cb.rate()
+		if math.IsNaN(p) {
+			p = 0
+		}
+		if cb.pressureConc > 0 {
+			saturation := float64(atomic.LoadInt64(&cb.inflight)) / float64(cb.pressureConc)
+			p = math.Max(p, math.Min(saturation, 1))
+		}
+	}
+	atomic.StoreUint64(&cb.pressure, math.Float64bits(p))
+}
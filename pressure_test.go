@@ -0,0 +1,55 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPressure(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{PressureConcurrency: 4})
+	if p := cb.Pressure(); p != 0 {
+		t.Fatalf("expected no pressure on a new breaker, got %v", p)
+	}
+
+	cb.Success()
+	cb.Fail(errors.New("boom"))
+	cb.Success()
+	cb.Success()
+	if p := cb.Pressure(); p != 0.25 {
+		t.Fatalf("expected the error rate as pressure, got %v", p)
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go cb.Call(func() error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}, 0)
+		<-started
+	}
+	if p, n := cb.Pressure(), cb.InFlight(); p != 0.5 || n != 2 {
+		t.Fatalf("expected 2 of 4 calls in flight to press by 0.5, got %v with %d in flight", p, n)
+	}
+	if s := cb.Stats(); s.Pressure != 0.5 || s.InFlight != 2 {
+		t.Fatalf("expected Stats to report the pressure, got %v and %d", s.Pressure, s.InFlight)
+	}
+	close(release)
+	for i := 0; cb.InFlight() != 0; i++ {
+		if i == 100 {
+			t.Fatal("expected the calls to return")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cb.Trip()
+	if p := cb.Pressure(); p != 1 {
+		t.Fatalf("expected full pressure while tripped, got %v", p)
+	}
+	cb.Reset()
+	if p := cb.Pressure(); p != 0 {
+		t.Fatalf("expected the reset to clear the pressure, got %v", p)
+	}
+}
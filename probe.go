@@ -0,0 +1,95 @@
+package circuit
+
+import "sync/atomic"
+
+// ProbeToken is a breaker's permission to make one call, as returned by
+// TryProbe and Acquire. A half-open breaker lets at most Options.MaxProbes
+// trial calls through at once, however many integrations share it, and the
+// token of a trial call holds one of those slots until Done or Release is
+// called.
+type ProbeToken struct {
+	cb    *Breaker
+	done  *int32
+	probe bool
+	held  trialSlots
+}
+
+// TryProbe returns a token for a trial call if the breaker is half-open and
+// fewer than MaxProbes trial calls are in flight. It reports false otherwise,
+// including when the breaker is closed and calls need no token. Call, Do and
+// Acquire take their tokens the same way, so trial calls made with TryProbe
+// and through them count against the same limit. Allow and Record take none,
+// and do not limit trial calls.
+func (cb *Breaker) TryProbe() (ProbeToken, bool) {
+	ready, probe, held := cb.readySlot(true)
+	if !ready || !probe {
+		return ProbeToken{}, false
+	}
+	return ProbeToken{cb: cb, done: new(int32), probe: true, held: held}, true
+}
+
+// Acquire is Allow for calls that count against Options.MaxProbes: a trial
+// call takes one of the half-open breaker's trial call slots, as with Call,
+// and is rejected with ErrBreakerOpen if there is none free. The outcome of a
+// call it allows must be passed to the token's Done. Integrations that make
+// calls in two steps use it, so that trial calls are limited across all of
+// them.
+func (cb *Breaker) Acquire() (ProbeToken, error) {
+	probe, held, err := cb.allow(true)
+	if err != nil {
+		return ProbeToken{}, err
+	}
+	return ProbeToken{cb: cb, done: new(int32), probe: probe, held: held}, nil
+}
+
+// Probe reports whether the token is for a trial call.
+func (t ProbeToken) Probe() bool {
+	return t.probe
+}
+
+// Done records the outcome of the call as Record does, and frees the token's
+// slot. Only the first call to Done or Release has an effect.
+func (t ProbeToken) Done(err error) {
+	if t.cb != nil && atomic.CompareAndSwapInt32(t.done, 0, 1) {
+		t.cb.Record(err)
+		t.held.release()
+	}
+}
+
+// Release frees the token's slot without recording anything, for a call that
+// was not made after all, or whose outcome says nothing about the
+// dependency.
+func (t ProbeToken) Release() {
+	if t.cb != nil && atomic.CompareAndSwapInt32(t.done, 0, 1) {
+		t.held.release()
+	}
+}
+
+// acquireProbe takes a trial call slot, if one is free.
+func (cb *Breaker) acquireProbe() bool {
+	for {
+		n := atomic.LoadInt64(&cb.probing)
+		if n >= cb.maxProbes {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&cb.probing, n, n+1) {
+			return true
+		}
+	}
+}
+
+// releaseProbe frees a trial call slot.
+func (cb *Breaker) releaseProbe() {
+	atomic.AddInt64(&cb.probing, -1)
+}
+
+// trialSlots are the trial call slots a call holds: that of the breaker, and
+// those of its ancestors, for each that is half-open.
+type trialSlots []*Breaker
+
+// release frees the slots.
+func (s trialSlots) release() {
+	for _, cb := range s {
+		cb.releaseProbe()
+	}
+}
@@ -0,0 +1,144 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/facebookgo/clock"
+)
+
+func TestTryProbe(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	cb := NewBreakerWithOptions(&Options{
+		Clock:   c,
+		BackOff: &backoff.ConstantBackOff{Interval: time.Second},
+	})
+	if _, ok := cb.TryProbe(); ok {
+		t.Fatal("expected no probe token from a closed breaker")
+	}
+
+	cb.Trip()
+	if _, ok := cb.TryProbe(); ok {
+		t.Fatal("expected no probe token from an open breaker")
+	}
+
+	c.Add(5 * time.Second)
+	token, ok := cb.TryProbe()
+	if !ok {
+		t.Fatal("expected a probe token from a half-open breaker")
+	}
+	if _, ok := cb.TryProbe(); ok {
+		t.Fatal("expected no second token while a trial call is in flight")
+	}
+	if err := cb.Call(func() error { return nil }, 0); err != ErrBreakerOpen {
+		t.Fatalf("expected Call to be rejected while a trial call is in flight, got %v", err)
+	}
+
+	token.Done(errors.New("still down"))
+	token.Done(nil)
+	if !cb.Tripped() {
+		t.Fatal("expected the failed trial call to keep the breaker tripped")
+	}
+
+	c.Add(5 * time.Second)
+	token, ok = cb.TryProbe()
+	if !ok {
+		t.Fatal("expected the slot to be freed by Done")
+	}
+	token.Done(nil)
+	if cb.Tripped() {
+		t.Fatal("expected the successful trial call to reset the breaker")
+	}
+}
+
+func TestMaxProbes(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := NewBreakerWithOptions(&Options{
+		Clock:     c,
+		BackOff:   &backoff.ConstantBackOff{Interval: time.Second},
+		MaxProbes: 2,
+	})
+	cb.Trip()
+	c.Add(5 * time.Second)
+
+	first, ok1 := cb.TryProbe()
+	_, ok2 := cb.TryProbe()
+	if !ok1 || !ok2 {
+		t.Fatal("expected two probe tokens")
+	}
+	if _, ok := cb.TryProbe(); ok {
+		t.Fatal("expected no third probe token")
+	}
+	first.Release()
+	if _, ok := cb.TryProbe(); !ok {
+		t.Fatal("expected Release to free a slot")
+	}
+	if !cb.Tripped() {
+		t.Fatal("expected Release not to record an outcome")
+	}
+}
+
+// countingBackOff is a constant BackOff counting the steps taken.
+type countingBackOff struct {
+	backoff.ConstantBackOff
+	steps int
+}
+
+func (b *countingBackOff) NextBackOff() time.Duration {
+	b.steps++
+	return b.ConstantBackOff.NextBackOff()
+}
+
+func TestAcquire(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	b := &countingBackOff{ConstantBackOff: backoff.ConstantBackOff{Interval: time.Second}}
+	cb := NewBreakerWithOptions(&Options{Clock: c, BackOff: b})
+
+	token, err := cb.Acquire()
+	if err != nil || token.Probe() {
+		t.Fatalf("expected a closed breaker to allow a call that is not a trial, got %v", err)
+	}
+	token.Done(errors.New("failed"))
+	if f := cb.Failures(); f != 1 {
+		t.Fatalf("expected Done to record the failure, got %d failures", f)
+	}
+
+	cb.Trip()
+	c.Add(5 * time.Second)
+	probe, ok := cb.TryProbe()
+	if !ok {
+		t.Fatal("expected a probe token from a half-open breaker")
+	}
+	events := cb.Subscribe()
+	steps := b.steps
+
+	if _, err := cb.Acquire(); err != ErrBreakerOpen {
+		t.Fatalf("expected Acquire to count against MaxProbes, got %v", err)
+	}
+	if err := cb.Call(func() error { return nil }, 0); err != ErrBreakerOpen {
+		t.Fatalf("expected Call to be rejected while a trial call is in flight, got %v", err)
+	}
+	if b.steps != steps {
+		t.Fatalf("expected rejected trial calls not to use up backoff steps, got %d more", b.steps-steps)
+	}
+	select {
+	case e := <-events:
+		t.Fatalf("expected no event for rejected trial calls, got %v", e)
+	default:
+	}
+
+	probe.Release()
+	token, err = cb.Acquire()
+	if err != nil || !token.Probe() {
+		t.Fatalf("expected a trial call once the slot is free, got %v", err)
+	}
+	token.Done(nil)
+	if cb.Tripped() {
+		t.Fatal("expected the successful trial call to reset the breaker")
+	}
+}
@@ -0,0 +1,36 @@
+package circuit
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// The pprof labels of the goroutines running calls made through a breaker
+// created with Options.ProfileLabels. CPU and goroutine profiles can be
+// filtered or grouped by them, such as with pprof's -tagfocus and -tagroot,
+// to find the dependency whose calls use the most resources.
+const (
+	// ProfileLabelBreaker is the label giving the breaker's name.
+	ProfileLabelBreaker = "circuit_breaker"
+
+	// ProfileLabelState is the label giving the breaker's state when the
+	// call was made: "closed", or "half-open" for trial calls.
+	ProfileLabelState = "circuit_state"
+)
+
+// withProfileLabels returns fn with the breaker's pprof labels, added to those
+// of ctx, set on the goroutine while it runs.
+func (cb *Breaker) withProfileLabels(ctx context.Context, probe bool, fn func() error) func() error {
+	state := StateClosed
+	if probe {
+		state = StateHalfOpen
+	}
+	labels := pprof.Labels(ProfileLabelBreaker, cb.name, ProfileLabelState, state.String())
+	return func() error {
+		var err error
+		pprof.Do(ctx, labels, func(context.Context) {
+			err = fn()
+		})
+		return err
+	}
+}
@@ -0,0 +1,27 @@
+package circuit
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfileLabels(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{Name: "db", ProfileLabels: true})
+
+	for _, timeout := range []time.Duration{0, time.Second} {
+		var profile bytes.Buffer
+		err := cb.Call(func() error {
+			return pprof.Lookup("goroutine").WriteTo(&profile, 1)
+		}, timeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `"circuit_breaker":"db", "circuit_state":"closed"`
+		if !strings.Contains(profile.String(), want) {
+			t.Fatalf("expected the goroutine profile to have labels %s", want)
+		}
+	}
+}
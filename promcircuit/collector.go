@@ -0,0 +1,285 @@
+// Package promcircuit exports the state and statistics of the breakers in a
+// circuit.Panel as Prometheus metrics.
+//
+// The time breakers spend in each state is exported as a histogram, so
+// questions such as how long a dependency typically stays open before it
+// recovers can be answered from the metrics:
+//
+//	prometheus.MustRegister(promcircuit.NewCollector(panel))
+package promcircuit
+
+import (
+	"log"
+	"sync"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	trippedDesc = prometheus.NewDesc(
+		"circuit_breaker_tripped",
+		"Whether the breaker is tripped (1) or closed (0).",
+		[]string{"breaker"}, nil)
+	tripsDesc = prometheus.NewDesc(
+		"circuit_breaker_trips_total",
+		"Number of times the breaker has tripped.",
+		[]string{"breaker"}, nil)
+	failuresDesc = prometheus.NewDesc(
+		"circuit_breaker_window_failures",
+		"Number of failures in the breaker's window.",
+		[]string{"breaker"}, nil)
+	categoryFailuresDesc = prometheus.NewDesc(
+		"circuit_breaker_window_failures_by_category",
+		"Number of failures in the breaker's window, by category.",
+		[]string{"breaker", "category"}, nil)
+	successesDesc = prometheus.NewDesc(
+		"circuit_breaker_window_successes",
+		"Number of successes in the breaker's window.",
+		[]string{"breaker"}, nil)
+	tlsFailuresDesc = prometheus.NewDesc(
+		"circuit_breaker_tls_failures_total",
+		"Number of failures that were failed TLS handshakes.",
+		[]string{"breaker"}, nil)
+	abandonedDesc = prometheus.NewDesc(
+		"circuit_breaker_abandoned_calls",
+		"Number of calls that timed out but are still running.",
+		[]string{"breaker"}, nil)
+	slaAvailabilityDesc = prometheus.NewDesc(
+		"circuit_breaker_sla_availability",
+		"Fraction of the calls in the breaker's window that succeeded, for breakers with an SLA.",
+		[]string{"breaker"}, nil)
+	slaLatencyP99Desc = prometheus.NewDesc(
+		"circuit_breaker_sla_latency_p99_seconds",
+		"99th percentile latency of the calls in the breaker's window, for breakers with an SLA.",
+		[]string{"breaker"}, nil)
+	slaMetDesc = prometheus.NewDesc(
+		"circuit_breaker_sla_met",
+		"Whether the calls in the breaker's window meet its SLA (1) or not (0).",
+		[]string{"breaker"}, nil)
+	overflowDesc = prometheus.NewDesc(
+		"circuit_breaker_overflow_breakers",
+		"Number of breakers whose metrics are aggregated under the \"other\" label.",
+		nil, nil)
+	stateDurationDesc = prometheus.NewDesc(
+		"circuit_breaker_state_duration_seconds",
+		"Time the breaker spent in a state before leaving it.",
+		[]string{"breaker", "state"}, nil)
+)
+
+// OtherLabel is the breaker label under which a Collector aggregates the
+// metrics of the breakers beyond its MaxBreakers.
+const OtherLabel = "other"
+
+// Collector is a prometheus.Collector for the breakers in a Panel. Breakers
+// added to the panel after the collector is registered are picked up on the
+// next scrape.
+type Collector struct {
+	// MaxBreakers, if non-zero, is the number of breakers exported under
+	// their own label. Panels that create breakers per URL or tenant can
+	// otherwise create a label value for every key they see. The metrics of
+	// the breakers beyond the limit are aggregated under OtherLabel: their
+	// circuit_breaker_tripped is the number of them that are tripped, and
+	// their other metrics are summed. Breakers keep the label they were
+	// first exported under until they are removed from the panel.
+	MaxBreakers int
+
+	// Warnf, if non-nil, is called the first time breakers are aggregated
+	// under OtherLabel. Defaults to log.Printf.
+	Warnf func(format string, v ...interface{})
+
+	panel    *circuit.Panel
+	lock     sync.Mutex
+	labels   map[string]bool
+	overflow bool
+}
+
+// NewCollector creates a Collector for the breakers in p.
+func NewCollector(p *circuit.Panel) *Collector {
+	return &Collector{panel: p}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- trippedDesc
+	ch <- tripsDesc
+	ch <- failuresDesc
+	ch <- categoryFailuresDesc
+	ch <- successesDesc
+	ch <- tlsFailuresDesc
+	ch <- abandonedDesc
+	ch <- slaAvailabilityDesc
+	ch <- slaLatencyP99Desc
+	ch <- slaMetDesc
+	ch <- stateDurationDesc
+	ch <- overflowDesc
+}
+
+// series is the metrics exported under a breaker label.
+type series struct {
+	tripped, trips, failures, successes, tlsFailures, abandoned float64
+	categories                                                  map[circuit.FailureCategory]float64
+	sla                                                         *circuit.SLACompliance
+	openDurations, closedDurations                              circuit.Histogram
+}
+
+// add adds the stats of a breaker to the series.
+func (m *series) add(s circuit.Stats) {
+	if s.Tripped {
+		m.tripped++
+	}
+	m.trips += float64(s.Trips)
+	m.failures += float64(s.Failures)
+	for c, n := range s.FailureCategories {
+		if m.categories == nil {
+			m.categories = make(map[circuit.FailureCategory]float64)
+		}
+		m.categories[c] += float64(n)
+	}
+	m.successes += float64(s.Successes)
+	m.tlsFailures += float64(s.TLSFailures)
+	m.abandoned += float64(s.Abandoned)
+	m.openDurations = addHistograms(m.openDurations, s.OpenDurations)
+	m.closedDurations = addHistograms(m.closedDurations, s.ClosedDurations)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	var names []string
+	breakers := make(map[string]*circuit.Breaker)
+	c.panel.Range(func(name string, cb *circuit.Breaker) bool {
+		names = append(names, name)
+		breakers[name] = cb
+		return true
+	})
+	c.prune(breakers)
+
+	all := make(map[string]*series)
+	overflowed := 0
+	for _, name := range names {
+		s := breakers[name].Stats()
+		label := name
+		if !c.admit(name) {
+			label = OtherLabel
+			overflowed++
+		}
+		m, ok := all[label]
+		if !ok {
+			m = &series{}
+			all[label] = m
+		}
+		m.add(s)
+		if label == name {
+			m.sla = s.SLA
+		}
+	}
+	if overflowed > 0 {
+		c.warnOverflow()
+	}
+	ch <- prometheus.MustNewConstMetric(overflowDesc, prometheus.GaugeValue, float64(overflowed))
+
+	for name, m := range all {
+		ch <- prometheus.MustNewConstMetric(trippedDesc, prometheus.GaugeValue, m.tripped, name)
+		ch <- prometheus.MustNewConstMetric(tripsDesc, prometheus.CounterValue, m.trips, name)
+		ch <- prometheus.MustNewConstMetric(failuresDesc, prometheus.GaugeValue, m.failures, name)
+		for c, n := range m.categories {
+			ch <- prometheus.MustNewConstMetric(categoryFailuresDesc, prometheus.GaugeValue, n, name, string(c))
+		}
+		ch <- prometheus.MustNewConstMetric(successesDesc, prometheus.GaugeValue, m.successes, name)
+		ch <- prometheus.MustNewConstMetric(tlsFailuresDesc, prometheus.CounterValue, m.tlsFailures, name)
+		ch <- prometheus.MustNewConstMetric(abandonedDesc, prometheus.GaugeValue, m.abandoned, name)
+		if sla := m.sla; sla != nil {
+			met := 0.0
+			if sla.Met {
+				met = 1
+			}
+			ch <- prometheus.MustNewConstMetric(slaAvailabilityDesc, prometheus.GaugeValue, sla.Availability, name)
+			ch <- prometheus.MustNewConstMetric(slaLatencyP99Desc, prometheus.GaugeValue, sla.LatencyP99.Seconds(), name)
+			ch <- prometheus.MustNewConstMetric(slaMetDesc, prometheus.GaugeValue, met, name)
+		}
+		ch <- constHistogram(m.openDurations, name, "open")
+		ch <- constHistogram(m.closedDurations, name, "closed")
+	}
+}
+
+// admit reports whether the breaker name is exported under its own label,
+// giving it one if there are fewer than MaxBreakers.
+func (c *Collector) admit(name string) bool {
+	if c.MaxBreakers <= 0 {
+		return true
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.labels[name] {
+		return true
+	}
+	if len(c.labels) >= c.MaxBreakers || name == OtherLabel {
+		return false
+	}
+	if c.labels == nil {
+		c.labels = make(map[string]bool)
+	}
+	c.labels[name] = true
+	return true
+}
+
+// prune drops the labels of the breakers no longer in the panel, so that the
+// breakers added in their place can be given them.
+func (c *Collector) prune(breakers map[string]*circuit.Breaker) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for name := range c.labels {
+		if _, ok := breakers[name]; !ok {
+			delete(c.labels, name)
+		}
+	}
+}
+
+// warnOverflow warns, the first time it is called, that breakers are being
+// aggregated.
+func (c *Collector) warnOverflow() {
+	c.lock.Lock()
+	warned := c.overflow
+	c.overflow = true
+	c.lock.Unlock()
+	if warned {
+		return
+	}
+	warnf := c.Warnf
+	if warnf == nil {
+		warnf = log.Printf
+	}
+	warnf("promcircuit: more than %d breakers, aggregating the rest under %q", c.MaxBreakers, OtherLabel)
+}
+
+// addHistograms returns the sum of a and b, either of which may be empty.
+func addHistograms(a, b circuit.Histogram) circuit.Histogram {
+	if a.Bounds == nil {
+		return b
+	}
+	sum := circuit.Histogram{
+		Bounds: a.Bounds,
+		Counts: append([]int64(nil), a.Counts...),
+		Count:  a.Count + b.Count,
+		Sum:    a.Sum + b.Sum,
+	}
+	for i := range b.Counts {
+		if i < len(sum.Counts) {
+			sum.Counts[i] += b.Counts[i]
+		}
+	}
+	return sum
+}
+
+// constHistogram converts h, whose buckets are not cumulative, into a
+// Prometheus histogram.
+func constHistogram(h circuit.Histogram, labels ...string) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(h.Bounds))
+	var cumulative uint64
+	for i, bound := range h.Bounds {
+		cumulative += uint64(h.Counts[i])
+		buckets[bound.Seconds()] = cumulative
+	}
+	return prometheus.MustNewConstHistogram(stateDurationDesc,
+		uint64(h.Count), h.Sum.Seconds(), buckets, labels...)
+}
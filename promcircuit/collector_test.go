@@ -0,0 +1,156 @@
+package promcircuit
+
+import (
+	"testing"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollector(t *testing.T) {
+	p := circuit.NewPanel()
+	cb := circuit.NewBreaker()
+	p.Add("db", cb)
+	cb.Trip()
+	cb.Reset()
+	cb.Break()
+	cache := p.AddWithOptions("cache", &circuit.Options{SLA: &circuit.SLA{Availability: 0.99}})
+	cache.Fail(circuit.ErrBreakerTimeout)
+	cache.Success()
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(NewCollector(p))
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]float64{}
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			name := mf.GetName()
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "breaker" && l.GetValue() != "db" {
+					name += "[" + l.GetValue() + "]"
+				}
+			}
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "state" || l.GetName() == "category" {
+					name += "{" + l.GetValue() + "}"
+				}
+			}
+			switch {
+			case m.GetHistogram() != nil:
+				got[name] = float64(m.GetHistogram().GetSampleCount())
+			case m.GetCounter() != nil:
+				got[name] = m.GetCounter().GetValue()
+			default:
+				got[name] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	want := map[string]float64{
+		"circuit_breaker_tripped":                                     1,
+		"circuit_breaker_trips_total":                                 2,
+		"circuit_breaker_state_duration_seconds{open}":                1,
+		"circuit_breaker_state_duration_seconds{closed}":              2,
+		"circuit_breaker_sla_met[cache]":                              0,
+		"circuit_breaker_sla_availability[cache]":                     0.5,
+		"circuit_breaker_window_failures_by_category[cache]{timeout}": 1,
+	}
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("expected %s to be %v, got %v", name, v, got[name])
+		}
+	}
+}
+
+func TestCollectorMaxBreakers(t *testing.T) {
+	p := circuit.NewPanel()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		cb := circuit.NewBreaker()
+		p.Add(name, cb)
+		if name != "a" {
+			cb.Trip()
+		}
+	}
+
+	var warnings int
+	c := NewCollector(p)
+	c.MaxBreakers = 2
+	c.Warnf = func(format string, v ...interface{}) { warnings++ }
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+
+	for i := 0; i < 2; i++ {
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tripped := map[string]float64{}
+		var overflow float64
+		for _, mf := range families {
+			for _, m := range mf.GetMetric() {
+				switch mf.GetName() {
+				case "circuit_breaker_tripped":
+					tripped[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+				case "circuit_breaker_overflow_breakers":
+					overflow = m.GetGauge().GetValue()
+				}
+			}
+		}
+		want := map[string]float64{"a": 0, "b": 1, OtherLabel: 2}
+		if len(tripped) != len(want) {
+			t.Fatalf("expected breakers %v, got %v", want, tripped)
+		}
+		for label, v := range want {
+			if tripped[label] != v {
+				t.Errorf("expected %s to have %v tripped, got %v", label, v, tripped[label])
+			}
+		}
+		if overflow != 2 {
+			t.Errorf("expected 2 overflow breakers, got %v", overflow)
+		}
+	}
+	if warnings != 1 {
+		t.Fatalf("expected a single warning, got %d", warnings)
+	}
+}
+
+func TestCollectorPrunesRemovedBreakers(t *testing.T) {
+	p := circuit.NewPanel()
+	p.Add("a", circuit.NewBreaker())
+	p.Add("b", circuit.NewBreaker())
+
+	c := NewCollector(p)
+	c.MaxBreakers = 1
+	c.Warnf = func(format string, v ...interface{}) {}
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+
+	labels := func() map[string]bool {
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]bool{}
+		for _, mf := range families {
+			if mf.GetName() != "circuit_breaker_tripped" {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				got[m.GetLabel()[0].GetValue()] = true
+			}
+		}
+		return got
+	}
+
+	if got := labels(); !got["a"] || got["b"] {
+		t.Fatalf("expected only a to have its own label, got %v", got)
+	}
+	p.Remove("a")
+	if got := labels(); !got["b"] || got["a"] || got[OtherLabel] {
+		t.Fatalf("expected b to take the label freed by a, got %v", got)
+	}
+}
@@ -0,0 +1,179 @@
+package circuit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TripHeader is the HTTP header, and the gRPC trailer in grpccircuit, that
+// carries a TripToken to callers when a request fails because a breaker is
+// open. Callers can tell from it which dependency is down, however many
+// services away it is, and apply their own policies to it.
+const TripHeader = "Circuit-Trip"
+
+// TripToken names the dependency whose open breaker failed a request. When a
+// service learns from the responses of a dependency that the dependency's
+// own dependency is down, the token it sends its callers names that root
+// cause, and lists the dependencies the outage was reported through in Via,
+// nearest first. A token is written as, for example:
+//
+//	dependency=db; via=orders,inventory; retry-after-ms=1500
+type TripToken struct {
+	// Dependency is the name of the root-cause dependency.
+	Dependency string
+
+	// Via are the names of the dependencies between the service sending
+	// the token and Dependency.
+	Via []string
+
+	// RetryAfter is how long until the breaker that is open will retry, or
+	// -1 if it will not retry on its own.
+	RetryAfter time.Duration
+}
+
+// NewTripToken returns the token for a request failed because cb, which
+// protects the dependency name, is open. If the calls through cb have
+// reported an outage further away, the token names its root cause. See
+// Breaker.UpstreamTrip.
+func NewTripToken(name string, cb *Breaker) TripToken {
+	t := TripToken{Dependency: name, RetryAfter: cb.RetryAfter()}
+	if up, ok := cb.UpstreamTrip(); ok {
+		t.Dependency = up.Dependency
+		t.Via = append([]string{name}, up.Via...)
+	}
+	return t
+}
+
+// String returns the token as it is written in TripHeader.
+func (t TripToken) String() string {
+	var b strings.Builder
+	b.WriteString("dependency=")
+	b.WriteString(t.Dependency)
+	if len(t.Via) > 0 {
+		b.WriteString("; via=")
+		b.WriteString(strings.Join(t.Via, ","))
+	}
+	b.WriteString("; retry-after-ms=")
+	retry := int64(-1)
+	if t.RetryAfter >= 0 {
+		retry = t.RetryAfter.Milliseconds()
+	}
+	b.WriteString(strconv.FormatInt(retry, 10))
+	return b.String()
+}
+
+// ParseTripToken parses a token written by TripToken.String. Unknown fields
+// are ignored, so that tokens can gain fields.
+func ParseTripToken(s string) (TripToken, error) {
+	t := TripToken{RetryAfter: -1}
+	for _, field := range strings.Split(s, ";") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, value := kv[0], kv[1]
+		switch key {
+		case "dependency":
+			t.Dependency = value
+		case "via":
+			if value != "" {
+				t.Via = strings.Split(value, ",")
+			}
+		case "retry-after-ms":
+			ms, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return TripToken{}, fmt.Errorf("circuit: bad retry-after-ms in trip token %q", s)
+			}
+			if ms >= 0 {
+				t.RetryAfter = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if t.Dependency == "" {
+		return TripToken{}, fmt.Errorf("circuit: no dependency in trip token %q", s)
+	}
+	return t, nil
+}
+
+// TripTokenFromResponse returns the token in the TripHeader of resp, if it has
+// a valid one.
+func TripTokenFromResponse(resp *http.Response) (TripToken, bool) {
+	if resp == nil {
+		return TripToken{}, false
+	}
+	v := resp.Header.Get(TripHeader)
+	if v == "" {
+		return TripToken{}, false
+	}
+	t, err := ParseTripToken(v)
+	return t, err == nil
+}
+
+// UpstreamTrip returns the token in the last response to a call made through
+// the breaker by Transport or HTTPClient, if it had one, or the token last
+// recorded with SetUpstreamTrip. It tells that the dependency the breaker
+// protects is failing because a dependency of its own is down. The token is
+// forgotten when the breaker resets.
+func (cb *Breaker) UpstreamTrip() (TripToken, bool) {
+	cb.eventLock.Lock()
+	defer cb.eventLock.Unlock()
+	if cb.upstream == nil {
+		return TripToken{}, false
+	}
+	return *cb.upstream, true
+}
+
+// SetUpstreamTrip records t as received from the dependency the breaker
+// protects, for clients other than Transport and HTTPClient, such as gRPC
+// ones. See UpstreamTrip.
+func (cb *Breaker) SetUpstreamTrip(t TripToken) {
+	cb.eventLock.Lock()
+	cb.upstream = &t
+	cb.eventLock.Unlock()
+}
+
+// noteUpstreamTrip records the token in resp, or forgets the last one if resp
+// has none.
+func (cb *Breaker) noteUpstreamTrip(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	t, ok := TripTokenFromResponse(resp)
+	cb.eventLock.Lock()
+	if ok {
+		cb.upstream = &t
+	} else {
+		cb.upstream = nil
+	}
+	cb.eventLock.Unlock()
+}
+
+// ShedHandler returns a handler that serves requests with next unless the
+// breaker in p of one of the dependencies returned by deps is open, in which
+// case it fails the request with 503 Service Unavailable, a Retry-After header
+// if the breaker will retry, and a TripToken in TripHeader. Unavailable
+// breakers are treated as open, and disabled ones as closed. A half-open
+// breaker is not considered open, so that the request can serve as its trial
+// call.
+func ShedHandler(p *Panel, deps func(*http.Request) []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range deps(r) {
+			cb, ok := p.Get(name)
+			if !ok || !cb.rejecting() {
+				continue
+			}
+			t := NewTripToken(name, cb)
+			w.Header().Set(TripHeader, t.String())
+			if t.RetryAfter > 0 {
+				secs := (t.RetryAfter + time.Second - 1) / time.Second
+				w.Header().Set("Retry-After", strconv.FormatInt(int64(secs), 10))
+			}
+			http.Error(w, fmt.Sprintf("circuit breaker for dependency %q is open", t.Dependency), http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
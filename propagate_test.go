@@ -0,0 +1,95 @@
+package circuit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTripTokenRoundTrip(t *testing.T) {
+	for _, token := range []TripToken{
+		{Dependency: "db", RetryAfter: 1500 * time.Millisecond},
+		{Dependency: "db", Via: []string{"orders", "inventory"}, RetryAfter: -1},
+	} {
+		got, err := ParseTripToken(token.String())
+		if err != nil || !reflect.DeepEqual(got, token) {
+			t.Fatalf("expected %q to parse back to %+v, got %+v, %v", token, token, got, err)
+		}
+	}
+	if _, err := ParseTripToken("via=orders"); err == nil {
+		t.Fatal("expected a token without a dependency to be rejected")
+	}
+	if got, err := ParseTripToken("dependency=db; shard=3"); err != nil || got.Dependency != "db" {
+		t.Fatalf("expected unknown fields to be ignored, got %+v, %v", got, err)
+	}
+}
+
+func TestTripPropagation(t *testing.T) {
+	// Service B sheds requests because its breaker for C is open.
+	bPanel := NewPanel()
+	toC := NewBreaker()
+	bPanel.Add("c", toC)
+	toC.Break()
+	deps := func(*http.Request) []string { return []string{"c"} }
+	b := httptest.NewServer(ShedHandler(bPanel, deps, http.NotFoundHandler()))
+	defer b.Close()
+
+	// Service A calls B and learns that C is down.
+	toB := NewBreaker()
+	client := NewHTTPClientWithBreaker(toB, 0, nil)
+	resp, err := client.Get(b.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected B to shed the request, got %d", resp.StatusCode)
+	}
+	if token, ok := toB.UpstreamTrip(); !ok || token.Dependency != "c" || len(token.Via) != 0 {
+		t.Fatalf("expected A to learn that c is down, got %+v", token)
+	}
+
+	// Once A's breaker for B is open, A names C as the root cause.
+	toB.Break()
+	aPanel := NewPanel()
+	aPanel.Add("b", toB)
+	rec := httptest.NewRecorder()
+	ShedHandler(aPanel, func(*http.Request) []string { return []string{"b"} }, http.NotFoundHandler()).
+		ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	token, err := ParseTripToken(rec.Header().Get(TripHeader))
+	if err != nil || token.Dependency != "c" || !reflect.DeepEqual(token.Via, []string{"b"}) {
+		t.Fatalf("expected A to report c via b, got %+v, %v", token, err)
+	}
+
+	toB.Reset()
+	if _, ok := toB.UpstreamTrip(); ok {
+		t.Fatal("expected the upstream trip to be forgotten on reset")
+	}
+}
+
+func TestShedHandlerStates(t *testing.T) {
+	p := NewPanel()
+	cb := NewBreaker()
+	p.Add("c", cb)
+	handler := ShedHandler(p, func(*http.Request) []string { return []string{"c"} }, http.NotFoundHandler())
+	status := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		return rec.Code
+	}
+
+	cb.Break()
+	cb.Disable()
+	if s := status(); s != http.StatusNotFound {
+		t.Fatalf("expected a disabled breaker to pass requests through, got %d", s)
+	}
+	cb.Enable()
+	cb.Reset()
+
+	cb.SetUnavailable("maintenance")
+	if s := status(); s != http.StatusServiceUnavailable {
+		t.Fatalf("expected an unavailable breaker to shed requests, got %d", s)
+	}
+}
@@ -0,0 +1,80 @@
+package circuit
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+)
+
+// ReverseProxy is an httputil.ReverseProxy that balances requests over a set of
+// backends, each protected by its own circuit breaker. Backends are tried in
+// round-robin order, skipping those whose breaker is open, so traffic rotates
+// to healthy backends while a failing one recovers. A backend whose breaker is
+// ready to retry is eligible again and the next request to it serves as the
+// trial call.
+//
+// If every backend is open, requests are answered with a 503 Service
+// Unavailable response from ServiceUnavailableResponse.
+type ReverseProxy struct {
+	httputil.ReverseProxy
+
+	// Panel holds the backend breakers, named by backend host.
+	Panel *Panel
+
+	targets []*url.URL
+	next    uint32
+}
+
+// NewReverseProxy creates a ReverseProxy for targets. Each backend gets a
+// breaker that trips after threshold consecutive failed requests. Requests are
+// proxied to the target URLs as with httputil.NewSingleHostReverseProxy.
+func NewReverseProxy(targets []*url.URL, threshold int64) *ReverseProxy {
+	p := &ReverseProxy{Panel: NewPanel(), targets: targets}
+	for _, target := range targets {
+		p.Panel.Add(target.Host, NewConsecutiveBreaker(threshold))
+	}
+
+	p.Rewrite = func(r *httputil.ProxyRequest) {
+		r.SetURL(p.pick())
+		r.SetXForwarded()
+	}
+	p.Transport = &Transport{
+		BreakerLookup: func(req *http.Request) *Breaker {
+			cb, _ := p.Panel.Get(req.URL.Host)
+			return cb
+		},
+		OpenResponse: ServiceUnavailableResponse,
+	}
+	return p
+}
+
+// Healthy returns the backends whose breakers are not open.
+func (p *ReverseProxy) Healthy() []*url.URL {
+	var healthy []*url.URL
+	for _, target := range p.targets {
+		if p.alive(target) {
+			healthy = append(healthy, target)
+		}
+	}
+	return healthy
+}
+
+// pick returns the next backend in round-robin order whose breaker is not
+// open. If all backends are open, it returns the next backend regardless so
+// that the request is rejected by its breaker.
+func (p *ReverseProxy) pick() *url.URL {
+	start := int(atomic.AddUint32(&p.next, 1))
+	for i := 0; i < len(p.targets); i++ {
+		target := p.targets[(start+i)%len(p.targets)]
+		if p.alive(target) {
+			return target
+		}
+	}
+	return p.targets[start%len(p.targets)]
+}
+
+func (p *ReverseProxy) alive(target *url.URL) bool {
+	cb, _ := p.Panel.Get(target.Host)
+	return !cb.rejecting()
+}
@@ -0,0 +1,62 @@
+package circuit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestReverseProxy(t *testing.T) {
+	backend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}))
+	}
+	a, b := backend("a"), backend("b")
+	defer a.Close()
+	defer b.Close()
+
+	aURL, _ := url.Parse(a.URL)
+	bURL, _ := url.Parse(b.URL)
+	p := NewReverseProxy([]*url.URL{aURL, bURL}, 1)
+	front := httptest.NewServer(p)
+	defer front.Close()
+
+	get := func() (int, string) {
+		resp, err := http.Get(front.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		_, body := get()
+		seen[body] = true
+	}
+	if !seen["a"] || !seen["b"] {
+		t.Fatalf("expected traffic to rotate over both backends, saw %v", seen)
+	}
+
+	cb, _ := p.Panel.Get(aURL.Host)
+	cb.Break()
+	if healthy := p.Healthy(); len(healthy) != 1 || healthy[0] != bURL {
+		t.Fatalf("expected only b to be healthy, got %v", healthy)
+	}
+	for i := 0; i < 4; i++ {
+		if _, body := get(); body != "b" {
+			t.Fatalf("expected traffic to go to healthy backend b, got %q", body)
+		}
+	}
+
+	cb, _ = p.Panel.Get(bURL.Host)
+	cb.Break()
+	if code, _ := get(); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 with all backends open, got %d", code)
+	}
+}
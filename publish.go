@@ -0,0 +1,125 @@
+package circuit
+
+import (
+	"context"
+	"time"
+)
+
+// Publisher publishes messages to a messaging service, such as GCP Pub/Sub or
+// AWS SNS or SQS, through a breaker. While the breaker is open, messages are
+// added to a Spool on local disk rather than failing, so that an outage of
+// the service does not block or fail the request handlers publishing them;
+// Run sends them once the breaker resets. Send is a function publishing
+// one message with the service's client:
+//
+//	p := circuit.NewPublisher(cb, func(ctx context.Context, msg []byte) error {
+//		_, err := snsClient.Publish(ctx, &sns.PublishInput{
+//			TopicArn: aws.String(topicARN),
+//			Message:  aws.String(string(msg)),
+//		})
+//		return err
+//	}, spool)
+//	go p.Run(ctx)
+//
+// or, for Pub/Sub:
+//
+//	p := circuit.NewPublisher(cb, func(ctx context.Context, msg []byte) error {
+//		_, err := topic.Publish(ctx, &pubsub.Message{Data: msg}).Get(ctx)
+//		return err
+//	}, spool)
+//
+// Messages carrying attributes should be encoded with them, so that they can
+// be spooled as bytes. Spooled messages are sent at least once, and may be
+// sent out of order with messages published after the breaker reset.
+type Publisher struct {
+	// Breaker protects the publish calls.
+	Breaker *Breaker
+
+	// Send publishes one message.
+	Send func(ctx context.Context, msg []byte) error
+
+	// Spool, if non-nil, holds the messages rejected while the breaker is
+	// open. Without one, they fail with ErrBreakerOpen.
+	Spool *Spool
+
+	// Timeout, if non-zero, is how long a publish call may take before it
+	// fails.
+	Timeout time.Duration
+}
+
+// NewPublisher creates a Publisher publishing with send through cb, and
+// spooling to spool while cb is open. spool may be nil.
+func NewPublisher(cb *Breaker, send func(ctx context.Context, msg []byte) error, spool *Spool) *Publisher {
+	return &Publisher{Breaker: cb, Send: send, Spool: spool}
+}
+
+// Publish publishes msg through the breaker. If the breaker rejects the call
+// because it is open or unavailable, msg is spooled and Publish returns nil,
+// or the error spooling it, such as ErrSpoolFull. Messages whose publish call
+// is made and fails are not spooled, as the service may have received them:
+// the error is returned for the caller to retry or give up.
+func (p *Publisher) Publish(ctx context.Context, msg []byte) error {
+	err := p.publish(ctx, msg)
+	if err == nil || p.Spool == nil || !rejectedOpen(err) {
+		return err
+	}
+	return p.Spool.Add(msg)
+}
+
+// Flush sends the spooled messages through the breaker, oldest first, until
+// the spool is empty or a call fails or is rejected. It returns the number of
+// messages sent and the error that stopped it, if any.
+func (p *Publisher) Flush(ctx context.Context) (int, error) {
+	if p.Spool == nil {
+		return 0, nil
+	}
+	return p.Spool.Drain(func(msg []byte) error {
+		return p.publish(ctx, msg)
+	})
+}
+
+// publisherMinRetry is the least Run waits between attempts to flush the
+// spool while the breaker is tripped, so that it does not spin while another
+// trial call is in flight.
+const publisherMinRetry = 100 * time.Millisecond
+
+// Run flushes the spool when it starts and each time the breaker resets,
+// until ctx is done. While the breaker is tripped and messages are spooled,
+// it also flushes whenever the breaker is ready for a trial call, making the
+// first spooled message the trial, so that the spool drains even when
+// nothing else is published.
+func (p *Publisher) Run(ctx context.Context) {
+	events, unsubscribe := p.Breaker.subscribe()
+	defer unsubscribe()
+
+	p.Flush(ctx)
+	for {
+		var retry <-chan time.Time
+		if d := p.Breaker.RetryAfter(); p.Breaker.Tripped() && d >= 0 && p.Spool != nil && p.Spool.Len() > 0 {
+			if d < publisherMinRetry {
+				d = publisherMinRetry
+			}
+			retry = p.Breaker.Clock.After(d)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e == BreakerReset {
+				p.Flush(ctx)
+			}
+		case <-retry:
+			p.Flush(ctx)
+		}
+	}
+}
+
+func (p *Publisher) publish(ctx context.Context, msg []byte) error {
+	return p.Breaker.Do(ctx, func(ctx context.Context) error {
+		return p.Send(ctx, msg)
+	}, p.Timeout)
+}
@@ -0,0 +1,110 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+)
+
+// recorder is a publish function recording the messages it publishes.
+type recorder struct {
+	lock sync.Mutex
+	err  error
+	msgs []string
+}
+
+func (r *recorder) publish(ctx context.Context, msg []byte) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.msgs = append(r.msgs, string(msg))
+	return nil
+}
+
+func (r *recorder) published() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.msgs...)
+}
+
+func TestPublisher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	spool, err := NewSpool(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cb := NewBreakerWithOptions(&Options{
+		BackOff:    &backoff.ConstantBackOff{Interval: 10 * time.Millisecond},
+		ShouldTrip: ConsecutiveTripFunc(1),
+	})
+	r := &recorder{}
+	p := NewPublisher(cb, r.publish, spool)
+	ctx := context.Background()
+
+	if err := p.Publish(ctx, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	errDown := errors.New("down")
+	r.err = errDown
+	if err := p.Publish(ctx, []byte("b")); err != errDown {
+		t.Fatalf("expected a failed publish to return its error, got %v", err)
+	}
+	if !cb.Tripped() {
+		t.Fatal("expected the failure to trip the breaker")
+	}
+	if err := p.Publish(ctx, []byte("c")); err != nil {
+		t.Fatalf("expected the message to be spooled while open, got %v", err)
+	}
+	if n := spool.Len(); n != 1 {
+		t.Fatalf("expected 1 spooled message, got %d", n)
+	}
+
+	r.lock.Lock()
+	r.err = nil
+	r.lock.Unlock()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		p.Run(runCtx)
+		close(done)
+	}()
+	for spool.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if msgs := r.published(); len(msgs) != 2 || msgs[0] != "a" || msgs[1] != "c" {
+		t.Fatalf("expected a and the spooled c to be published, got %v", msgs)
+	}
+	if cb.Tripped() {
+		t.Fatal("expected the spooled message to serve as a successful trial call")
+	}
+}
+
+func TestPublisherWithoutSpool(t *testing.T) {
+	cb := NewBreaker()
+	cb.Trip()
+	p := NewPublisher(cb, (&recorder{}).publish, nil)
+	if err := p.Publish(context.Background(), []byte("a")); err != ErrBreakerOpen {
+		t.Fatalf("expected ErrBreakerOpen without a spool, got %v", err)
+	}
+	if n, err := p.Flush(context.Background()); n != 0 || err != nil {
+		t.Fatalf("expected nothing to flush, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,120 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultQueueTimeout is the longest a call is parked by a breaker with a
+// queue when Options.QueueTimeout is 0.
+const DefaultQueueTimeout = 30 * time.Second
+
+// ErrQueueTimeout is returned by Call when a call parked while the breaker was
+// open is still parked after Options.QueueTimeout.
+var ErrQueueTimeout = errors.New("breaker queue timed out")
+
+// Queued returns the number of calls parked while the breaker is open. See
+// Options.QueueSize.
+func (cb *Breaker) Queued() int64 {
+	return atomic.LoadInt64(&cb.queued)
+}
+
+// admit is allow for Call and CallContext, which also takes a trial call slot
+// for a trial call. If the breaker has a queue, calls it would reject are
+// parked until it closes or lets a trial call through instead.
+func (cb *Breaker) admit(ctx context.Context) (probe bool, held trialSlots, err error) {
+	if cb.queueSize == 0 {
+		return cb.allow(true)
+	}
+
+	if err := cb.unavailableErr(); err != nil {
+		return false, nil, err
+	}
+	queued := false
+	defer func() {
+		if queued {
+			atomic.AddInt64(&cb.queued, -1)
+		}
+	}()
+	var deadline time.Time
+	for {
+		// Take the signal before checking the state, so that a reset in
+		// between is not missed.
+		closed := cb.closedSignalChan()
+		if ready, probe, held := cb.readySlot(true); ready {
+			return probe, held, nil
+		}
+		if !queued {
+			if atomic.AddInt64(&cb.queued, 1) > cb.queueSize {
+				atomic.AddInt64(&cb.queued, -1)
+				cb.reject()
+				return false, nil, ErrBreakerOpen
+			}
+			queued = true
+			deadline = cb.Clock.Now().Add(cb.queueTimeout)
+		}
+		if err := cb.park(ctx, closed, deadline); err != nil {
+			cb.reject()
+			return false, nil, err
+		}
+	}
+}
+
+// park waits for the breaker to close, for it to be ready to let a trial call
+// through or for deadline, whichever comes first.
+func (cb *Breaker) park(ctx context.Context, closed <-chan struct{}, deadline time.Time) error {
+	wait := deadline.Sub(cb.Clock.Now())
+	if wait <= 0 {
+		return ErrQueueTimeout
+	}
+	expires := true
+	retry := cb.RetryAfter()
+	if retry == 0 {
+		// The breaker is ready, but its trial call slots are taken.
+		retry = cb.BackOffInterval()
+	}
+	if retry > 0 && retry < wait {
+		wait = retry
+		expires = false
+	}
+
+	t := cb.Clock.Timer(wait)
+	defer t.Stop()
+	select {
+	case <-closed:
+		return nil
+	case <-t.C:
+		if expires {
+			return ErrQueueTimeout
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closedSignalChan returns a channel that is closed when the breaker next
+// resets.
+func (cb *Breaker) closedSignalChan() <-chan struct{} {
+	cb.queueLock.Lock()
+	defer cb.queueLock.Unlock()
+	if cb.closedSignal == nil {
+		cb.closedSignal = make(chan struct{})
+	}
+	return cb.closedSignal
+}
+
+// flushQueue wakes the calls parked while the breaker was open.
+func (cb *Breaker) flushQueue() {
+	if cb.queueSize == 0 {
+		return
+	}
+	cb.queueLock.Lock()
+	if cb.closedSignal != nil {
+		close(cb.closedSignal)
+		cb.closedSignal = nil
+	}
+	cb.queueLock.Unlock()
+}
@@ -0,0 +1,89 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+// waitQueued waits for n calls to be parked by cb.
+func waitQueued(t *testing.T, cb *Breaker, n int64) {
+	t.Helper()
+	for i := 0; cb.Queued() != n; i++ {
+		if i == 100 {
+			t.Fatalf("expected %d queued calls, got %d", n, cb.Queued())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestQueueFlushOnReset(t *testing.T) {
+	cb := NewBreakerWithOptions(&Options{QueueSize: 1})
+	cb.Break()
+
+	done := make(chan error)
+	ran := false
+	go func() {
+		done <- cb.Call(func() error {
+			ran = true
+			return nil
+		}, 0)
+	}()
+	waitQueued(t, cb, 1)
+	if s := cb.Stats(); s.Queued != 1 {
+		t.Fatalf("expected Stats to report 1 queued call, got %d", s.Queued)
+	}
+
+	if err := cb.Call(func() error { return nil }, 0); err != ErrBreakerOpen {
+		t.Fatalf("expected a call beyond the queue to be rejected, got %v", err)
+	}
+
+	cb.Reset()
+	if err := <-done; err != nil || !ran {
+		t.Fatalf("expected the queued call to be made once the breaker closed, got %v", err)
+	}
+	if cb.Queued() != 0 || cb.Successes() != 1 {
+		t.Fatal("expected the queued call to leave the queue and be recorded")
+	}
+}
+
+func TestQueueTimeout(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := NewBreakerWithOptions(&Options{Clock: c, QueueSize: 1, QueueTimeout: time.Minute})
+	cb.Break()
+
+	done := make(chan error)
+	go func() {
+		done <- cb.Call(func() error { return nil }, 0)
+	}()
+	waitQueued(t, cb, 1)
+	c.Add(time.Minute)
+	if err := <-done; err != ErrQueueTimeout {
+		t.Fatalf("expected ErrQueueTimeout, got %v", err)
+	}
+	if cb.Queued() != 0 || cb.Rejects() != 1 {
+		t.Fatalf("expected the timed out call to be rejected, got %d rejects", cb.Rejects())
+	}
+}
+
+func TestQueueTrialCall(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := NewBreakerWithOptions(&Options{Clock: c, QueueSize: 1, QueueTimeout: time.Hour})
+	cb.Trip()
+
+	done := make(chan error)
+	go func() {
+		done <- cb.Call(func() error { return nil }, 0)
+	}()
+	waitQueued(t, cb, 1)
+	c.Add(time.Minute)
+	if err := <-done; err != nil {
+		t.Fatalf("expected the queued call to be made as the trial call, got %v", err)
+	}
+	if cb.Tripped() {
+		t.Fatal("expected the successful trial call to reset the breaker")
+	}
+}
@@ -0,0 +1,74 @@
+package circuit
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff"
+)
+
+var (
+	// randSeq distinguishes the seeds of breakers created at the same
+	// instant.
+	randSeq int64
+
+	// globalRand is used by a Selector created without NewSelector.
+	globalRand = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
+
+	// seedLock serializes draws from the sources given as Options.Rand,
+	// which breakers in a panel may share.
+	seedLock sync.Mutex
+)
+
+// lockedSource is a rand.Source that is safe for concurrent use.
+type lockedSource struct {
+	lock sync.Mutex
+	src  rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.lock.Lock()
+	n := s.src.Int63()
+	s.lock.Unlock()
+	return n
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.lock.Lock()
+	s.src.Seed(seed)
+	s.lock.Unlock()
+}
+
+// newRand returns a *rand.Rand that is safe for concurrent use, with a source
+// seeded from src, or from the time if src is nil.
+func newRand(src rand.Source) *rand.Rand {
+	var seed int64
+	if src != nil {
+		seedLock.Lock()
+		seed = src.Int63()
+		seedLock.Unlock()
+	} else {
+		seed = time.Now().UnixNano() + atomic.AddInt64(&randSeq, 1)
+	}
+	return rand.New(&lockedSource{src: rand.NewSource(seed)})
+}
+
+// jitterBackOff randomizes the intervals of a BackOff by up to factor either
+// way, drawing from rng rather than the global source the backoff package
+// uses.
+type jitterBackOff struct {
+	backoff.BackOff
+	factor float64
+	rng    *rand.Rand
+}
+
+func (b *jitterBackOff) NextBackOff() time.Duration {
+	d := b.BackOff.NextBackOff()
+	if d == backoff.Stop {
+		return d
+	}
+	delta := b.factor * float64(d)
+	return time.Duration(float64(d) - delta + b.rng.Float64()*(2*delta+1))
+}
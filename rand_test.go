@@ -0,0 +1,68 @@
+package circuit
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSeededBackOff(t *testing.T) {
+	a := New(WithSeed(42))
+	b := New(WithSeed(42))
+	for i := 0; i < 5; i++ {
+		da, db := a.BackOff.NextBackOff(), b.BackOff.NextBackOff()
+		if da != db {
+			t.Fatalf("expected breakers with the same seed to back off alike, got %s and %s", da, db)
+		}
+	}
+
+	c := New()
+	c.BackOff.Reset()
+	d := c.BackOff.NextBackOff()
+	if d < defaultInitialBackOffInterval/2 || d > defaultInitialBackOffInterval*3/2 {
+		t.Fatalf("expected the first interval to be jittered around %s, got %s", defaultInitialBackOffInterval, d)
+	}
+}
+
+func TestSeededFaults(t *testing.T) {
+	outcomes := func(src rand.Source) []bool {
+		f := NewFaultInjector()
+		f.Set(Faults{FailureRate: 0.5})
+		cb := NewBreakerWithOptions(&Options{FaultInjector: f, Rand: src})
+		var failed []bool
+		for i := 0; i < 20; i++ {
+			failed = append(failed, cb.Call(func() error { return nil }, time.Second) == ErrInjectedFault)
+		}
+		return failed
+	}
+
+	a, b := outcomes(rand.NewSource(7)), outcomes(rand.NewSource(7))
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("expected the same faults from the same seed, got %v and %v", a, b)
+		}
+	}
+}
+
+func TestSelectorSetRand(t *testing.T) {
+	picks := func() []string {
+		s := NewSelector()
+		s.SetRand(rand.NewSource(3))
+		for _, name := range []string{"a", "b", "c"} {
+			s.Add(name, NewBreaker())
+		}
+		var names []string
+		for i := 0; i < 10; i++ {
+			name, _, _ := s.Select()
+			names = append(names, name)
+		}
+		return names
+	}
+
+	a, b := picks(), picks()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("expected the same picks from the same seed, got %v and %v", a, b)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package circuit
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ReadinessHandler is an http.Handler for readiness probes, such as those of
+// Kubernetes, that reports the process unready while any of a set of critical
+// breakers in a Panel has been tripped for longer than a grace period. Taking
+// an instance out of rotation lets traffic move to instances whose
+// dependencies are reachable, while the grace period keeps short trips from
+// flapping the whole fleet.
+//
+// The handler responds with 200 when ready and 503 when not, listing the
+// breakers that have been tripped for too long.
+type ReadinessHandler struct {
+	// Panel holds the breakers.
+	Panel *Panel
+
+	// Critical are the names of the breakers that affect readiness. Names
+	// missing from the panel are ignored.
+	Critical []string
+
+	// GracePeriod is how long a critical breaker may stay tripped before the
+	// process is reported unready.
+	GracePeriod time.Duration
+}
+
+// NewReadinessHandler creates a ReadinessHandler for the named critical
+// breakers in p.
+func NewReadinessHandler(p *Panel, gracePeriod time.Duration, critical ...string) *ReadinessHandler {
+	return &ReadinessHandler{Panel: p, Critical: critical, GracePeriod: gracePeriod}
+}
+
+// Unready returns the names of the critical breakers that have been tripped
+// for longer than the grace period, sorted by name. Disabled breakers are
+// never unready, as they let calls through, and neither are breakers whose
+// trip time is unknown.
+func (h *ReadinessHandler) Unready() []string {
+	var unready []string
+	for _, name := range h.Critical {
+		cb, ok := h.Panel.Get(name)
+		if !ok {
+			continue
+		}
+		if cb.State() == StateClosed {
+			continue
+		}
+		trippedAt := cb.Stats().TrippedAt
+		if !trippedAt.IsZero() && cb.Clock.Now().Sub(trippedAt) > h.GracePeriod {
+			unready = append(unready, name)
+		}
+	}
+	sort.Strings(unready)
+	return unready
+}
+
+// ServeHTTP implements http.Handler.
+func (h *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	unready := h.Unready()
+	if len(unready) == 0 {
+		fmt.Fprintln(w, "ok")
+		return
+	}
+
+	w.WriteHeader(http.StatusServiceUnavailable)
+	for _, name := range unready {
+		fmt.Fprintf(w, "circuit breaker %s open\n", name)
+	}
+}
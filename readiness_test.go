@@ -0,0 +1,77 @@
+package circuit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	p := NewPanel()
+	db := NewBreakerWithOptions(&Options{Clock: c})
+	cache := NewBreakerWithOptions(&Options{Clock: c})
+	p.Add("db", db)
+	p.Add("cache", cache)
+
+	h := NewReadinessHandler(p, 30*time.Second, "db", "missing")
+	probe := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+		return w
+	}
+
+	if w := probe(); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with all breakers closed, got %d", w.Code)
+	}
+
+	cache.Break()
+	db.Break()
+	c.Add(10 * time.Second)
+	if w := probe(); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 within the grace period, got %d", w.Code)
+	}
+
+	c.Add(time.Minute)
+	w := probe()
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 after the grace period, got %d", w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "db") || strings.Contains(body, "cache") {
+		t.Fatalf("expected only the critical breaker to be listed, got %q", body)
+	}
+
+	db.Disable()
+	if w := probe(); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 while the breaker is disabled, got %d", w.Code)
+	}
+	db.Enable()
+
+	db.Reset()
+	if w := probe(); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 after the breaker reset, got %d", w.Code)
+	}
+}
+
+func TestReadinessRestoredWithoutTripTime(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	p := NewPanel()
+	db := NewBreakerWithOptions(&Options{Clock: c})
+	p.Add("db", db)
+	db.restoreStats(Stats{Tripped: true})
+
+	h := NewReadinessHandler(p, 30*time.Second, "db")
+	if unready := h.Unready(); len(unready) != 0 {
+		t.Fatalf("expected a breaker restored without a trip time to start its grace period, got %v", unready)
+	}
+	c.Add(time.Minute)
+	if unready := h.Unready(); len(unready) != 1 {
+		t.Fatalf("expected the breaker to be unready after the grace period, got %v", unready)
+	}
+}
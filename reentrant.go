@@ -0,0 +1,73 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrReentrantCall is returned by CallContext and Do for a call made through a
+// breaker from inside a call already made through it, under ReentrantReject.
+var ErrReentrantCall = errors.New("breaker called reentrantly")
+
+// ReentrancyPolicy is what a breaker does with reentrant calls: calls made
+// through it from inside a call already made through it. The outcome of a
+// reentrant call is recorded twice, once for itself and once as part of the
+// enclosing call, which skews the breaker's statistics. See
+// Options.ReentrancyPolicy.
+//
+// Reentrant calls are detected through the context passed to Do's function,
+// so only calls made with that context, or one derived from it, are detected.
+type ReentrancyPolicy int
+
+const (
+	// ReentrantAllow makes reentrant calls as usual.
+	ReentrantAllow ReentrancyPolicy = iota
+
+	// ReentrantReject fails reentrant calls with ErrReentrantCall without
+	// making them. They are counted as rejected.
+	ReentrantReject
+
+	// ReentrantPanic panics on a reentrant call, to find them in tests.
+	ReentrantPanic
+)
+
+// callKey is the context key marking the contexts of calls made through cb.
+type callKey struct {
+	cb *Breaker
+}
+
+// Do is CallContext for functions that take a context. The context passed to
+// fn is derived from ctx and carries the call's CallInfo. It also marks it as
+// being inside a call through the breaker, so that calls made through the
+// breaker with it are detected as reentrant. See Options.ReentrancyPolicy.
+func (cb *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error, timeout time.Duration) error {
+	return cb.do(ctx, fn, timeout, nil)
+}
+
+// do is Do, but for the call token was taken for if it is non-nil. See
+// callWith.
+func (cb *Breaker) do(ctx context.Context, fn func(ctx context.Context) error, timeout time.Duration, token *ProbeToken) error {
+	callCtx := context.WithValue(ctx, callKey{cb}, true)
+	return cb.callWith(ctx, func(info CallInfo) error {
+		return fn(context.WithValue(callCtx, callInfoKey{}, info))
+	}, 1, timeout, token)
+}
+
+// InCall reports whether ctx is the context of a call made through the
+// breaker with Do, or derived from one.
+func (cb *Breaker) InCall(ctx context.Context) bool {
+	return ctx.Value(callKey{cb}) != nil
+}
+
+// checkReentry applies the breaker's ReentrancyPolicy to a call made with ctx.
+func (cb *Breaker) checkReentry(ctx context.Context) error {
+	if cb.onReentry == ReentrantAllow || !cb.InCall(ctx) {
+		return nil
+	}
+	if cb.onReentry == ReentrantPanic {
+		panic("circuit: breaker " + cb.name + " called reentrantly")
+	}
+	cb.reject()
+	return ErrReentrantCall
+}
@@ -0,0 +1,52 @@
+package circuit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReentrantCalls(t *testing.T) {
+	ctx := context.Background()
+	nested := func(cb *Breaker) error {
+		return cb.Do(ctx, func(ctx context.Context) error {
+			if !cb.InCall(ctx) {
+				t.Fatal("expected the context to be marked as inside a call")
+			}
+			return cb.CallContext(ctx, func() error { return nil }, 0)
+		}, 0)
+	}
+
+	cb := NewBreaker()
+	if err := nested(cb); err != nil {
+		t.Fatalf("expected reentrant calls to be allowed by default, got %v", err)
+	}
+	if n := cb.Successes(); n != 2 {
+		t.Fatalf("expected both calls to be recorded, got %d", n)
+	}
+	if cb.InCall(ctx) {
+		t.Fatal("expected a plain context not to be inside a call")
+	}
+
+	cb = NewBreakerWithOptions(&Options{ReentrancyPolicy: ReentrantReject})
+	if err := nested(cb); err != ErrReentrantCall {
+		t.Fatalf("expected ErrReentrantCall, got %v", err)
+	}
+	if cb.Rejects() != 1 {
+		t.Fatalf("expected the reentrant call to be rejected, got %d rejects", cb.Rejects())
+	}
+	other := NewBreakerWithOptions(&Options{ReentrancyPolicy: ReentrantReject})
+	err := cb.Do(ctx, func(ctx context.Context) error {
+		return other.CallContext(ctx, func() error { return nil }, 0)
+	}, 0)
+	if err != nil {
+		t.Fatalf("expected calls through other breakers to be allowed, got %v", err)
+	}
+
+	cb = NewBreakerWithOptions(&Options{ReentrancyPolicy: ReentrantPanic})
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a reentrant call to panic")
+		}
+	}()
+	nested(cb)
+}
@@ -0,0 +1,101 @@
+package circuit
+
+import "sync/atomic"
+
+// Rollup returns the panel's roll-up breaker, creating it with opts the first
+// time it is called. The outcomes of the calls of every breaker in the panel,
+// including those added later, are also recorded on the roll-up breaker, so
+// its window holds the sum of theirs and its ShouldTrip can trip a
+// whole-service degradation mode once the overall error volume is too high:
+//
+//	rollup := panel.Rollup(&circuit.Options{ShouldTrip: circuit.RateTripFunc(0.2, 500)})
+//	...
+//	if rollup.Tripped() {
+//		serveDegraded(w, r)
+//	}
+//
+// Unlike a parent breaker, the roll-up breaker never rejects the calls of the
+// panel's breakers. It is not in the panel itself, so Get, Range and Stats
+// leave it out. Once tripped, it resets on the first success recorded after
+// its backoff has elapsed, as a breaker does on a successful trial call.
+func (p *Panel) Rollup(opts *Options) *Breaker {
+	p.panelLock.Lock()
+	defer p.panelLock.Unlock()
+	if p.rollup != nil {
+		return p.rollup
+	}
+	p.rollup = NewBreakerWithOptions(mergeOptions(nil, opts))
+	for _, cb := range p.Circuits {
+		cb.addRollup(p.rollup)
+	}
+	return p.rollup
+}
+
+// addRollup makes the breaker record its outcomes on r as well.
+func (cb *Breaker) addRollup(r *Breaker) {
+	cb.eventLock.Lock()
+	defer cb.eventLock.Unlock()
+	rollups := cb.rollupList()
+	for _, existing := range rollups {
+		if existing == r {
+			return
+		}
+	}
+	cb.rollups.Store(append(rollups[:len(rollups):len(rollups)], r))
+}
+
+// removeRollup undoes addRollup.
+func (cb *Breaker) removeRollup(r *Breaker) {
+	cb.eventLock.Lock()
+	defer cb.eventLock.Unlock()
+	var rollups []*Breaker
+	for _, existing := range cb.rollupList() {
+		if existing != r {
+			rollups = append(rollups, existing)
+		}
+	}
+	cb.rollups.Store(rollups)
+}
+
+// rollupList returns the roll-up breakers the breaker records its outcomes on.
+func (cb *Breaker) rollupList() []*Breaker {
+	rollups, _ := cb.rollups.Load().([]*Breaker)
+	return rollups
+}
+
+// callRollups returns the roll-up breakers a call made through the breaker is
+// recorded on: those of the breaker and of its ancestors, each once, as a
+// parent is often in the same panel as its children.
+func (cb *Breaker) callRollups() []*Breaker {
+	rollups := cb.rollupList()
+	for p := cb.parent; p != nil; p = p.parent {
+		for _, r := range p.rollupList() {
+			if !containsBreaker(rollups, r) {
+				rollups = append(rollups[:len(rollups):len(rollups)], r)
+			}
+		}
+	}
+	return rollups
+}
+
+func containsBreaker(breakers []*Breaker, cb *Breaker) bool {
+	for _, b := range breakers {
+		if b == cb {
+			return true
+		}
+	}
+	return false
+}
+
+// rollupSuccess records n successes of the panel's breakers. While the
+// roll-up breaker is open or unavailable, they only count toward its window.
+func (cb *Breaker) rollupSuccess(n int64, cost float64) {
+	if cb.rejecting() {
+		atomic.StoreInt64(&cb.consecFailures, 0)
+		cb.counts.SuccessN(n, cost)
+		cb.noteRate()
+		cb.updatePressure()
+		return
+	}
+	cb.success(n, cost)
+}
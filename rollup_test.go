@@ -0,0 +1,93 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestPanelRollup(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	p := NewPanel()
+	db := NewBreaker()
+	p.Add("db", db)
+	rollup := p.Rollup(&Options{Clock: c, ShouldTrip: ThresholdTripFunc(3)})
+	if p.Rollup(nil) != rollup {
+		t.Fatal("expected the panel to have a single roll-up breaker")
+	}
+	cache := NewBreaker()
+	p.Add("cache", cache)
+
+	boom := errors.New("boom")
+	db.Fail(boom)
+	cache.Fail(boom)
+	cache.Success()
+	if rollup.Failures() != 2 || rollup.Successes() != 1 || rollup.Tripped() {
+		t.Fatalf("expected the roll-up to sum the members' windows, got %d failures and %d successes",
+			rollup.Failures(), rollup.Successes())
+	}
+	if _, ok := p.Get(""); ok || len(p.Stats()) != 2 {
+		t.Fatal("expected the roll-up breaker to stay out of the panel")
+	}
+
+	db.Fail(boom)
+	if !rollup.Tripped() || db.Tripped() || cache.Tripped() {
+		t.Fatal("expected only the roll-up breaker to trip")
+	}
+	if err := db.Call(func() error { return nil }, 0); err != nil {
+		t.Fatalf("expected the members' calls to be let through, got %v", err)
+	}
+	if !rollup.Tripped() {
+		t.Fatal("expected a success before the backoff elapsed not to reset the roll-up")
+	}
+	c.Add(time.Minute)
+	cache.Success()
+	if rollup.Tripped() {
+		t.Fatal("expected a success after the backoff to reset the roll-up")
+	}
+
+	p.Remove("cache")
+	cache.Fail(boom)
+	if rollup.Failures() != 0 {
+		t.Fatal("expected a removed breaker to stop recording on the roll-up")
+	}
+
+	rollup.Trip()
+	rollup.SetUnavailable("maintenance")
+	c.Add(time.Minute)
+	db.Success()
+	if !rollup.Tripped() {
+		t.Fatal("expected a success not to reset an unavailable roll-up")
+	}
+	rollup.SetAvailable()
+	db.Success()
+	if rollup.Tripped() {
+		t.Fatal("expected a success to reset the roll-up once available")
+	}
+}
+
+func TestPanelRollupWithParent(t *testing.T) {
+	p := NewPanel()
+	rollup := p.Rollup(nil)
+	parent := p.AddWithOptions("service", nil)
+	child := p.AddWithOptions("endpoint", &Options{Parent: parent})
+	orphan := NewBreakerWithOptions(&Options{Parent: parent})
+
+	child.Fail(errors.New("boom"))
+	child.Success()
+	if child.Failures() != 1 || parent.Failures() != 1 || rollup.Failures() != 1 {
+		t.Fatalf("expected the failure to be counted once on each breaker, got child=%d parent=%d rollup=%d",
+			child.Failures(), parent.Failures(), rollup.Failures())
+	}
+	if rollup.Successes() != 1 {
+		t.Fatalf("expected the success to be counted once on the roll-up, got %d", rollup.Successes())
+	}
+
+	orphan.Fail(errors.New("boom"))
+	if rollup.Failures() != 2 {
+		t.Fatalf("expected a child outside the panel to be counted through its parent, got %d", rollup.Failures())
+	}
+}
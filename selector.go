@@ -0,0 +1,123 @@
+package circuit
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// minSelectorLatency bounds the latency used to weight backends, so a backend
+// that answers in no time at all does not take all of the traffic.
+const minSelectorLatency = time.Millisecond
+
+// Selector picks among backends that are each protected by their own breaker,
+// such as the replicas of a service. Backends whose breakers are open are never
+// selected. The others are chosen at random, weighted by the error rate and
+// mean latency over their breakers' windows, so callers automatically prefer
+// the healthiest replica while still sending some traffic to the rest.
+type Selector struct {
+	backends []selectorBackend
+	rng      *rand.Rand
+	lock     sync.RWMutex
+}
+
+type selectorBackend struct {
+	name string
+	cb   *Breaker
+}
+
+// NewSelector creates an empty Selector.
+func NewSelector() *Selector {
+	return &Selector{rng: newRand(nil)}
+}
+
+// SetRand makes the Selector's choices draw from a source seeded from src,
+// so that a seeded src makes them reproducible. See Options.Rand.
+func (s *Selector) SetRand(src rand.Source) {
+	s.lock.Lock()
+	s.rng = newRand(src)
+	s.lock.Unlock()
+}
+
+// Add adds a backend with the given name and breaker.
+func (s *Selector) Add(name string, cb *Breaker) {
+	s.lock.Lock()
+	s.backends = append(s.backends, selectorBackend{name: name, cb: cb})
+	s.lock.Unlock()
+}
+
+// Select returns the name and breaker of the backend to use. ok is false if
+// the breakers of all backends are open.
+//
+// A backend's weight is (1 - e)^2 / l, where e is its error rate and l its
+// mean latency. Backends without latency samples are given the mean latency of
+// the others.
+func (s *Selector) Select() (name string, cb *Breaker, ok bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var candidates []selectorBackend
+	var latencies []time.Duration
+	var sumLatency time.Duration
+	var timed int
+	for _, b := range s.backends {
+		if b.cb.rejecting() {
+			continue
+		}
+		l := b.cb.MeanLatency()
+		if l > 0 {
+			sumLatency += l
+			timed++
+		}
+		candidates = append(candidates, b)
+		latencies = append(latencies, l)
+	}
+	if len(candidates) == 0 {
+		return "", nil, false
+	}
+	rng := s.rng
+	if rng == nil {
+		rng = globalRand
+	}
+
+	defaultLatency := minSelectorLatency
+	if timed > 0 {
+		defaultLatency = sumLatency / time.Duration(timed)
+	}
+
+	weights := make([]float64, len(candidates))
+	var total float64
+	for i, b := range candidates {
+		l := latencies[i]
+		if l == 0 {
+			l = defaultLatency
+		}
+		if l < minSelectorLatency {
+			l = minSelectorLatency
+		}
+		health := 1 - b.cb.ErrorRate()
+		if math.IsNaN(health) {
+			// No calls yet; treat it as healthy.
+			health = 1
+		}
+		weights[i] = health * health / l.Seconds()
+		total += weights[i]
+	}
+
+	if total == 0 {
+		// Every candidate is failing; spread the trial calls evenly.
+		b := candidates[rng.Intn(len(candidates))]
+		return b.name, b.cb, true
+	}
+
+	r := rng.Float64() * total
+	for i, b := range candidates {
+		r -= weights[i]
+		if r < 0 {
+			return b.name, b.cb, true
+		}
+	}
+	b := candidates[len(candidates)-1]
+	return b.name, b.cb, true
+}
@@ -0,0 +1,63 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSelectorPrefersHealthyBackends(t *testing.T) {
+	healthy, failing, slow := NewBreaker(), NewBreaker(), NewBreaker()
+	for i := 0; i < 10; i++ {
+		healthy.counts.Observe(5 * time.Millisecond)
+		healthy.Success()
+		failing.counts.Observe(5 * time.Millisecond)
+		if i < 8 {
+			failing.Fail(nil)
+		} else {
+			failing.Success()
+		}
+		slow.counts.Observe(500 * time.Millisecond)
+		slow.Success()
+	}
+
+	s := NewSelector()
+	s.Add("healthy", healthy)
+	s.Add("failing", failing)
+	s.Add("slow", slow)
+
+	picks := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		name, _, ok := s.Select()
+		if !ok {
+			t.Fatal("expected a backend to be selected")
+		}
+		picks[name]++
+	}
+	if picks["healthy"] < picks["failing"] || picks["healthy"] < picks["slow"] {
+		t.Fatalf("expected the healthy backend to be preferred, got %v", picks)
+	}
+}
+
+func TestSelectorSkipsOpenBackends(t *testing.T) {
+	a, b := NewBreaker(), NewBreaker()
+	s := NewSelector()
+	s.Add("a", a)
+	s.Add("b", b)
+
+	a.Break()
+	for i := 0; i < 100; i++ {
+		if name, _, _ := s.Select(); name != "b" {
+			t.Fatalf("expected open backend to be skipped, got %s", name)
+		}
+	}
+
+	b.Break()
+	if _, _, ok := s.Select(); ok {
+		t.Fatal("expected no backend to be selected when all are open")
+	}
+
+	b.Disable()
+	if name, _, _ := s.Select(); name != "b" {
+		t.Fatalf("expected a disabled backend to be selected, got %s", name)
+	}
+}
@@ -0,0 +1,67 @@
+package circuit
+
+import "time"
+
+// SLA is the service level expected of the dependency a breaker protects.
+// Declaring it with Options.SLA makes the breaker measure the dependency's
+// compliance over its window, see Breaker.SLACompliance, so the breaker can
+// double as the point where the dependency's SLO is measured.
+type SLA struct {
+	// LatencyP99 is the target 99th percentile latency of calls, or 0 for no
+	// latency target.
+	LatencyP99 time.Duration `json:"latency_p99"`
+
+	// Availability is the target fraction of calls that succeed, such as
+	// 0.999, or 0 for no availability target.
+	Availability float64 `json:"availability"`
+}
+
+// SLACompliance is how the calls in a breaker's window measure up to its SLA.
+type SLACompliance struct {
+	// Target is the breaker's SLA.
+	Target SLA `json:"target"`
+
+	// LatencyP99 is the 99th percentile latency of the calls in the window.
+	LatencyP99 time.Duration `json:"latency_p99"`
+
+	// Availability is the fraction of the calls in the window that
+	// succeeded, or 1 if no calls were made. Calls rejected without being
+	// made are not counted.
+	Availability float64 `json:"availability"`
+
+	// LatencyMet and AvailabilityMet report whether each target is met, and
+	// Met whether both are. A target of 0 is always met.
+	LatencyMet      bool `json:"latency_met"`
+	AvailabilityMet bool `json:"availability_met"`
+	Met             bool `json:"met"`
+}
+
+// SLA returns the breaker's SLA, or nil if it was not given one.
+func (cb *Breaker) SLA() *SLA {
+	if cb.sla == nil {
+		return nil
+	}
+	sla := *cb.sla
+	return &sla
+}
+
+// SLACompliance returns the compliance of the calls in the breaker's window
+// with its SLA, or nil if it was not given one.
+func (cb *Breaker) SLACompliance() *SLACompliance {
+	if cb.sla == nil {
+		return nil
+	}
+	c := &SLACompliance{
+		Target:       *cb.sla,
+		LatencyP99:   cb.LatencyQuantile(0.99),
+		Availability: 1,
+	}
+	failures, successes := cb.Failures(), cb.Successes()
+	if total := failures + successes; total > 0 {
+		c.Availability = float64(successes) / float64(total)
+	}
+	c.LatencyMet = c.Target.LatencyP99 == 0 || c.LatencyP99 <= c.Target.LatencyP99
+	c.AvailabilityMet = c.Availability >= c.Target.Availability
+	c.Met = c.LatencyMet && c.AvailabilityMet
+	return c
+}
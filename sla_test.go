@@ -0,0 +1,57 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestSLACompliance(t *testing.T) {
+	if NewBreaker().SLACompliance() != nil {
+		t.Fatal("expected no compliance without an SLA")
+	}
+
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	cb := NewBreakerWithOptions(&Options{
+		Clock: c,
+		SLA:   &SLA{LatencyP99: 100 * time.Millisecond, Availability: 0.9},
+	})
+
+	s := cb.SLACompliance()
+	if !s.Met || s.Availability != 1 {
+		t.Fatalf("expected an idle breaker to meet its SLA, got %+v", s)
+	}
+
+	call := func(latency time.Duration, err error) {
+		cb.Call(func() error {
+			c.Add(latency)
+			return err
+		}, 0)
+	}
+	for i := 0; i < 9; i++ {
+		call(10*time.Millisecond, nil)
+	}
+	call(10*time.Millisecond, errors.New("failed"))
+	s = cb.SLACompliance()
+	if s.Availability != 0.9 || !s.AvailabilityMet {
+		t.Fatalf("expected availability of 0.9 to be met, got %+v", s)
+	}
+	if !s.LatencyMet || !s.Met {
+		t.Fatalf("expected latency target to be met, got %+v", s)
+	}
+
+	call(time.Second, errors.New("failed"))
+	s = cb.Stats().SLA
+	if s == nil {
+		t.Fatal("expected Stats to include SLA compliance")
+	}
+	if s.AvailabilityMet || s.LatencyMet || s.Met {
+		t.Fatalf("expected both targets to be missed, got %+v", s)
+	}
+	if s.Target != *cb.SLA() {
+		t.Fatalf("expected target %+v, got %+v", *cb.SLA(), s.Target)
+	}
+}
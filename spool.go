@@ -0,0 +1,167 @@
+package circuit
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrSpoolFull is returned by Spool.Add when the message would take the spool
+// over its MaxBytes.
+var ErrSpoolFull = errors.New("spool full")
+
+// spoolExt is the extension of the files holding spooled messages.
+const spoolExt = ".msg"
+
+// Spool holds messages in a directory on local disk, one file per message, so
+// that they survive restarts until they can be sent. Messages are drained in
+// the order they were added. A Spool is safe for concurrent use, but only one
+// Spool may use a directory at a time. See Publisher.
+type Spool struct {
+	dir      string
+	maxBytes int64
+
+	lock  sync.Mutex
+	seq   uint64
+	size  int64
+	files int
+
+	drainLock sync.Mutex
+}
+
+// NewSpool creates a Spool in dir, creating the directory if needed. Messages
+// spooled in dir before, such as by a previous run of the process, are kept
+// and drained first. If maxBytes is non-zero, the spool holds at most
+// maxBytes of messages.
+func NewSpool(dir string, maxBytes int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	s := &Spool{dir: dir, maxBytes: maxBytes}
+	names, err := s.names()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		s.size += info.Size()
+		s.files++
+		if seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolExt), 10, 64); err == nil && seq > s.seq {
+			s.seq = seq
+		}
+	}
+	return s, nil
+}
+
+// Add spools msg. It returns ErrSpoolFull if the spool does not have room for
+// it.
+func (s *Spool) Add(msg []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.maxBytes != 0 && s.size+int64(len(msg)) > s.maxBytes {
+		return ErrSpoolFull
+	}
+
+	// Write to a temporary file first, so that a crash cannot leave a
+	// partial message to be drained.
+	name := fmt.Sprintf("%020d%s", s.seq+1, spoolExt)
+	tmp, err := ioutil.TempFile(s.dir, name+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(msg)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	s.seq++
+	s.size += int64(len(msg))
+	s.files++
+	return nil
+}
+
+// Len returns the number of messages in the spool.
+func (s *Spool) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.files
+}
+
+// Size returns the total size in bytes of the messages in the spool.
+func (s *Spool) Size() int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.size
+}
+
+// Drain calls send with each message in the spool, oldest first, and removes
+// the message once send returns nil. It stops at the first error, which it
+// returns, leaving that message and the ones after it in the spool. It
+// returns the number of messages sent. Messages added while Drain runs may be
+// left for the next call. Concurrent calls to Drain run one at a time.
+func (s *Spool) Drain(send func(msg []byte) error) (int, error) {
+	s.drainLock.Lock()
+	defer s.drainLock.Unlock()
+
+	s.lock.Lock()
+	names, err := s.names()
+	s.lock.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, name := range names {
+		path := filepath.Join(s.dir, name)
+		msg, err := ioutil.ReadFile(path)
+		if err != nil {
+			return sent, err
+		}
+		if err := send(msg); err != nil {
+			return sent, err
+		}
+		if err := os.Remove(path); err != nil {
+			return sent, err
+		}
+		sent++
+		s.lock.Lock()
+		s.size -= int64(len(msg))
+		s.files--
+		s.lock.Unlock()
+	}
+	return sent, nil
+}
+
+// names returns the names of the files of the spooled messages, oldest first.
+func (s *Spool) names() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), spoolExt) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
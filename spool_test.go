@@ -0,0 +1,71 @@
+package circuit
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewSpool(dir, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"one", "two", "three"} {
+		if err := s.Add([]byte(msg)); err != nil {
+			t.Fatalf("expected %s to be spooled, got %v", msg, err)
+		}
+	}
+	if err := s.Add([]byte("four")); err != ErrSpoolFull {
+		t.Fatalf("expected ErrSpoolFull, got %v", err)
+	}
+	if n, size := s.Len(), s.Size(); n != 3 || size != 11 {
+		t.Fatalf("expected 3 messages of 11 bytes, got %d of %d", n, size)
+	}
+
+	// A new spool in the same directory picks up where the last left off.
+	s, err = NewSpool(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add([]byte("four")); err != nil {
+		t.Fatal(err)
+	}
+
+	var sent []string
+	errDown := errors.New("down")
+	n, err := s.Drain(func(msg []byte) error {
+		if string(msg) == "three" {
+			return errDown
+		}
+		sent = append(sent, string(msg))
+		return nil
+	})
+	if n != 2 || err != errDown {
+		t.Fatalf("expected 2 messages sent before the error, got %d, %v", n, err)
+	}
+	if s.Len() != 2 {
+		t.Fatalf("expected 2 messages left, got %d", s.Len())
+	}
+
+	n, err = s.Drain(func(msg []byte) error {
+		sent = append(sent, string(msg))
+		return nil
+	})
+	if n != 2 || err != nil {
+		t.Fatalf("expected the remaining 2 messages sent, got %d, %v", n, err)
+	}
+	if got := len(sent); got != 4 || sent[0] != "one" || sent[1] != "two" || sent[2] != "three" || sent[3] != "four" {
+		t.Fatalf("expected messages drained in order, got %v", sent)
+	}
+	if s.Len() != 0 || s.Size() != 0 {
+		t.Fatalf("expected an empty spool, got %d messages of %d bytes", s.Len(), s.Size())
+	}
+}
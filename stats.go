@@ -0,0 +1,203 @@
+package circuit
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of a breaker's state and of the statistics over its
+// window.
+type Stats struct {
+	Tripped        bool          `json:"tripped"`
+	Broken         bool          `json:"broken"`
+	Disabled       bool          `json:"disabled"`
+	Unavailable    bool          `json:"unavailable"`
+	Failures       int64         `json:"failures"`
+	Successes      int64         `json:"successes"`
+	Rejects        int64         `json:"rejects"`
+	TLSFailures    int64         `json:"tls_failures"`
+	Abandoned      int64         `json:"abandoned"`
+	Queued         int64         `json:"queued"`
+	InFlight       int64         `json:"in_flight"`
+	Pressure       float64       `json:"pressure"`
+	DroppedEvents  int64         `json:"dropped_events"`
+	Samples        int64         `json:"samples"`
+	FailureScore   float64       `json:"failure_score"`
+	ConsecFailures int64         `json:"consec_failures"`
+	ErrorRate      float64       `json:"error_rate"`
+	MeanLatency    time.Duration `json:"mean_latency"`
+	LastFailure    time.Time     `json:"last_failure"`
+	RetryAfter     time.Duration `json:"retry_after"`
+
+	// UnavailableReason is the reason the breaker is unavailable, if it is.
+	// See Breaker.SetUnavailable.
+	UnavailableReason string `json:"unavailable_reason,omitempty"`
+
+	// FilledBuckets is the number of buckets of the window holding calls, and
+	// WindowAge the age of the oldest of them. See Breaker.WindowWarmth.
+	FilledBuckets int           `json:"filled_buckets"`
+	WindowAge     time.Duration `json:"window_age"`
+
+	// FailureCategories counts the failures in the window by category. See
+	// Breaker.CategoryFailures.
+	FailureCategories map[FailureCategory]int64 `json:"failure_categories,omitempty"`
+
+	// Extra holds the values of the breaker's BucketExtensions aggregated
+	// over the window, by key.
+	Extra map[string]float64 `json:"extra,omitempty"`
+
+	// BackOff is the current backoff interval and NextAttempt when a tripped
+	// breaker will allow a trial call. See Breaker.NextAttempt.
+	BackOff     time.Duration `json:"backoff"`
+	NextAttempt time.Time     `json:"next_attempt"`
+
+	// TripCause is the cause of the last trip. See Breaker.TripCause.
+	TripCause *TripCause `json:"trip_cause,omitempty"`
+
+	// SLA is the breaker's compliance with its SLA, if it has one. See
+	// Breaker.SLACompliance.
+	SLA *SLACompliance `json:"sla,omitempty"`
+
+	// Flaps is the number of trips and resets suppressed by
+	// Options.MinClosedDuration and MinOpenDuration.
+	Flaps int64 `json:"flaps"`
+
+	// CacheServed is the number of calls served from the breaker's Cache
+	// while it was open. See CachedCall.
+	CacheServed int64 `json:"cache_served"`
+
+	// Trips is the number of times the breaker has gone from closed to
+	// tripped, and Recoveries the number of times it has been reset since.
+	Trips      int64 `json:"trips"`
+	Recoveries int64 `json:"recoveries"`
+	// TrippedAt is when the breaker last tripped, or zero if it is closed.
+	TrippedAt time.Time `json:"tripped_at"`
+	// TimeOpen is the total time the breaker has spent tripped, including the
+	// current trip.
+	TimeOpen time.Duration `json:"time_open"`
+	// MTTR is the mean time to recovery: the mean time from a trip to the
+	// following reset.
+	MTTR time.Duration `json:"mttr"`
+
+	// OpenDurations is the distribution of the time from each trip to the
+	// following reset, and ClosedDurations of the time from each reset (or
+	// the breaker's creation) to the following trip.
+	OpenDurations   Histogram `json:"open_durations"`
+	ClosedDurations Histogram `json:"closed_durations"`
+}
+
+// Stats returns a snapshot of the breaker's state and statistics.
+func (cb *Breaker) Stats() Stats {
+	s := Stats{
+		Tripped:        cb.Tripped(),
+		Broken:         atomic.LoadInt32(&cb.broken) == 1,
+		Disabled:       cb.Disabled(),
+		Failures:       cb.Failures(),
+		Successes:      cb.Successes(),
+		Rejects:        cb.Rejects(),
+		TLSFailures:    cb.TLSFailures(),
+		Abandoned:      cb.Abandoned(),
+		Queued:         cb.Queued(),
+		InFlight:       cb.InFlight(),
+		Pressure:       cb.Pressure(),
+		DroppedEvents:  cb.DroppedEvents(),
+		FailureScore:   cb.FailureScore(),
+		ConsecFailures: cb.ConsecFailures(),
+		ErrorRate:      cb.ErrorRate(),
+		Samples:        cb.Samples(),
+		MeanLatency:    cb.MeanLatency(),
+		RetryAfter:     cb.RetryAfter(),
+		BackOff:        cb.BackOffInterval(),
+		NextAttempt:    cb.NextAttempt(),
+	}
+	s.FilledBuckets, s.WindowAge = cb.counts.Occupancy()
+	s.FailureCategories = cb.counts.Categories()
+	s.Extra = cb.extras()
+	if math.IsNaN(s.ErrorRate) {
+		// JSON cannot represent NaN; Samples tells that the rate is unknown.
+		s.ErrorRate = 0
+	}
+	if last := atomic.LoadInt64(&cb.lastFailure); last != 0 {
+		s.LastFailure = time.Unix(0, last)
+	}
+
+	s.UnavailableReason, s.Unavailable = cb.Unavailable()
+	s.TripCause = cb.TripCause()
+	s.SLA = cb.SLACompliance()
+	s.Trips = atomic.LoadInt64(&cb.trips)
+	s.Flaps = cb.Flaps()
+	s.CacheServed = cb.CacheServed()
+	s.Recoveries = atomic.LoadInt64(&cb.recoveries)
+	timeOpen := time.Duration(atomic.LoadInt64(&cb.timeOpen))
+	s.TimeOpen = timeOpen
+	if trippedAt := atomic.LoadInt64(&cb.trippedAt); trippedAt != 0 {
+		s.TrippedAt = time.Unix(0, trippedAt)
+		s.TimeOpen += cb.Clock.Now().Sub(s.TrippedAt)
+	}
+	s.MTTR = mttr(timeOpen, s.Recoveries)
+	s.OpenDurations = cb.openTimes.Snapshot()
+	s.ClosedDurations = cb.closedTimes.Snapshot()
+	return s
+}
+
+// mttr returns the mean time to recovery of recoveries that took timeOpen in
+// total, or 0 if there were none.
+func mttr(timeOpen time.Duration, recoveries int64) time.Duration {
+	if recoveries == 0 {
+		return 0
+	}
+	return timeOpen / time.Duration(recoveries)
+}
+
+// restoreStats sets the breaker's state and statistics to those in s without
+// sending events. The failures, successes and failure score are placed in the
+// current bucket of the window.
+func (cb *Breaker) restoreStats(s Stats) {
+	cb.counts.Seed(s.Failures, s.Successes, s.FailureScore)
+	atomic.StoreInt64(&cb.consecFailures, s.ConsecFailures)
+	if !s.LastFailure.IsZero() {
+		atomic.StoreInt64(&cb.lastFailure, s.LastFailure.UnixNano())
+	}
+	cb.eventLock.Lock()
+	cb.tripCause = s.TripCause
+	cb.eventLock.Unlock()
+	atomic.StoreInt64(&cb.trips, s.Trips)
+	atomic.StoreInt64(&cb.recoveries, s.Recoveries)
+	timeOpen := s.TimeOpen
+	var trippedAt int64
+	if s.Tripped && s.TrippedAt.IsZero() {
+		// The trip time was not saved, so the trip is counted from now.
+		trippedAt = cb.Clock.Now().UnixNano()
+	} else if !s.TrippedAt.IsZero() {
+		trippedAt = s.TrippedAt.UnixNano()
+		// TimeOpen includes the current trip, which the breaker counts from
+		// TrippedAt itself. s may have been taken a while ago, so the time
+		// spent open before the current trip is at least that of the
+		// recoveries.
+		timeOpen -= cb.Clock.Now().Sub(s.TrippedAt)
+		if before := s.MTTR * time.Duration(s.Recoveries); timeOpen < before {
+			timeOpen = before
+		}
+	}
+	atomic.StoreInt64(&cb.timeOpen, int64(timeOpen))
+	atomic.StoreInt64(&cb.trippedAt, trippedAt)
+	if s.BackOff != 0 {
+		cb.backoffLock.Lock()
+		cb.nextBackOff = s.BackOff
+		cb.backoffLock.Unlock()
+	}
+	cb.openTimes.Restore(s.OpenDurations)
+	cb.closedTimes.Restore(s.ClosedDurations)
+
+	var tripped, broken int32
+	if s.Tripped {
+		tripped = 1
+	}
+	if s.Broken {
+		broken = 1
+	}
+	atomic.StoreInt32(&cb.broken, broken)
+	atomic.StoreInt32(&cb.tripped, tripped)
+	atomic.StoreInt64(&cb.halfOpens, 0)
+}
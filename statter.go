@@ -0,0 +1,124 @@
+package circuit
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultStatsFlushInterval is the flush interval of a BatchStatter created
+// with an interval of 0.
+const DefaultStatsFlushInterval = time.Second
+
+// BatchStatter is a Statter that buffers the stats reported to it and passes
+// them on to another Statter periodically, rather than on every event. For a
+// busy breaker reporting to statsd, this turns a packet per failed call into a
+// packet per flush:
+//
+//	bs := circuit.NewBatchStatter(statsdClient, 5*time.Second)
+//	defer bs.Stop()
+//	panel.Statter = bs
+//
+// Counters are summed and gauges keep their last value until the next flush.
+// Timings are passed on together, so Statters that send one packet per call
+// send one per bucket.
+type BatchStatter struct {
+	statter  Statter
+	counters map[statKey]int
+	timings  map[statKey][]time.Duration
+	gauges   map[statKey]string
+	lock     sync.Mutex
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// statKey identifies the stats buffered by a BatchStatter.
+type statKey struct {
+	sampleRate float32
+	bucket     string
+}
+
+// NewBatchStatter creates a BatchStatter that flushes to s every interval, or
+// every DefaultStatsFlushInterval if interval is 0. Stop must be called to
+// stop flushing.
+func NewBatchStatter(s Statter, interval time.Duration) *BatchStatter {
+	if interval == 0 {
+		interval = DefaultStatsFlushInterval
+	}
+	bs := &BatchStatter{
+		statter:  s,
+		counters: make(map[statKey]int),
+		timings:  make(map[statKey][]time.Duration),
+		gauges:   make(map[statKey]string),
+		stop:     make(chan struct{}),
+	}
+	go bs.flushEvery(interval)
+	return bs
+}
+
+func (bs *BatchStatter) flushEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			bs.Flush()
+		case <-bs.stop:
+			return
+		}
+	}
+}
+
+// Counter implements Statter.
+func (bs *BatchStatter) Counter(sampleRate float32, bucket string, n ...int) {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+	for _, x := range n {
+		bs.counters[statKey{sampleRate, bucket}] += x
+	}
+}
+
+// Timing implements Statter.
+func (bs *BatchStatter) Timing(sampleRate float32, bucket string, d ...time.Duration) {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+	key := statKey{sampleRate, bucket}
+	bs.timings[key] = append(bs.timings[key], d...)
+}
+
+// Gauge implements Statter.
+func (bs *BatchStatter) Gauge(sampleRate float32, bucket string, value ...string) {
+	if len(value) == 0 {
+		return
+	}
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+	bs.gauges[statKey{sampleRate, bucket}] = value[len(value)-1]
+}
+
+// Flush passes the stats buffered since the last flush on.
+func (bs *BatchStatter) Flush() {
+	bs.lock.Lock()
+	counters, timings, gauges := bs.counters, bs.timings, bs.gauges
+	bs.counters = make(map[statKey]int, len(counters))
+	bs.timings = make(map[statKey][]time.Duration, len(timings))
+	bs.gauges = make(map[statKey]string, len(gauges))
+	bs.lock.Unlock()
+
+	for key, n := range counters {
+		bs.statter.Counter(key.sampleRate, key.bucket, n)
+	}
+	for key, d := range timings {
+		bs.statter.Timing(key.sampleRate, key.bucket, d...)
+	}
+	for key, v := range gauges {
+		bs.statter.Gauge(key.sampleRate, key.bucket, v)
+	}
+}
+
+// Stop stops flushing periodically, and flushes the stats still buffered.
+func (bs *BatchStatter) Stop() {
+	bs.stopOnce.Do(func() {
+		close(bs.stop)
+		bs.Flush()
+	})
+}
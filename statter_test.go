@@ -0,0 +1,96 @@
+package circuit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// callStatter is a Statter that records each call made to it.
+type callStatter struct {
+	calls  []string
+	gauges map[string]string
+	*testStatter
+	lock sync.Mutex
+}
+
+func (s *callStatter) Counter(sampleRate float32, bucket string, n ...int) {
+	s.lock.Lock()
+	s.calls = append(s.calls, bucket)
+	s.lock.Unlock()
+	s.testStatter.Counter(sampleRate, bucket, n...)
+}
+
+func (s *callStatter) Timing(sampleRate float32, bucket string, d ...time.Duration) {
+	s.lock.Lock()
+	s.calls = append(s.calls, bucket)
+	s.lock.Unlock()
+	s.testStatter.Timing(sampleRate, bucket, d...)
+}
+
+func (s *callStatter) Gauge(sampleRate float32, bucket string, value ...string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls = append(s.calls, bucket)
+	s.gauges[bucket] = value[len(value)-1]
+}
+
+func (s *callStatter) Calls() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.calls)
+}
+
+func TestBatchStatter(t *testing.T) {
+	s := &callStatter{testStatter: newTestStatter(), gauges: make(map[string]string)}
+	bs := NewBatchStatter(s, time.Hour)
+	defer bs.Stop()
+
+	for i := 0; i < 100; i++ {
+		bs.Counter(1, "circuit.db.fail", 1)
+		bs.Timing(1, "circuit.db.trip-time", time.Millisecond)
+	}
+	bs.Gauge(1, "circuit.db.trips", "1")
+	bs.Gauge(1, "circuit.db.trips", "2")
+	if s.Calls() != 0 {
+		t.Fatal("expected nothing to be passed on before a flush")
+	}
+
+	bs.Flush()
+	if n := s.Calls(); n != 3 {
+		t.Fatalf("expected a call per bucket, got %d", n)
+	}
+	if c := s.Count("circuit.db.fail"); c != 100 {
+		t.Fatalf("expected counters to be summed to 100, got %d", c)
+	}
+	if d := s.Time("circuit.db.trip-time"); d != 100*time.Millisecond {
+		t.Fatalf("expected all timings to be passed on, got %v", d)
+	}
+	if v := s.gauges["circuit.db.trips"]; v != "2" {
+		t.Fatalf("expected the last gauge value, got %s", v)
+	}
+
+	bs.Flush()
+	if n := s.Calls(); n != 3 {
+		t.Fatalf("expected an empty flush to pass nothing on, got %d calls", n)
+	}
+}
+
+func TestBatchStatterFlushesPeriodically(t *testing.T) {
+	s := &callStatter{testStatter: newTestStatter(), gauges: make(map[string]string)}
+	bs := NewBatchStatter(s, 10*time.Millisecond)
+	defer bs.Stop()
+
+	p := NewPanel()
+	p.Statter = bs
+	cb := NewBreaker()
+	p.Add("db", cb)
+	cb.Fail(nil)
+
+	for i := 0; s.Count("circuit.db.fail") != 1; i++ {
+		if i == 500 {
+			t.Fatal("expected the failure to be flushed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
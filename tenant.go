@@ -0,0 +1,129 @@
+package circuit
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultMaxTenants is the number of tenants a Panel tracks breakers for when
+// its MaxTenants is 0.
+const DefaultMaxTenants = 1000
+
+// TenantPanel holds the breakers of one tenant of a Panel. See Panel.ForTenant.
+type TenantPanel struct {
+	tenant   string
+	panel    *Panel
+	defaults *Options
+	circuits map[string]*Breaker
+	evicted  bool
+	lock     sync.Mutex
+}
+
+// tenantSet tracks the tenants of a Panel, least recently used last.
+type tenantSet struct {
+	tenants map[string]*list.Element
+	lru     *list.List
+	lock    sync.Mutex
+}
+
+// ForTenant returns the breakers of tenant, for services where one tenant's
+// traffic should not trip the breakers used by others. The tenant's breakers
+// are created on first use with the panel's Defaults, and added to the panel
+// under "tenant/name", so that they are reported with its other breakers.
+//
+// At most MaxTenants tenants are tracked; when another is added, the breakers
+// of the least recently used tenant are removed from the panel and discarded.
+func (p *Panel) ForTenant(tenant string) *TenantPanel {
+	max := p.MaxTenants
+	if max <= 0 {
+		max = DefaultMaxTenants
+	}
+
+	p.panelLock.RLock()
+	defaults := p.Defaults
+	p.panelLock.RUnlock()
+
+	ts := &p.tenants
+	ts.lock.Lock()
+	if ts.tenants == nil {
+		ts.tenants = make(map[string]*list.Element)
+		ts.lru = list.New()
+	}
+	if e, ok := ts.tenants[tenant]; ok {
+		ts.lru.MoveToFront(e)
+		ts.lock.Unlock()
+		return e.Value.(*TenantPanel)
+	}
+
+	tp := &TenantPanel{
+		tenant:   tenant,
+		panel:    p,
+		defaults: defaults,
+		circuits: make(map[string]*Breaker),
+	}
+	ts.tenants[tenant] = ts.lru.PushFront(tp)
+	var evicted []*TenantPanel
+	for ts.lru.Len() > max {
+		oldest := ts.lru.Back()
+		ts.lru.Remove(oldest)
+		e := oldest.Value.(*TenantPanel)
+		delete(ts.tenants, e.tenant)
+		evicted = append(evicted, e)
+	}
+	ts.lock.Unlock()
+
+	// Removing breakers sends panel events, so it is done without holding
+	// the lock.
+	for _, e := range evicted {
+		e.discard()
+	}
+	return tp
+}
+
+// discard removes the breakers of an evicted tenant from the panel, and
+// detaches them from their parents.
+func (tp *TenantPanel) discard() {
+	tp.lock.Lock()
+	defer tp.lock.Unlock()
+	tp.evicted = true
+	for name, cb := range tp.circuits {
+		if c, ok := tp.panel.Get(tp.key(name)); ok && c == cb {
+			tp.panel.Remove(tp.key(name))
+		}
+		cb.detach()
+	}
+}
+
+// key is the name the tenant's breaker for name is added to the panel under.
+func (tp *TenantPanel) key(name string) string {
+	return tp.tenant + "/" + name
+}
+
+// Tenants returns the number of tenants whose breakers are tracked.
+func (p *Panel) Tenants() int {
+	p.tenants.lock.Lock()
+	defer p.tenants.lock.Unlock()
+	return len(p.tenants.tenants)
+}
+
+// Get returns the tenant's breaker for name, creating it if needed. Once the
+// tenant has been evicted, the breakers it creates are no longer added to the
+// panel.
+func (tp *TenantPanel) Get(name string) *Breaker {
+	tp.lock.Lock()
+	defer tp.lock.Unlock()
+
+	cb, ok := tp.circuits[name]
+	if !ok {
+		opts := mergeOptions(tp.defaults, nil)
+		if opts.Name == "" {
+			opts.Name = tp.key(name)
+		}
+		cb = NewBreakerWithOptions(opts)
+		tp.circuits[name] = cb
+		if !tp.evicted {
+			tp.panel.Add(tp.key(name), cb)
+		}
+	}
+	return cb
+}
@@ -0,0 +1,46 @@
+package circuit
+
+import (
+	"testing"
+)
+
+func TestPanelForTenant(t *testing.T) {
+	p := NewPanel()
+	p.Defaults = &Options{ShouldTrip: ConsecutiveTripFunc(1)}
+	p.MaxTenants = 2
+
+	a := p.ForTenant("a").Get("db")
+	b := p.ForTenant("b").Get("db")
+	if a == b {
+		t.Fatal("expected each tenant to get its own breaker")
+	}
+	if p.ForTenant("a").Get("db") != a {
+		t.Fatal("expected the tenant's breaker to be reused")
+	}
+	if _, ok := p.Get("db"); ok {
+		t.Fatal("expected tenant breakers to be kept apart from the panel's")
+	}
+	if cb, ok := p.Get("a/db"); !ok || cb != a {
+		t.Fatal("expected tenant breakers to be added to the panel under the tenant's name")
+	}
+
+	a.Fail(nil)
+	if !a.Tripped() || b.Tripped() {
+		t.Fatal("expected one tenant's failures not to trip another's breaker")
+	}
+
+	// a was used more recently than b, so b is evicted.
+	p.ForTenant("c").Get("db")
+	if n := p.Tenants(); n != 2 {
+		t.Fatalf("expected 2 tracked tenants, got %d", n)
+	}
+	if p.ForTenant("a").Get("db") != a {
+		t.Fatal("expected the recently used tenant to be kept")
+	}
+	if _, ok := p.Get("b/db"); ok {
+		t.Fatal("expected the evicted tenant's breakers to be removed from the panel")
+	}
+	if p.ForTenant("b").Get("db") == b {
+		t.Fatal("expected the least recently used tenant to be evicted")
+	}
+}
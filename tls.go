@@ -0,0 +1,49 @@
+package circuit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"sync/atomic"
+)
+
+// IsTLSError reports whether err is a failed TLS handshake, such as one with a
+// peer whose certificate has expired, is for another host, or is signed by an
+// unknown authority. Such failures usually mean a configuration problem
+// rather than a transient outage, and are counted separately by the breaker.
+// See Breaker.TLSFailures and Options.TLSTripThreshold.
+func IsTLSError(err error) bool {
+	var (
+		verifyErr   *tls.CertificateVerificationError
+		recordErr   tls.RecordHeaderError
+		alertErr    tls.AlertError
+		invalidErr  x509.CertificateInvalidError
+		unknownErr  x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+	)
+	return errors.As(err, &verifyErr) ||
+		errors.As(err, &recordErr) ||
+		errors.As(err, &alertErr) ||
+		errors.As(err, &invalidErr) ||
+		errors.As(err, &unknownErr) ||
+		errors.As(err, &hostnameErr)
+}
+
+// TLSFailures returns the number of failures recorded since the breaker was
+// created whose error was a failed TLS handshake, as reported by IsTLSError.
+// They are also counted as ordinary failures.
+func (cb *Breaker) TLSFailures() int64 {
+	return atomic.LoadInt64(&cb.tlsFailures)
+}
+
+// tlsFail counts n failures caused by err if it is a TLS failure, and reports
+// whether the breaker's TLSTripThreshold has been reached.
+func (cb *Breaker) tlsFail(err error, n int64) bool {
+	if !IsTLSError(err) {
+		atomic.StoreInt64(&cb.tlsStreak, 0)
+		return false
+	}
+	atomic.AddInt64(&cb.tlsFailures, n)
+	consec := atomic.AddInt64(&cb.tlsStreak, n)
+	return cb.tlsThreshold > 0 && !cb.Tripped() && consec >= cb.tlsThreshold
+}
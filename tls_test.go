@@ -0,0 +1,57 @@
+package circuit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsTLSError(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	// The default client does not trust the test server's certificate.
+	_, err := http.Get(ts.URL)
+	if !IsTLSError(err) {
+		t.Fatalf("expected an unknown authority error to be a TLS error, got %v", err)
+	}
+	if IsTLSError(errors.New("connection refused")) {
+		t.Fatal("expected other errors not to be TLS errors")
+	}
+}
+
+func TestTLSTripThreshold(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	_, tlsErr := http.Get(ts.URL)
+
+	cb := NewBreakerWithOptions(&Options{
+		ShouldTrip:       ThresholdTripFunc(10),
+		TLSTripThreshold: 2,
+	})
+
+	cb.Fail(tlsErr)
+	cb.Fail(errors.New("500"))
+	cb.Fail(tlsErr)
+	if cb.Tripped() {
+		t.Fatal("expected other failures to break the run of TLS failures")
+	}
+	if n := cb.TLSFailures(); n != 2 {
+		t.Fatalf("expected 2 TLS failures, got %d", n)
+	}
+	if n := cb.Failures(); n != 3 {
+		t.Fatalf("expected TLS failures to count as failures, got %d", n)
+	}
+
+	cb.Fail(tlsErr)
+	if !cb.Tripped() {
+		t.Fatal("expected breaker to trip after 2 consecutive TLS failures")
+	}
+	if cause := cb.TripCause(); cause == nil || cause.err != tlsErr {
+		t.Fatalf("expected the TLS failure to be the trip cause, got %v", cause)
+	}
+	if n := cb.Stats().TLSFailures; n != 3 {
+		t.Fatalf("expected Stats to report 3 TLS failures, got %d", n)
+	}
+}
@@ -0,0 +1,142 @@
+package circuit
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Transport is an http.RoundTripper that protects requests with a circuit
+// breaker. It is useful when a client has to be handed an http.Client, or only
+// accepts an http.RoundTripper, so HTTPClient cannot be used.
+//
+// By default, the transport uses a single breaker. A BreakerLookup function may
+// be provided to select a breaker per request.
+type Transport struct {
+	// Transport makes the requests. http.DefaultTransport is used if nil.
+	Transport http.RoundTripper
+
+	// Breaker is used for requests when BreakerLookup is nil.
+	Breaker *Breaker
+
+	// BreakerLookup returns the breaker to use for a request, or nil to make
+	// the request without one.
+	BreakerLookup func(*http.Request) *Breaker
+
+	// OpenResponse, if non-nil, is called when the breaker for a request is
+	// open or unavailable. The response it returns is given to the caller in
+	// place of the breaker's error, which some HTTP frameworks handle better
+	// than transport errors. See ServiceUnavailableResponse.
+	OpenResponse func(req *http.Request, cb *Breaker) *http.Response
+
+	// ResponseError, if non-nil, returns the error to record for a response,
+	// or nil if it is a success. It lets protocols that report failures in
+	// the response, such as RPC frameworks over HTTP, have them recorded. The
+	// response is given to the caller either way. By default, every response
+	// is a success.
+	ResponseError func(resp *http.Response) error
+}
+
+// responseFailure is the error a Transport records for a response its
+// ResponseError counts as a failure.
+type responseFailure struct {
+	err error
+}
+
+func (f *responseFailure) Error() string { return f.err.Error() }
+func (f *responseFailure) Unwrap() error { return f.err }
+
+// NewTransport provides a circuit breaker wrapper around an http.RoundTripper.
+// Passing in nil will wrap http.DefaultTransport.
+func NewTransport(breaker *Breaker, rt http.RoundTripper) *Transport {
+	return &Transport{Transport: rt, Breaker: breaker}
+}
+
+// RoundTrip implements http.RoundTripper. If the breaker for req is open, the
+// request is not sent and RoundTrip returns ErrBreakerOpen, or the response
+// from OpenResponse if it is set. Requests the breaker rejects for other
+// reasons, such as ErrDeadlineTooShort, are not sent either, and fail with
+// the breaker's error.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cb := t.breaker(req)
+	if cb == nil {
+		return t.transport().RoundTrip(req)
+	}
+
+	var resp *http.Response
+	var sent int32
+	err := cb.CallContext(req.Context(), func() error {
+		atomic.StoreInt32(&sent, 1)
+		aresp, err := t.transport().RoundTrip(req)
+		resp = aresp
+		cb.noteUpstreamTrip(resp)
+		if err == nil && t.ResponseError != nil {
+			if rerr := t.ResponseError(resp); rerr != nil {
+				return &responseFailure{rerr}
+			}
+		}
+		return err
+	}, 0)
+
+	var failure *responseFailure
+	if errors.As(err, &failure) && resp != nil {
+		return resp, nil
+	}
+
+	if atomic.LoadInt32(&sent) == 1 {
+		return resp, err
+	}
+	if req.Body != nil {
+		// A RoundTripper must always close the request body.
+		req.Body.Close()
+	}
+	if t.OpenResponse != nil && rejectedOpen(err) {
+		return t.OpenResponse(req, cb), nil
+	}
+	return resp, err
+}
+
+func (t *Transport) breaker(req *http.Request) *Breaker {
+	if t.BreakerLookup != nil {
+		return t.BreakerLookup(req)
+	}
+	return t.Breaker
+}
+
+func (t *Transport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
+
+var openResponseBody = []byte(`{"error":"circuit breaker open"}`)
+
+// ServiceUnavailableResponse synthesizes a 503 Service Unavailable response to
+// req with a JSON body. If cb will retry, the Retry-After header is set to the
+// number of seconds until it does. It can be used as a Transport's
+// OpenResponse.
+func ServiceUnavailableResponse(req *http.Request, cb *Breaker) *http.Response {
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	if d := cb.RetryAfter(); d > 0 {
+		secs := (d + time.Second - 1) / time.Second
+		header.Set("Retry-After", strconv.FormatInt(int64(secs), 10))
+	}
+
+	return &http.Response{
+		Status:        "503 Service Unavailable",
+		StatusCode:    http.StatusServiceUnavailable,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(openResponseBody)),
+		ContentLength: int64(len(openResponseBody)),
+		Request:       req,
+	}
+}
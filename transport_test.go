@@ -0,0 +1,139 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	cb := NewBreaker()
+	client := &http.Client{Transport: NewTransport(cb, nil)}
+
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("expected request to succeed, got %v", err)
+	}
+	resp.Body.Close()
+	if s := cb.Successes(); s != 1 {
+		t.Fatalf("expected 1 success, got %d", s)
+	}
+
+	cb.Break()
+	if _, err := client.Get(ts.URL); err == nil {
+		t.Fatal("expected request to fail while the breaker is open")
+	}
+}
+
+func TestTransportOpenResponse(t *testing.T) {
+	c := clock.NewMock()
+	cb := NewBreaker()
+	cb.Clock = c
+	cb.Trip()
+	cb.nextBackOff = 1500 * time.Millisecond
+
+	transport := NewTransport(cb, nil)
+	transport.OpenResponse = ServiceUnavailableResponse
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get("http://example.invalid/")
+	if err != nil {
+		t.Fatalf("expected synthesized response, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", resp.StatusCode)
+	}
+	if ra := resp.Header.Get("Retry-After"); ra != "2" {
+		t.Fatalf("expected Retry-After of 2, got %q", ra)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != `{"error":"circuit breaker open"}` {
+		t.Fatalf("unexpected body %s", body)
+	}
+}
+
+func TestTransportResponseError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	cb := NewBreaker()
+	transport := NewTransport(cb, nil)
+	transport.ResponseError = func(resp *http.Response) error {
+		if resp.StatusCode >= 500 {
+			return errors.New(resp.Status)
+		}
+		return nil
+	}
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("expected the response to be returned, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", resp.StatusCode)
+	}
+	if f := cb.Failures(); f != 1 {
+		t.Fatalf("expected 1 failure, got %d", f)
+	}
+}
+
+// closeTracker is a request body recording whether it was closed.
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (b *closeTracker) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestTransportRejectedClosesBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		setup   func(cb *Breaker)
+		respond bool
+	}{
+		{"open", context.Background(), func(cb *Breaker) { cb.Break() }, true},
+		{"unavailable", context.Background(), func(cb *Breaker) { cb.SetUnavailable("maintenance") }, true},
+		{"budget", WithLatencyBudget(context.Background(), -time.Second), func(cb *Breaker) {}, false},
+	}
+
+	for _, test := range tests {
+		cb := NewBreaker()
+		test.setup(cb)
+		transport := NewTransport(cb, nil)
+		transport.OpenResponse = ServiceUnavailableResponse
+
+		body := &closeTracker{Reader: strings.NewReader("payload")}
+		req, _ := http.NewRequestWithContext(test.ctx, "POST", "http://example.invalid/", body)
+		resp, err := transport.RoundTrip(req)
+		if !body.closed {
+			t.Fatalf("%s: expected the request body to be closed", test.name)
+		}
+		if test.respond && (err != nil || resp.StatusCode != http.StatusServiceUnavailable) {
+			t.Fatalf("%s: expected the open response, got %v", test.name, err)
+		}
+		if !test.respond && err == nil {
+			t.Fatalf("%s: expected the breaker's error", test.name)
+		}
+	}
+}
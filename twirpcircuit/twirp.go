@@ -0,0 +1,104 @@
+// Package twirpcircuit integrates circuit breakers with Twirp clients.
+//
+// Twirp clients make their requests with an HTTP client, and Twirp errors are
+// error responses with a JSON body giving a code. NewTransport returns an
+// http.RoundTripper that records the responses whose code is the server's
+// fault as failures, for the client of a Twirp service:
+//
+//	httpClient := &http.Client{Transport: twirpcircuit.NewTransport(cb, nil)}
+//	client := example.NewHaberdasherProtobufClient(addr, httpClient)
+//
+// It needs no import of Twirp itself.
+package twirpcircuit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+// FailureCodes are the Twirp error codes that NewTransport records as
+// failures: those meaning the server or something on the way to it is in
+// trouble. Other codes, such as "invalid_argument" or "not_found", mean the
+// request itself was at fault, and are recorded as successes. The table may be
+// changed before transports are created.
+var FailureCodes = map[string]bool{
+	"deadline_exceeded":  true,
+	"resource_exhausted": true,
+	"internal":           true,
+	"unavailable":        true,
+	"dataloss":           true,
+	"unknown":            true,
+}
+
+// maxErrorBody is the most of an error response body read for its code.
+const maxErrorBody = 64 << 10
+
+// Error is a Twirp error response.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"msg"`
+}
+
+func (e *Error) Error() string {
+	return "twirp error " + e.Code + ": " + e.Message
+}
+
+// NewTransport returns a Transport protecting Twirp requests made with rt with
+// cb, recording responses with an error code in FailureCodes as failures.
+// Passing in a nil rt will wrap http.DefaultTransport.
+func NewTransport(cb *circuit.Breaker, rt http.RoundTripper) *circuit.Transport {
+	codes := make(map[string]bool, len(FailureCodes))
+	for code, fail := range FailureCodes {
+		codes[code] = fail
+	}
+	t := circuit.NewTransport(cb, rt)
+	t.ResponseError = func(resp *http.Response) error {
+		if err := ResponseError(resp); err != nil && codes[err.Code] {
+			return err
+		}
+		return nil
+	}
+	return t
+}
+
+// ResponseError returns the Twirp error of resp, or nil if it is a success.
+// The start of the body of an error response is read, and put back so that
+// the caller can read it again. Error responses without a Twirp error body, such as from a proxy, are
+// given the code Twirp clients give them based on their status.
+func ResponseError(resp *http.Response) *Error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+
+	var e Error
+	if err != nil || json.Unmarshal(body, &e) != nil || e.Code == "" {
+		return &Error{Code: statusCode(resp.StatusCode), Message: resp.Status}
+	}
+	return &e
+}
+
+// statusCode returns the code Twirp clients give an error response without a
+// Twirp error body.
+func statusCode(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "unauthenticated"
+	case http.StatusForbidden:
+		return "permission_denied"
+	case http.StatusNotFound:
+		return "bad_route"
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return "unavailable"
+	}
+	return "internal"
+}
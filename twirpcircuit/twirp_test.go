@@ -0,0 +1,65 @@
+package twirpcircuit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	circuit "github.com/cockroachdb/circuitbreaker"
+)
+
+func TestTransport(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		fail   bool
+	}{
+		{http.StatusOK, `{}`, false},
+		{http.StatusServiceUnavailable, `{"code":"unavailable","msg":"overloaded"}`, true},
+		{http.StatusInternalServerError, `{"code":"internal","msg":"boom"}`, true},
+		{http.StatusBadRequest, `{"code":"invalid_argument","msg":"bad hat size"}`, false},
+		{http.StatusNotFound, `{"code":"not_found","msg":"no such hat"}`, false},
+		{http.StatusBadGateway, `<html>bad gateway</html>`, true},
+		{http.StatusForbidden, `<html>forbidden</html>`, false},
+	}
+
+	for i, test := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		}))
+		cb := circuit.NewBreaker()
+		client := &http.Client{Transport: NewTransport(cb, nil)}
+
+		resp, err := client.Post(ts.URL+"/twirp/example.Haberdasher/MakeHat", "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			t.Fatalf("%d: expected the response to be returned, got %v", i, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		ts.Close()
+
+		if string(body) != test.body {
+			t.Fatalf("%d: expected the body to be intact, got %s", i, body)
+		}
+		if failed := cb.Failures() == 1; failed != test.fail {
+			t.Fatalf("%d: expected failure %v, got %d failures", i, test.fail, cb.Failures())
+		}
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"code":"unavailable","msg":"overloaded"}`)),
+	}
+	err := ResponseError(resp)
+	if err == nil || err.Code != "unavailable" || err.Message != "overloaded" {
+		t.Fatalf("unexpected error %+v", err)
+	}
+	if s := err.Error(); s != "twirp error unavailable: overloaded" {
+		t.Fatalf("unexpected error string %q", s)
+	}
+}
@@ -0,0 +1,76 @@
+package circuit
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrUnavailable is the error calls made through a breaker marked unavailable
+// with SetUnavailable match with errors.Is. They fail with an
+// *UnavailableError.
+var ErrUnavailable = errors.New("breaker dependency unavailable")
+
+// UnavailableError is the error of calls made through a breaker marked
+// unavailable with SetUnavailable.
+type UnavailableError struct {
+	// Breaker is the name of the breaker.
+	Breaker string
+
+	// Reason is the reason passed to SetUnavailable.
+	Reason string
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Breaker == "" {
+		return fmt.Sprintf("dependency unavailable: %s", e.Reason)
+	}
+	return fmt.Sprintf("dependency %s unavailable: %s", e.Breaker, e.Reason)
+}
+
+// Unwrap returns ErrUnavailable.
+func (e *UnavailableError) Unwrap() error {
+	return ErrUnavailable
+}
+
+// SetUnavailable marks the dependency the breaker protects as intentionally
+// turned off, such as by a feature flag, until SetAvailable is called. Calls
+// fail with an *UnavailableError giving reason, without being made, and the
+// breaker's State is StateUnavailable. Unlike a tripped or broken breaker, an
+// unavailable one does not record the calls as rejected or send events, so
+// dashboards and alerts on open breakers are not set off by it. It takes
+// precedence over Disable.
+func (cb *Breaker) SetUnavailable(reason string) {
+	cb.eventLock.Lock()
+	cb.unavailReason = reason
+	cb.eventLock.Unlock()
+	atomic.StoreInt32(&cb.unavailable, 1)
+	cb.updatePressure()
+}
+
+// SetAvailable undoes SetUnavailable.
+func (cb *Breaker) SetAvailable() {
+	atomic.StoreInt32(&cb.unavailable, 0)
+	cb.updatePressure()
+}
+
+// Unavailable reports whether the breaker is marked unavailable and, if it
+// is, the reason passed to SetUnavailable.
+func (cb *Breaker) Unavailable() (reason string, ok bool) {
+	if atomic.LoadInt32(&cb.unavailable) == 0 {
+		return "", false
+	}
+	cb.eventLock.Lock()
+	defer cb.eventLock.Unlock()
+	return cb.unavailReason, true
+}
+
+// unavailableErr returns the error of calls made while the breaker is
+// unavailable, or nil if it is not.
+func (cb *Breaker) unavailableErr() error {
+	reason, ok := cb.Unavailable()
+	if !ok {
+		return nil
+	}
+	return &UnavailableError{Breaker: cb.name, Reason: reason}
+}
@@ -0,0 +1,47 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetUnavailable(t *testing.T) {
+	cb := New(WithName("search"))
+	events := cb.Subscribe()
+	cb.SetUnavailable("disabled by flag search-v2")
+
+	called := false
+	err := cb.Call(func() error { called = true; return nil }, time.Second)
+	if called {
+		t.Fatal("expected the call not to be made")
+	}
+	var ue *UnavailableError
+	if !errors.As(err, &ue) || ue.Breaker != "search" || ue.Reason != "disabled by flag search-v2" {
+		t.Fatalf("expected an UnavailableError, got %v", err)
+	}
+	if !errors.Is(err, ErrUnavailable) || errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("expected the error to match ErrUnavailable only, got %v", err)
+	}
+	if err := cb.Allow(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected Allow to fail with ErrUnavailable, got %v", err)
+	}
+
+	if cb.State() != StateUnavailable || cb.State().String() != "unavailable" {
+		t.Fatalf("expected StateUnavailable, got %v", cb.State())
+	}
+	s := cb.Stats()
+	if !s.Unavailable || s.UnavailableReason != "disabled by flag search-v2" || s.Tripped || s.Rejects != 0 {
+		t.Fatalf("expected Stats to report the breaker unavailable, not open, got %+v", s)
+	}
+	select {
+	case e := <-events:
+		t.Fatalf("expected no events, got %v", e)
+	default:
+	}
+
+	cb.SetAvailable()
+	if err := cb.Call(func() error { return nil }, time.Second); err != nil || cb.State() != StateClosed {
+		t.Fatalf("expected calls to be made again, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package circuit
+
+import "time"
+
+// BreakerView is the read-only part of a breaker: its state and statistics,
+// without Trip, Reset, Fail or anything else that changes them. Handing a
+// BreakerView rather than a *Breaker to monitoring code and plugins keeps them
+// from changing the breaker's state by accident. See Breaker.View.
+type BreakerView interface {
+	// State returns the breaker's state. See Breaker.State.
+	State() State
+
+	// Stats returns a snapshot of the breaker's state and statistics.
+	Stats() Stats
+
+	Tripped() bool
+	Disabled() bool
+	Failures() int64
+	Successes() int64
+	Rejects() int64
+	Samples() int64
+	ConsecFailures() int64
+	ErrorRate() float64
+	MeanLatency() time.Duration
+	LatencyQuantile(q float64) time.Duration
+	RetryAfter() time.Duration
+	NextAttempt() time.Time
+	TripCause() *TripCause
+	SLACompliance() *SLACompliance
+
+	// Subscribe returns a channel of the breaker's events.
+	Subscribe() <-chan BreakerEvent
+}
+
+var _ BreakerView = (*Breaker)(nil)
+
+// breakerView hides the methods of a Breaker that BreakerView leaves out, so
+// that a view cannot be converted back to the breaker.
+type breakerView struct {
+	cb *Breaker
+}
+
+// View returns a read-only view of the breaker.
+func (cb *Breaker) View() BreakerView {
+	return breakerView{cb}
+}
+
+func (v breakerView) State() State                            { return v.cb.State() }
+func (v breakerView) Stats() Stats                            { return v.cb.Stats() }
+func (v breakerView) Tripped() bool                           { return v.cb.Tripped() }
+func (v breakerView) Disabled() bool                          { return v.cb.Disabled() }
+func (v breakerView) Failures() int64                         { return v.cb.Failures() }
+func (v breakerView) Successes() int64                        { return v.cb.Successes() }
+func (v breakerView) Rejects() int64                          { return v.cb.Rejects() }
+func (v breakerView) Samples() int64                          { return v.cb.Samples() }
+func (v breakerView) ConsecFailures() int64                   { return v.cb.ConsecFailures() }
+func (v breakerView) ErrorRate() float64                      { return v.cb.ErrorRate() }
+func (v breakerView) MeanLatency() time.Duration              { return v.cb.MeanLatency() }
+func (v breakerView) LatencyQuantile(q float64) time.Duration { return v.cb.LatencyQuantile(q) }
+func (v breakerView) RetryAfter() time.Duration               { return v.cb.RetryAfter() }
+func (v breakerView) NextAttempt() time.Time                  { return v.cb.NextAttempt() }
+func (v breakerView) TripCause() *TripCause                   { return v.cb.TripCause() }
+func (v breakerView) SLACompliance() *SLACompliance           { return v.cb.SLACompliance() }
+func (v breakerView) Subscribe() <-chan BreakerEvent          { return v.cb.Subscribe() }
+
+// PanelView is the read-only part of a Panel: the views of its breakers and
+// their statistics, without Add, Remove or access to the breakers themselves.
+// See Panel.View.
+type PanelView interface {
+	// Get returns a view of the breaker named name.
+	Get(name string) (BreakerView, bool)
+
+	// Range calls fn with a view of each breaker in the panel in order of
+	// name, until fn returns false. See Panel.Range.
+	Range(fn func(name string, cb BreakerView) bool)
+
+	Stats() map[string]Stats
+	Snapshot() Snapshot
+	Graph() Graph
+	Dependencies(name string) []string
+
+	// Subscribe returns a channel of the panel's events.
+	Subscribe() <-chan PanelEvent
+}
+
+// panelView is the PanelView of a Panel.
+type panelView struct {
+	p *Panel
+}
+
+// View returns a read-only view of the panel.
+func (p *Panel) View() PanelView {
+	return panelView{p}
+}
+
+func (v panelView) Get(name string) (BreakerView, bool) {
+	cb, ok := v.p.Get(name)
+	if !ok {
+		return nil, false
+	}
+	return cb.View(), true
+}
+
+func (v panelView) Range(fn func(name string, cb BreakerView) bool) {
+	v.p.Range(func(name string, cb *Breaker) bool {
+		return fn(name, cb.View())
+	})
+}
+
+func (v panelView) Stats() map[string]Stats           { return v.p.Stats() }
+func (v panelView) Snapshot() Snapshot                { return v.p.Snapshot() }
+func (v panelView) Graph() Graph                      { return v.p.Graph() }
+func (v panelView) Dependencies(name string) []string { return v.p.Dependencies(name) }
+func (v panelView) Subscribe() <-chan PanelEvent      { return v.p.Subscribe() }
@@ -0,0 +1,50 @@
+package circuit
+
+import "testing"
+
+func TestBreakerView(t *testing.T) {
+	cb := NewThresholdBreaker(1)
+	v := cb.View()
+	if _, ok := v.(*Breaker); ok {
+		t.Fatal("expected a view not to be convertible to the breaker")
+	}
+	if _, ok := v.(interface{ Trip() }); ok {
+		t.Fatal("expected a view to have no Trip method")
+	}
+
+	cb.Trip()
+	if !v.Tripped() || v.State() != StateOpen || !v.Stats().Tripped {
+		t.Fatal("expected the view to reflect the breaker's state")
+	}
+}
+
+func TestPanelView(t *testing.T) {
+	p := NewPanel()
+	a := NewBreaker()
+	p.Add("a", a)
+	p.Add("b", NewBreaker())
+	v := p.View()
+
+	if _, ok := v.(interface{ Remove(string) bool }); ok {
+		t.Fatal("expected a view to have no Remove method")
+	}
+	if _, ok := v.Get("missing"); ok {
+		t.Fatal("expected no view of a missing breaker")
+	}
+	a.Trip()
+	if cb, ok := v.Get("a"); !ok || !cb.Tripped() {
+		t.Fatal("expected a view of the tripped breaker")
+	}
+
+	var names []string
+	v.Range(func(name string, cb BreakerView) bool {
+		names = append(names, name)
+		return true
+	})
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Fatalf("expected to range over a and b, got %v", names)
+	}
+	if open := v.Snapshot().Open(); len(open) != 1 || open[0] != "a" {
+		t.Fatalf("expected a to be open in the snapshot, got %v", open)
+	}
+}
@@ -0,0 +1,34 @@
+package circuit
+
+import "time"
+
+// WindowOccupancy returns the number of buckets of the window holding calls,
+// and the time since the start of the oldest of them.
+func (cb *Breaker) WindowOccupancy() (buckets int, age time.Duration) {
+	return cb.counts.Occupancy()
+}
+
+// WindowWarmth returns how much of the window holds data, from 0 for a window
+// without calls to 1 for one whose oldest calls are as old as the window: the
+// age of the oldest calls over the window's length. A breaker that has just
+// been created, or whose stats were just reset, has a cold window, and an
+// error rate computed over a few seconds of a minute-long window says little.
+func (cb *Breaker) WindowWarmth() float64 {
+	windowTime := cb.counts.bucketTime * time.Duration(cb.counts.buckets.Len())
+	if windowTime <= 0 {
+		return 1
+	}
+	_, age := cb.counts.Occupancy()
+	if age >= windowTime {
+		return 1
+	}
+	return float64(age) / float64(windowTime)
+}
+
+// WarmTripFunc returns a TripFunc that trips as tf does, but only once the
+// window's warmth has reached warmth. See Breaker.WindowWarmth.
+func WarmTripFunc(warmth float64, tf TripFunc) TripFunc {
+	return func(cb *Breaker) bool {
+		return cb.WindowWarmth() >= warmth && tf(cb)
+	}
+}
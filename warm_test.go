@@ -0,0 +1,43 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+func TestWindowWarmth(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour) // The mock clock starts at the Unix epoch.
+	cb := NewBreakerWithOptions(&Options{
+		Clock:         c,
+		WindowTime:    10 * time.Second,
+		WindowBuckets: 10,
+		ShouldTrip:    WarmTripFunc(0.8, RateTripFunc(0.5, 1)),
+	})
+	if w := cb.WindowWarmth(); w != 0 {
+		t.Fatalf("expected an empty window to be cold, got %v", w)
+	}
+
+	cb.Success()
+	c.Add(5 * time.Second)
+	cb.Fail(errors.New("failed"))
+	if cb.Tripped() {
+		t.Fatal("expected the breaker not to trip on a window only half warm")
+	}
+	s := cb.Stats()
+	if s.FilledBuckets != 2 || s.WindowAge != 5*time.Second {
+		t.Fatalf("expected 2 filled buckets 5s old, got %d and %v", s.FilledBuckets, s.WindowAge)
+	}
+	if w := cb.WindowWarmth(); w != 0.5 {
+		t.Fatalf("expected a warmth of 0.5, got %v", w)
+	}
+
+	c.Add(3 * time.Second)
+	cb.Fail(errors.New("failed"))
+	if !cb.Tripped() {
+		t.Fatalf("expected the breaker to trip once the window is warm, got warmth %v", cb.WindowWarmth())
+	}
+}
@@ -0,0 +1,244 @@
+package circuit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cenkalti/backoff"
+)
+
+// WebhookSignatureHeader is the header in which a Webhook with a Secret sends
+// the signature of the request body: "sha256=" followed by the hex HMAC-SHA256
+// of the body keyed by the secret.
+const WebhookSignatureHeader = "X-Circuit-Signature"
+
+// DefaultWebhookRetries is the number of times a Webhook retries a delivery
+// when its MaxRetries is 0.
+const DefaultWebhookRetries = 3
+
+// WebhookPayload describes a breaker event delivered by a Webhook. It is the
+// request body, as JSON, unless the webhook has a Template.
+type WebhookPayload struct {
+	Breaker     string     `json:"breaker"`
+	Event       string     `json:"event"`
+	State       string     `json:"state"`
+	Time        time.Time  `json:"time"`
+	NextAttempt time.Time  `json:"next_attempt,omitempty"`
+	Cause       *TripCause `json:"cause,omitempty"`
+}
+
+// Webhook POSTs the trip and reset events of the breakers in a Panel to an
+// HTTP endpoint, such as a Slack incoming webhook or a PagerDuty-compatible
+// receiver. A template shapes the body for the endpoint:
+//
+//	hook := circuit.NewWebhook(slackURL)
+//	hook.Template = template.Must(template.New("slack").Parse(
+//		`{"text": {{printf "%q" (printf "circuit breaker %s %s" .Breaker .Event)}}}`))
+//	go hook.Run(ctx, panel)
+//
+// Several webhooks may watch one panel, each for some of its breakers.
+type Webhook struct {
+	// URL is the endpoint the events are posted to.
+	URL string
+
+	// Breakers, if non-empty, are the names of the breakers whose events are
+	// posted. The events of all the panel's breakers are posted otherwise.
+	Breakers []string
+
+	// Events are the events posted. BreakerTripped and BreakerReset are
+	// posted if it is empty.
+	Events []BreakerEvent
+
+	// Template, if non-nil, renders the request body from a WebhookPayload.
+	Template *template.Template
+
+	// ContentType is the Content-Type of the request body. It defaults to
+	// "application/json".
+	ContentType string
+
+	// Header holds additional request headers, such as Authorization.
+	Header http.Header
+
+	// Secret, if non-empty, is the key the request body is signed with. See
+	// WebhookSignatureHeader.
+	Secret []byte
+
+	// MaxRetries is the number of times a delivery that failed with a
+	// network error, a 429 or a 5xx response is retried.
+	// DefaultWebhookRetries is used if it is 0, and none are made if it is
+	// negative.
+	MaxRetries int
+
+	// BackOff spaces the retries of a delivery. An exponential backoff is
+	// used if it is nil.
+	BackOff backoff.BackOff
+
+	// Client makes the requests. http.DefaultClient is used if it is nil.
+	Client *http.Client
+
+	// OnError, if non-nil, is called with the errors of deliveries that
+	// failed after all their retries. Such events are dropped.
+	OnError func(error)
+}
+
+// NewWebhook creates a Webhook posting to url.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{URL: url}
+}
+
+// Run posts the events of the breakers in p until ctx is done. Events are
+// delivered one at a time, in order.
+func (w *Webhook) Run(ctx context.Context, p *Panel) {
+	events, unsubscribe := p.subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case pe := <-events:
+			if !w.wants(pe) {
+				continue
+			}
+			payload := WebhookPayload{
+				Breaker:     pe.Name,
+				Event:       eventName(pe.Event),
+				Time:        realClock.Now(),
+				NextAttempt: pe.NextAttempt,
+			}
+			if cb, ok := p.Get(pe.Name); ok {
+				payload.State = cb.State().String()
+				payload.Time = cb.Clock.Now()
+				if pe.Event == BreakerTripped {
+					payload.Cause = cb.TripCause()
+				}
+			}
+			if err := w.Send(ctx, payload); err != nil && w.OnError != nil {
+				w.OnError(err)
+			}
+		}
+	}
+}
+
+func (w *Webhook) wants(pe PanelEvent) bool {
+	events := w.Events
+	if len(events) == 0 {
+		events = []BreakerEvent{BreakerTripped, BreakerReset}
+	}
+	wanted := false
+	for _, e := range events {
+		wanted = wanted || e == pe.Event
+	}
+	if !wanted || len(w.Breakers) == 0 {
+		return wanted
+	}
+	for _, name := range w.Breakers {
+		if name == pe.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// eventName returns the name of e in a WebhookPayload, such as "tripped".
+func eventName(e BreakerEvent) string {
+	return strings.ToLower(strings.TrimPrefix(e.String(), "Breaker"))
+}
+
+// Send posts payload to the webhook's URL, retrying as configured.
+func (w *Webhook) Send(ctx context.Context, payload WebhookPayload) error {
+	var body []byte
+	if w.Template != nil {
+		var buf bytes.Buffer
+		if err := w.Template.Execute(&buf, payload); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	} else {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+
+	retries := w.MaxRetries
+	if retries == 0 {
+		retries = DefaultWebhookRetries
+	}
+	b := copyBackOff(w.BackOff)
+	if b == nil {
+		b = backoff.NewExponentialBackOff()
+	}
+	b.Reset()
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, body)
+		if err == nil || !retry || attempt >= retries {
+			return err
+		}
+		wait := b.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// post makes one delivery of body and reports whether a failure may be
+// retried.
+func (w *Webhook) post(ctx context.Context, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	for k, vs := range w.Header {
+		req.Header[k] = vs
+	}
+	contentType := w.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	if len(w.Secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(w.Secret, body))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook %s: %s", w.URL, resp.Status)
+}
+
+// SignWebhook returns the value of the WebhookSignatureHeader of a request
+// with body signed with secret, for receivers to compare against with
+// hmac.Equal.
+func SignWebhook(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,121 @@
+package circuit
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/cenkalti/backoff"
+)
+
+func TestWebhook(t *testing.T) {
+	secret := []byte("secret")
+	bodies := make(chan []byte, 10)
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if got, want := r.Header.Get(WebhookSignatureHeader), SignWebhook(secret, body); got != want {
+			t.Errorf("expected signature %q, got %q", want, got)
+		}
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		bodies <- body
+	}))
+	defer ts.Close()
+
+	p := NewPanel()
+	hook := NewWebhook(ts.URL)
+	hook.Secret = secret
+	hook.Breakers = []string{"db"}
+	hook.BackOff = &backoff.ConstantBackOff{Interval: time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	hook.OnError = func(err error) {
+		if ctx.Err() == nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		hook.Run(ctx, p)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	db := NewBreaker()
+	cache := NewBreaker()
+	p.Add("db", db)
+	p.Add("cache", cache)
+	cache.Trip()
+	db.Trip()
+
+	var payload WebhookPayload
+	select {
+	case body := <-bodies:
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the webhook")
+	}
+	if payload.Breaker != "db" || payload.Event != "tripped" || payload.State != "open" {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Fatalf("expected the failed delivery to be retried once, got %d attempts", n)
+	}
+
+	db.Reset()
+	select {
+	case body := <-bodies:
+		json.Unmarshal(body, &payload)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the webhook")
+	}
+	if payload.Event != "reset" {
+		t.Fatalf("expected a reset event, got %+v", payload)
+	}
+
+	cancel()
+	<-done
+	p.panelLock.Lock()
+	defer p.panelLock.Unlock()
+	if len(p.eventReceivers) != 0 {
+		t.Fatal("expected Run to unsubscribe when ctx is done")
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	var body string
+	var status int32 = http.StatusBadRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer ts.Close()
+
+	hook := NewWebhook(ts.URL)
+	hook.Template = template.Must(template.New("slack").Parse(
+		`{"text": {{printf "%q" (printf "circuit breaker %s %s" .Breaker .Event)}}}`))
+	payload := WebhookPayload{Breaker: "db", Event: "tripped"}
+	if err := hook.Send(context.Background(), payload); err == nil {
+		t.Fatal("expected an error for a 400 response")
+	}
+
+	atomic.StoreInt32(&status, http.StatusOK)
+	if err := hook.Send(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"text": "circuit breaker db tripped"}`; body != want {
+		t.Fatalf("expected body %s, got %s", want, body)
+	}
+}
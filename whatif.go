@@ -0,0 +1,31 @@
+package circuit
+
+import "sync/atomic"
+
+// EvaluateWith reports whether tf would trip the breaker if it were its
+// ShouldTrip, given the statistics in its window now. Nothing about the
+// breaker changes, so operators can trial a tighter threshold against live
+// traffic before adopting it. Like most TripFuncs, those of this package
+// report false while the breaker is tripped.
+func (cb *Breaker) EvaluateWith(tf TripFunc) bool {
+	return tf != nil && tf(cb)
+}
+
+// WouldTrip reports whether the breaker would trip if it had been created
+// with opts, given the statistics in its window now. Only the settings that
+// decide whether to trip are evaluated: ShouldTrip, or the breaker's own if
+// it is nil, and TLSTripThreshold. Settings that shape the statistics, such
+// as WindowTime and RateDecay, are those of the breaker.
+func (cb *Breaker) WouldTrip(opts *Options) bool {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.TLSTripThreshold > 0 && !cb.Tripped() && atomic.LoadInt64(&cb.tlsStreak) >= opts.TLSTripThreshold {
+		return true
+	}
+	tf := opts.ShouldTrip
+	if tf == nil {
+		tf = cb.ShouldTrip
+	}
+	return cb.EvaluateWith(tf)
+}
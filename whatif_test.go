@@ -0,0 +1,53 @@
+package circuit
+
+import (
+	"crypto/x509"
+	"errors"
+	"testing"
+)
+
+func TestEvaluateWith(t *testing.T) {
+	cb := NewRateBreaker(0.5, 10)
+	for i := 0; i < 7; i++ {
+		cb.Success()
+	}
+	for i := 0; i < 3; i++ {
+		cb.Fail(errors.New("boom"))
+	}
+
+	if cb.EvaluateWith(cb.ShouldTrip) {
+		t.Fatal("expected the breaker's own TripFunc not to trip at a 30% error rate")
+	}
+	if !cb.EvaluateWith(RateTripFunc(0.25, 10)) {
+		t.Fatal("expected a 25% threshold to trip at a 30% error rate")
+	}
+	if cb.EvaluateWith(nil) {
+		t.Fatal("expected a nil TripFunc not to trip")
+	}
+	if cb.Tripped() || cb.Failures() != 3 || cb.Successes() != 7 {
+		t.Fatal("expected evaluation to leave the breaker unchanged")
+	}
+}
+
+func TestWouldTrip(t *testing.T) {
+	cb := NewThresholdBreaker(5)
+	tlsErr := x509.UnknownAuthorityError{}
+	cb.Fail(tlsErr)
+	cb.Fail(tlsErr)
+
+	if cb.WouldTrip(nil) {
+		t.Fatal("expected the breaker's own settings not to trip")
+	}
+	if !cb.WouldTrip(&Options{ShouldTrip: ThresholdTripFunc(2)}) {
+		t.Fatal("expected a threshold of 2 to trip after 2 failures")
+	}
+	if !cb.WouldTrip(&Options{TLSTripThreshold: 2}) {
+		t.Fatal("expected a TLS threshold of 2 to trip after 2 TLS failures")
+	}
+	if cb.WouldTrip(&Options{TLSTripThreshold: 3}) {
+		t.Fatal("expected a TLS threshold of 3 not to trip after 2 TLS failures")
+	}
+	if cb.Tripped() {
+		t.Fatal("expected WouldTrip to leave the breaker closed")
+	}
+}
@@ -16,26 +16,83 @@ var (
 	DefaultWindowBuckets = 10
 )
 
-// bucket holds counts of failures and successes
+// latencyBounds are the upper bounds of the latency histogram kept in each
+// bucket, doubling from 1ms to about a minute.
+var latencyBounds = func() [17]time.Duration {
+	var bounds [17]time.Duration
+	for i := range bounds {
+		bounds[i] = time.Millisecond << uint(i)
+	}
+	return bounds
+}()
+
+// bucket holds the calls made during one period of a window.
+//
+// It counts failures, successes and rejected calls, and sums the weighted
+// failure score and the cost of failed and successful calls. Timed calls
+// add to the total latency and a latency histogram.
 type bucket struct {
-	failure int64
-	success int64
+	failure   int64
+	success   int64
+	reject    int64
+	score     float64
+	failCost  float64
+	succCost  float64
+	latency   time.Duration
+	timed     int64
+	latencies [len(latencyBounds) + 1]int64
+	extra     map[string]float64        // values of the window's BucketExtensions
+	category  map[FailureCategory]int64 // failures by category
+	period    uint64                    // the window's period the bucket holds; see FailCategory
 }
 
 // Reset resets the counts to 0
 func (b *bucket) Reset() {
 	b.failure = 0
 	b.success = 0
+	b.reject = 0
+	b.score = 0
+	b.failCost = 0
+	b.succCost = 0
+	b.latency = 0
+	b.timed = 0
+	b.latencies = [len(latencyBounds) + 1]int64{}
+	for key := range b.extra {
+		delete(b.extra, key)
+	}
+	for c := range b.category {
+		delete(b.category, c)
+	}
+}
+
+// Fail adds n to the failure count, and n times weight to the failure score
+// and cost to the failure cost
+func (b *bucket) Fail(n int64, weight, cost float64) {
+	b.failure += n
+	b.score += float64(n) * weight
+	b.failCost += float64(n) * cost
 }
 
-// Fail increments the failure count
-func (b *bucket) Fail() {
-	b.failure++
+// Sucecss adds n to the success count and n times cost to the success cost
+func (b *bucket) Success(n int64, cost float64) {
+	b.success += n
+	b.succCost += float64(n) * cost
 }
 
-// Sucecss increments the success count
-func (b *bucket) Success() {
-	b.success++
+// Reject increments the rejected call count
+func (b *bucket) Reject() {
+	b.reject++
+}
+
+// Observe adds the latency of a timed call
+func (b *bucket) Observe(d time.Duration) {
+	b.latency += d
+	b.timed++
+	i := 0
+	for i < len(latencyBounds) && d > latencyBounds[i] {
+		i++
+	}
+	b.latencies[i]++
 }
 
 // window maintains a ring of buckets and increments the failure and success
@@ -47,7 +104,22 @@ type window struct {
 	bucketTime time.Duration
 	bucketLock sync.RWMutex
 	lastAccess time.Time
+	aligned    bool // buckets start on multiples of bucketTime
 	clock      clock.Clock
+	onRollover func(BucketCounts)
+	extensions map[string]BucketExtension
+	periods    uint64 // the number of periods the buckets have held
+}
+
+// BucketCounts holds the counts of a bucket of a breaker's window, which
+// covers the calls recorded from Start to End. See Options.BucketRollover.
+type BucketCounts struct {
+	Start        time.Time
+	End          time.Time
+	Failures     int64
+	Successes    int64
+	Rejects      int64
+	FailureScore float64
 }
 
 // newWindow creates a new window. windowTime is the time covering the entire
@@ -62,6 +134,7 @@ func newWindow(windowTime time.Duration, windowBuckets int) *window {
 	}
 
 	clock := clock.New()
+	buckets.Value.(*bucket).period = 1
 
 	bucketTime := time.Duration(windowTime.Nanoseconds() / int64(windowBuckets))
 	return &window{
@@ -69,23 +142,99 @@ func newWindow(windowTime time.Duration, windowBuckets int) *window {
 		bucketTime: bucketTime,
 		clock:      clock,
 		lastAccess: clock.Now(),
+		periods:    1,
 	}
 }
 
-// Fail records a failure in the current bucket.
+// Fail records a failure with a weight and cost of 1 in the current bucket.
 func (w *window) Fail() {
+	w.FailN(1, 1, 1)
+}
+
+// FailN records n failures, each with the given weight and cost, in the
+// current bucket.
+func (w *window) FailN(n int64, weight, cost float64) {
+	w.FailCategory("", n, weight, cost)
+}
+
+// FailCategory is like FailN, but also counts the failures under category c
+// unless it is empty. It returns the period of the bucket the failures were
+// recorded in. Each time a bucket becomes the current one, it starts a new
+// period, so a period names the bucket for as long as it holds the calls
+// recorded in it.
+func (w *window) FailCategory(c FailureCategory, n int64, weight, cost float64) uint64 {
 	w.bucketLock.Lock()
-	b := w.getLatestBucket()
-	b.Fail()
+	b, rolled := w.getLatestBucket()
+	b.Fail(n, weight, cost)
+	if c != "" {
+		if b.category == nil {
+			b.category = make(map[FailureCategory]int64)
+		}
+		b.category[c] += n
+	}
+	period := b.period
 	w.bucketLock.Unlock()
+	w.rolledOver(rolled)
+	return period
 }
 
-// Success records a success in the current bucket.
+// Success records a success with a cost of 1 in the current bucket.
 func (w *window) Success() {
+	w.SuccessN(1, 1)
+}
+
+// SuccessN records n successes, each with the given cost, in the current
+// bucket.
+func (w *window) SuccessN(n int64, cost float64) {
 	w.bucketLock.Lock()
-	b := w.getLatestBucket()
-	b.Success()
+	b, rolled := w.getLatestBucket()
+	b.Success(n, cost)
 	w.bucketLock.Unlock()
+	w.rolledOver(rolled)
+}
+
+// Amend turns a failure recorded with the given weight and cost into a
+// success, in the bucket holding period. It reports false if the bucket has
+// moved on to another period, or holds no failure to amend.
+func (w *window) Amend(period uint64, weight, cost float64) bool {
+	w.bucketLock.Lock()
+	defer w.bucketLock.Unlock()
+
+	r := w.buckets
+	for i := 0; i < r.Len(); i++ {
+		b := r.Value.(*bucket)
+		if b.period == period {
+			if b.failure == 0 {
+				return false
+			}
+			b.Fail(-1, weight, cost)
+			b.Success(1, cost)
+			if b.category[FailureTimeout] > 0 {
+				b.category[FailureTimeout]--
+			}
+			return true
+		}
+		r = r.Prev()
+	}
+	return false
+}
+
+// Reject records a rejected call in the current bucket.
+func (w *window) Reject() {
+	w.bucketLock.Lock()
+	b, rolled := w.getLatestBucket()
+	b.Reject()
+	w.bucketLock.Unlock()
+	w.rolledOver(rolled)
+}
+
+// Observe records the latency of a call in the current bucket.
+func (w *window) Observe(d time.Duration) {
+	w.bucketLock.Lock()
+	b, rolled := w.getLatestBucket()
+	b.Observe(d)
+	w.bucketLock.Unlock()
+	w.rolledOver(rolled)
 }
 
 // Failures returns the total number of failures recorded in all buckets.
@@ -115,6 +264,83 @@ func (w *window) Successes() int64 {
 	return successes
 }
 
+// Rejects returns the total number of rejected calls recorded in all buckets.
+func (w *window) Rejects() int64 {
+	w.bucketLock.RLock()
+
+	var rejects int64
+	w.buckets.Do(func(x interface{}) {
+		b := x.(*bucket)
+		rejects += b.reject
+	})
+	w.bucketLock.RUnlock()
+	return rejects
+}
+
+// CountsSince returns the failures and successes recorded in the buckets
+// covering the last d of the window, including the current bucket. d is
+// rounded up to a whole number of buckets and capped at the window's length.
+func (w *window) CountsSince(d time.Duration) (failures, successes int64) {
+	n := 1
+	if w.bucketTime > 0 {
+		n = int((d + w.bucketTime - 1) / w.bucketTime)
+	}
+
+	w.bucketLock.RLock()
+	defer w.bucketLock.RUnlock()
+
+	if n < 1 {
+		n = 1
+	}
+	if n > w.buckets.Len() {
+		n = w.buckets.Len()
+	}
+	r := w.buckets
+	for i := 0; i < n; i++ {
+		b := r.Value.(*bucket)
+		failures += b.failure
+		successes += b.success
+		r = r.Prev()
+	}
+	return failures, successes
+}
+
+// Occupancy returns the number of buckets holding failures or successes, and
+// the time since the start of the oldest of them.
+func (w *window) Occupancy() (buckets int, age time.Duration) {
+	w.bucketLock.RLock()
+	defer w.bucketLock.RUnlock()
+
+	oldest := -1
+	r := w.buckets
+	for i := 0; i < r.Len(); i++ {
+		if b := r.Value.(*bucket); b.failure+b.success > 0 {
+			buckets++
+			oldest = i
+		}
+		r = r.Prev()
+	}
+	if oldest < 0 {
+		return 0, 0
+	}
+	start := w.lastAccess.Add(-time.Duration(oldest) * w.bucketTime)
+	return buckets, w.clock.Now().Sub(start)
+}
+
+// FailureScore returns the sum of the weights of the failures recorded in all
+// buckets.
+func (w *window) FailureScore() float64 {
+	w.bucketLock.RLock()
+
+	var score float64
+	w.buckets.Do(func(x interface{}) {
+		b := x.(*bucket)
+		score += b.score
+	})
+	w.bucketLock.RUnlock()
+	return score
+}
+
 // ErrorRate returns the error rate calculated over all buckets, expressed as
 // a floating point number (e.g. 0.9 for 90%)
 func (w *window) ErrorRate() float64 {
@@ -136,6 +362,132 @@ func (w *window) ErrorRate() float64 {
 	return float64(failures) / float64(total)
 }
 
+// DecayedErrorRate returns the error rate with the calls in each bucket
+// weighed by weight, which is given the age of the bucket: 0 for the current
+// bucket, 1 for the one before, and so on.
+func (w *window) DecayedErrorRate(weight func(age int) float64) float64 {
+	w.bucketLock.RLock()
+	defer w.bucketLock.RUnlock()
+
+	var total, failures float64
+	r := w.buckets
+	for age := 0; age < w.buckets.Len(); age++ {
+		b := r.Value.(*bucket)
+		wt := weight(age)
+		total += wt * float64(b.failure+b.success)
+		failures += wt * float64(b.failure)
+		r = r.Prev()
+	}
+
+	if total == 0 {
+		return 0.0
+	}
+	return failures / total
+}
+
+// WeightedErrorRate returns the error rate calculated over all buckets with
+// each failure counted by its weight, expressed as a floating point number.
+func (w *window) WeightedErrorRate() float64 {
+	var score float64
+	var successes int64
+
+	w.bucketLock.RLock()
+	w.buckets.Do(func(x interface{}) {
+		b := x.(*bucket)
+		score += b.score
+		successes += b.success
+	})
+	w.bucketLock.RUnlock()
+
+	if score+float64(successes) == 0 {
+		return 0.0
+	}
+
+	return score / (score + float64(successes))
+}
+
+// CostErrorRate returns the error rate calculated over all buckets with each
+// call counted by its cost, expressed as a floating point number, and the
+// total cost of the calls.
+func (w *window) CostErrorRate() (rate, total float64) {
+	var failCost float64
+
+	w.bucketLock.RLock()
+	w.buckets.Do(func(x interface{}) {
+		b := x.(*bucket)
+		failCost += b.failCost
+		total += b.failCost + b.succCost
+	})
+	w.bucketLock.RUnlock()
+
+	if total == 0 {
+		return 0.0, 0
+	}
+
+	return failCost / total, total
+}
+
+// MeanLatency returns the mean latency of the calls observed in all buckets,
+// or 0 if there are none.
+func (w *window) MeanLatency() time.Duration {
+	var latency time.Duration
+	var timed int64
+
+	w.bucketLock.RLock()
+	w.buckets.Do(func(x interface{}) {
+		b := x.(*bucket)
+		latency += b.latency
+		timed += b.timed
+	})
+	w.bucketLock.RUnlock()
+
+	if timed == 0 {
+		return 0
+	}
+	return latency / time.Duration(timed)
+}
+
+// LatencyQuantile estimates the q-quantile (e.g. 0.5 for the median) of the
+// latencies observed in all buckets, or returns 0 if there are none. The
+// estimate is interpolated within the histogram bin the quantile falls in.
+func (w *window) LatencyQuantile(q float64) time.Duration {
+	var latencies [len(latencyBounds) + 1]int64
+	var timed int64
+
+	w.bucketLock.RLock()
+	w.buckets.Do(func(x interface{}) {
+		b := x.(*bucket)
+		for i, n := range b.latencies {
+			latencies[i] += n
+		}
+		timed += b.timed
+	})
+	w.bucketLock.RUnlock()
+
+	if timed == 0 {
+		return 0
+	}
+
+	rank := q * float64(timed)
+	var seen float64
+	for i, n := range latencies {
+		if n == 0 || seen+float64(n) < rank {
+			seen += float64(n)
+			continue
+		}
+		if i == len(latencyBounds) {
+			return latencyBounds[i-1]
+		}
+		var lower time.Duration
+		if i > 0 {
+			lower = latencyBounds[i-1]
+		}
+		frac := (rank - seen) / float64(n)
+		return lower + time.Duration(frac*float64(latencyBounds[i]-lower))
+	}
+	return latencyBounds[len(latencyBounds)-1]
+}
+
 // Reset resets the count of all buckets.
 func (w *window) Reset() {
 	w.bucketLock.Lock()
@@ -146,29 +498,190 @@ func (w *window) Reset() {
 	w.bucketLock.Unlock()
 }
 
+// Seed resets all buckets and records the given counts and failure score in
+// the current bucket, with a cost of 1 per call.
+func (w *window) Seed(failures, successes int64, score float64) {
+	w.bucketLock.Lock()
+
+	w.buckets.Do(func(x interface{}) {
+		x.(*bucket).Reset()
+	})
+	b, _ := w.getLatestBucket()
+	b.failure = failures
+	b.success = successes
+	b.score = score
+	b.failCost = float64(failures)
+	b.succCost = float64(successes)
+	w.bucketLock.Unlock()
+}
+
 // getLatestBucket returns the current bucket. If the bucket time has elapsed
 // it will move to the next bucket, resetting its counts and updating the last
-// access time before returning it. getLatestBucket assumes that the caller has
-// locked the bucketLock
-func (w *window) getLatestBucket() *bucket {
+// access time before returning it, along with the counts of the bucket it
+// moved on from if the window has an onRollover callback. getLatestBucket
+// assumes that the caller has locked the bucketLock
+func (w *window) getLatestBucket() (*bucket, *BucketCounts) {
 	var b *bucket
+	var rolled *BucketCounts
 	b = w.buckets.Value.(*bucket)
-	elapsed := w.clock.Now().Sub(w.lastAccess)
+	now := w.clock.Now()
+	elapsed := now.Sub(w.lastAccess)
 
-	if elapsed > w.bucketTime {
+	if elapsed > w.bucketTime || w.aligned && elapsed == w.bucketTime {
+		if w.onRollover != nil {
+			end := now
+			if w.aligned {
+				end = w.lastAccess.Add(w.bucketTime)
+			}
+			rolled = &BucketCounts{
+				Start:        w.lastAccess,
+				End:          end,
+				Failures:     b.failure,
+				Successes:    b.success,
+				Rejects:      b.reject,
+				FailureScore: b.score,
+			}
+		}
 		// Reset the buckets between now and number of buckets ago. If
 		// that is more that the existing buckets, reset all.
 		for i := 0; i < w.buckets.Len(); i++ {
 			w.buckets = w.buckets.Next()
 			b = w.buckets.Value.(*bucket)
 			b.Reset()
+			w.periods++
+			b.period = w.periods
 			elapsed = time.Duration(int64(elapsed) - int64(w.bucketTime))
 			if elapsed < w.bucketTime {
 				// Done resetting buckets.
 				break
 			}
 		}
-		w.lastAccess = w.clock.Now()
+		w.lastAccess = w.bucketStart(now)
+	}
+	return b, rolled
+}
+
+// bucketStart returns the start of a bucket beginning at now: now itself, or
+// the last bucket boundary of the wall clock if the window is aligned.
+func (w *window) bucketStart(now time.Time) time.Time {
+	if w.aligned && w.bucketTime > 0 {
+		return now.Truncate(w.bucketTime)
+	}
+	return now
+}
+
+// rolledOver passes the counts of a bucket the window moved on from to the
+// onRollover callback. It is called without the bucketLock held, so the
+// callback may use the window.
+func (w *window) rolledOver(rolled *BucketCounts) {
+	if rolled != nil {
+		w.onRollover(*rolled)
+	}
+}
+
+// windowJSON is the JSON representation of a window's buckets.
+type windowJSON struct {
+	LastAccess time.Time `json:"last_access"`
+	// Buckets are ordered from the oldest to the current bucket.
+	Buckets []bucketJSON `json:"buckets"`
+}
+
+type bucketJSON struct {
+	Failures     int64         `json:"failures"`
+	Successes    int64         `json:"successes"`
+	Rejects      int64         `json:"rejects,omitempty"`
+	FailureScore float64       `json:"failure_score"`
+	FailureCost  float64       `json:"failure_cost"`
+	SuccessCost  float64       `json:"success_cost"`
+	Latency      time.Duration `json:"latency"`
+	Timed        int64         `json:"timed"`
+	Latencies    []int64       `json:"latencies"`
+
+	Categories map[FailureCategory]int64 `json:"categories,omitempty"`
+	Extra      map[string]float64        `json:"extra,omitempty"`
+}
+
+// Snapshot returns the contents of all buckets.
+func (w *window) Snapshot() *windowJSON {
+	w.bucketLock.RLock()
+	defer w.bucketLock.RUnlock()
+
+	wj := &windowJSON{LastAccess: w.lastAccess}
+	// The bucket after the current one is the oldest.
+	w.buckets.Next().Do(func(x interface{}) {
+		b := x.(*bucket)
+		wj.Buckets = append(wj.Buckets, bucketJSON{
+			Failures:     b.failure,
+			Successes:    b.success,
+			Rejects:      b.reject,
+			FailureScore: b.score,
+			FailureCost:  b.failCost,
+			SuccessCost:  b.succCost,
+			Latency:      b.latency,
+			Timed:        b.timed,
+			Latencies:    append([]int64(nil), b.latencies[:]...),
+			Categories:   copyCategories(b.category),
+			Extra:        copyExtra(b.extra),
+		})
+	})
+	return wj
+}
+
+// copyCategories returns a copy of m, or nil if it is empty.
+func copyCategories(m map[FailureCategory]int64) map[FailureCategory]int64 {
+	if len(m) == 0 {
+		return nil
+	}
+	c := make(map[FailureCategory]int64, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// copyExtra returns a copy of m, or nil if it is empty.
+func copyExtra(m map[string]float64) map[string]float64 {
+	if len(m) == 0 {
+		return nil
+	}
+	c := make(map[string]float64, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// Restore replaces the contents of all buckets with those of a snapshot taken
+// of a window with the same number of buckets, and reports whether it did.
+// Buckets that have expired since the snapshot was taken are reset on the next
+// access.
+func (w *window) Restore(wj *windowJSON) bool {
+	w.bucketLock.Lock()
+	defer w.bucketLock.Unlock()
+
+	if len(wj.Buckets) != w.buckets.Len() {
+		return false
+	}
+	r := w.buckets.Next()
+	for _, bj := range wj.Buckets {
+		b := r.Value.(*bucket)
+		b.Reset()
+		b.failure = bj.Failures
+		b.success = bj.Successes
+		b.reject = bj.Rejects
+		b.score = bj.FailureScore
+		b.failCost = bj.FailureCost
+		b.succCost = bj.SuccessCost
+		b.latency = bj.Latency
+		b.timed = bj.Timed
+		copy(b.latencies[:], bj.Latencies)
+		b.category = copyCategories(bj.Categories)
+		b.extra = copyExtra(bj.Extra)
+		// Calls made before the restore must not amend the restored counts.
+		w.periods++
+		b.period = w.periods
+		r = r.Next()
 	}
-	return b
+	w.lastAccess = wj.LastAccess
+	return true
 }
@@ -72,3 +72,253 @@ func TestWindowSlides(t *testing.T) {
 		t.Fatalf("expected 0 buckets to have failures, got %d", counts)
 	}
 }
+
+func TestWindowMeanLatency(t *testing.T) {
+	w := newWindow(time.Millisecond*10, 2)
+	if l := w.MeanLatency(); l != 0 {
+		t.Fatalf("expected empty window to have 0 latency, got %v", l)
+	}
+
+	w.Observe(10 * time.Millisecond)
+	w.Observe(30 * time.Millisecond)
+	if l := w.MeanLatency(); l != 20*time.Millisecond {
+		t.Fatalf("expected mean latency of 20ms, got %v", l)
+	}
+}
+
+func TestWindowLatencyQuantile(t *testing.T) {
+	w := newWindow(time.Minute, 2)
+	if l := w.LatencyQuantile(0.5); l != 0 {
+		t.Fatalf("expected empty window to have 0 latency, got %v", l)
+	}
+
+	// 60 calls between 32ms and 64ms, 40 calls between 128ms and 256ms.
+	for i := 0; i < 60; i++ {
+		w.Observe(40 * time.Millisecond)
+	}
+	for i := 0; i < 40; i++ {
+		w.Observe(200 * time.Millisecond)
+	}
+	if l := w.LatencyQuantile(0.5); l <= 32*time.Millisecond || l > 64*time.Millisecond {
+		t.Fatalf("expected median between 32ms and 64ms, got %v", l)
+	}
+	if l := w.LatencyQuantile(0.9); l <= 128*time.Millisecond || l > 256*time.Millisecond {
+		t.Fatalf("expected 90th percentile between 128ms and 256ms, got %v", l)
+	}
+
+	w.Observe(time.Hour)
+	if l := w.LatencyQuantile(1); l != latencyBounds[len(latencyBounds)-1] {
+		t.Fatalf("expected the maximum to be capped at the last bound, got %v", l)
+	}
+}
+
+func TestWindowSnapshotRestore(t *testing.T) {
+	c := clock.NewMock()
+	w := newWindow(time.Second*3, 3)
+	w.clock = c
+	w.lastAccess = c.Now()
+
+	w.extensions = map[string]BucketExtension{"retries": SumExtension("retries")}
+
+	w.Fail()
+	w.FailCategory(FailureTimeout, 1, 1, 1)
+	c.Add(time.Millisecond * 1100)
+	w.Success()
+	w.Observe(50 * time.Millisecond)
+	w.RecordExtra("retries", 3)
+
+	restored := newWindow(time.Second*3, 3)
+	restored.clock = c
+	restored.extensions = w.extensions
+	period := restored.buckets.Value.(*bucket).period
+	if !restored.Restore(w.Snapshot()) {
+		t.Fatal("expected snapshot to be restored")
+	}
+	if f, s := restored.Failures(), restored.Successes(); f != 2 || s != 1 {
+		t.Fatalf("expected 2 failures and 1 success, got %d and %d", f, s)
+	}
+	if n := restored.CategoryFailures(FailureTimeout); n != 1 {
+		t.Fatalf("expected the failure categories to be restored, got %d timeouts", n)
+	}
+	if v, _ := restored.Extra("retries"); v != 3 {
+		t.Fatalf("expected the extension values to be restored, got %v", v)
+	}
+	if restored.Amend(period, 1, 1) {
+		t.Fatal("expected a call made before the restore not to amend it")
+	}
+	if l := restored.LatencyQuantile(1); l != w.LatencyQuantile(1) {
+		t.Fatalf("expected latencies to be restored, got %v", l)
+	}
+
+	// The failures are in an older bucket than the success, so they expire
+	// first.
+	c.Add(time.Millisecond * 2000)
+	restored.Success()
+	if f, s := restored.Failures(), restored.Successes(); f != 0 || s != 2 {
+		t.Fatalf("expected the failures to have expired, got %d failures and %d successes", f, s)
+	}
+
+	if newWindow(time.Second, 2).Restore(w.Snapshot()) {
+		t.Fatal("expected a snapshot with a different number of buckets not to be restored")
+	}
+}
+
+func TestWindowCountsSince(t *testing.T) {
+	c := clock.NewMock()
+
+	w := newWindow(time.Millisecond*10, 5)
+	w.clock = c
+	w.lastAccess = c.Now()
+
+	w.Fail()
+	w.Fail()
+	c.Add(time.Millisecond * 5)
+	w.Success()
+	w.Fail()
+	c.Add(time.Millisecond * 3)
+	w.Success()
+
+	cases := []struct {
+		since               time.Duration
+		failures, successes int64
+	}{
+		{0, 0, 1},
+		{time.Millisecond, 0, 1},
+		{time.Millisecond * 2, 0, 1},
+		{time.Millisecond * 3, 1, 2},
+		{time.Millisecond * 6, 1, 2},
+		{time.Millisecond * 7, 3, 2},
+		{time.Hour, 3, 2},
+	}
+	for _, tc := range cases {
+		f, s := w.CountsSince(tc.since)
+		if f != tc.failures || s != tc.successes {
+			t.Errorf("CountsSince(%v) = %d, %d, expected %d, %d", tc.since, f, s, tc.failures, tc.successes)
+		}
+	}
+}
+
+func TestWindowRollover(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour)
+
+	var rolled []BucketCounts
+	cb := NewBreakerWithOptions(&Options{
+		Clock:          c,
+		WindowTime:     time.Second,
+		WindowBuckets:  10,
+		BucketRollover: func(bc BucketCounts) { rolled = append(rolled, bc) },
+	})
+
+	start := c.Now()
+	cb.Fail(nil)
+	cb.Success()
+	cb.Fail(nil)
+	if len(rolled) != 0 {
+		t.Fatalf("expected no rollover within a bucket, got %+v", rolled)
+	}
+
+	c.Add(time.Millisecond * 150)
+	cb.Success()
+	want := BucketCounts{Start: start, End: c.Now(), Failures: 2, Successes: 1, FailureScore: 2}
+	if len(rolled) != 1 || rolled[0] != want {
+		t.Fatalf("expected rollover %+v, got %+v", want, rolled)
+	}
+}
+
+func TestWindowAlignBuckets(t *testing.T) {
+	c := clock.NewMock()
+	c.Add(time.Hour + 400*time.Millisecond)
+
+	var rolled []BucketCounts
+	cb := NewBreakerWithOptions(&Options{
+		Clock:          c,
+		WindowTime:     10 * time.Second,
+		WindowBuckets:  10,
+		AlignBuckets:   true,
+		BucketRollover: func(bc BucketCounts) { rolled = append(rolled, bc) },
+	})
+
+	cb.Fail(nil)
+	c.Add(600 * time.Millisecond)
+	cb.Success()
+	start := time.Unix(3600, 0)
+	want := BucketCounts{Start: start, End: start.Add(time.Second), Failures: 1, FailureScore: 1}
+	if len(rolled) != 1 || !rolled[0].Start.Equal(want.Start) || !rolled[0].End.Equal(want.End) ||
+		rolled[0].Failures != 1 || rolled[0].Successes != 0 {
+		t.Fatalf("expected rollover %+v on the second, got %+v", want, rolled)
+	}
+
+	c.Add(2500 * time.Millisecond)
+	cb.Success()
+	if len(rolled) != 2 || !rolled[1].Start.Equal(start.Add(time.Second)) || rolled[1].Successes != 1 {
+		t.Fatalf("expected the next bucket to start on the next second, got %+v", rolled)
+	}
+	if f, s := cb.CountsSince(time.Second); f != 0 || s != 1 {
+		t.Fatalf("expected only the latest success in the current bucket, got %d failures and %d successes", f, s)
+	}
+	if cb.Failures() != 1 {
+		t.Fatalf("expected the failure to stay in the window, got %d", cb.Failures())
+	}
+}
+
+func TestWindowDecayedErrorRate(t *testing.T) {
+	c := clock.NewMock()
+
+	w := newWindow(time.Millisecond*10, 2)
+	w.clock = c
+	w.lastAccess = c.Now()
+
+	w.Success()
+	w.Success()
+	c.Add(time.Millisecond * 6)
+	w.Fail()
+	w.Success()
+
+	if r := w.DecayedErrorRate(func(age int) float64 { return 1 }); r != 0.25 {
+		t.Fatalf("expected an even weighting to give 0.25, got %v", r)
+	}
+	// The current bucket weighs 2, the previous one 1: (2*1)/(2*2+1*2).
+	if r := w.DecayedErrorRate(decayFunc(DecayLinear, 0, 2)); r != 2.0/6 {
+		t.Fatalf("expected a linear decay to give 1/3, got %v", r)
+	}
+	// The previous bucket weighs 0.25: (1*1)/(1*2+0.25*2).
+	if r := w.DecayedErrorRate(decayFunc(DecayExponential, 0.25, 2)); r != 0.4 {
+		t.Fatalf("expected an exponential decay to give 0.4, got %v", r)
+	}
+	if decayFunc(DecayNone, 0, 2) != nil {
+		t.Fatal("expected no decay function without decay")
+	}
+}
+
+func TestWindowAmend(t *testing.T) {
+	c := clock.NewMock()
+	w := newWindow(time.Millisecond*10, 2)
+	w.clock = c
+	w.lastAccess = c.Now()
+
+	period := w.FailCategory(FailureTimeout, 1, 1, 1)
+	c.Add(time.Millisecond * 6)
+	w.Fail()
+	if !w.Amend(period, 1, 1) {
+		t.Fatal("expected the failure to be amended")
+	}
+	latest := w.buckets.Value.(*bucket)
+	if latest.failure != 1 || latest.success != 0 {
+		t.Fatalf("expected the later failure to be left alone, got %d failures and %d successes",
+			latest.failure, latest.success)
+	}
+	if w.Failures() != 1 || w.Successes() != 1 {
+		t.Fatalf("expected 1 failure and 1 success, got %d and %d", w.Failures(), w.Successes())
+	}
+	if w.Amend(period, 1, 1) {
+		t.Fatal("expected no failure left to amend in the bucket")
+	}
+
+	period = w.FailCategory(FailureTimeout, 1, 1, 1)
+	c.Add(time.Millisecond * 25)
+	w.Fail()
+	if w.Amend(period, 1, 1) {
+		t.Fatal("expected a bucket that moved on not to be amended")
+	}
+}
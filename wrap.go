@@ -0,0 +1,68 @@
+package circuit
+
+import (
+	"context"
+	"reflect"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// Wrap returns a version of fn protected by cb, which saves wrapping each
+// method of a large API client by hand:
+//
+//	getObject := circuit.Wrap(cb, client.GetObject)
+//	out, err := getObject(ctx, input)
+//
+// fn must be a function whose last result is an error, which is recorded on
+// the breaker. When the breaker is open, fn is not called and the wrapped
+// function returns zero values and ErrBreakerOpen. If the first parameter of
+// fn is a context.Context, it is used as with Do, and fn is passed the context
+// Do passes its function. Wrap panics if fn is not a function returning an
+// error.
+func Wrap[T any](cb *Breaker, fn T) T {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumOut() == 0 || t.Out(t.NumOut()-1) != errorType {
+		panic("circuit: Wrap requires a function whose last result is an error, got " + t.String())
+	}
+	hasContext := t.NumIn() > 0 && t.In(0) == contextType
+
+	wrapped := reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		ctx := context.Background()
+		if hasContext {
+			if c, ok := args[0].Interface().(context.Context); ok {
+				ctx = c
+			}
+		}
+
+		var results []reflect.Value
+		err := cb.Do(ctx, func(ctx context.Context) error {
+			if hasContext {
+				args[0] = reflect.ValueOf(&ctx).Elem()
+			}
+			if t.IsVariadic() {
+				results = v.CallSlice(args)
+			} else {
+				results = v.Call(args)
+			}
+			if errResult := results[len(results)-1]; !errResult.IsNil() {
+				return errResult.Interface().(error)
+			}
+			return nil
+		}, 0)
+
+		if results == nil {
+			// fn was not called.
+			results = make([]reflect.Value, t.NumOut())
+			for i := range results {
+				results[i] = reflect.Zero(t.Out(i))
+			}
+			results[len(results)-1] = reflect.ValueOf(&err).Elem()
+		}
+		return results
+	})
+	return wrapped.Interface().(T)
+}
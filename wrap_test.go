@@ -0,0 +1,61 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type testClient struct {
+	calls int
+}
+
+func (c *testClient) Get(ctx context.Context, key string) (string, error) {
+	c.calls++
+	if key == "" {
+		return "", errors.New("missing key")
+	}
+	return "value of " + key, nil
+}
+
+func (c *testClient) Join(sep string, parts ...string) (string, int, error) {
+	c.calls++
+	return fmt.Sprint(parts), len(parts), nil
+}
+
+func TestWrap(t *testing.T) {
+	c := &testClient{}
+	cb := NewConsecutiveBreaker(1)
+	get := Wrap(cb, c.Get)
+
+	if v, err := get(context.Background(), "a"); err != nil || v != "value of a" {
+		t.Fatalf("expected wrapped call to succeed, got %q, %v", v, err)
+	}
+	if _, err := get(context.Background(), ""); err == nil || err == ErrBreakerOpen {
+		t.Fatalf("expected the call's error, got %v", err)
+	}
+	if !cb.Tripped() {
+		t.Fatal("expected the failure to trip the breaker")
+	}
+	if v, err := get(context.Background(), "a"); err != ErrBreakerOpen || v != "" {
+		t.Fatalf("expected zero value and ErrBreakerOpen, got %q, %v", v, err)
+	}
+	if c.calls != 2 {
+		t.Fatalf("expected the rejected call not to be made, got %d calls", c.calls)
+	}
+
+	join := Wrap(NewBreaker(), c.Join)
+	if s, n, err := join(",", "a", "b"); err != nil || n != 2 || s != "[a b]" {
+		t.Fatalf("expected variadic call to be wrapped, got %q, %d, %v", s, n, err)
+	}
+}
+
+func TestWrapPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Wrap to panic for a function without an error result")
+		}
+	}()
+	Wrap(NewBreaker(), func() int { return 0 })
+}